@@ -20,6 +20,7 @@ import (
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller"
 	"github.com/stolostron/managedcluster-import-controller/pkg/controller/agentregistration"
@@ -50,6 +51,7 @@ import (
 	capiv1beta1 "sigs.k8s.io/cluster-api/api/v1beta1"
 
 	routeclient "github.com/openshift/client-go/route/clientset/versioned"
+	corev1 "k8s.io/api/core/v1"
 	apiextensionsclient "k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/fields"
@@ -259,6 +261,27 @@ func main() {
 		},
 	)
 
+	// saTokenSecretInformerF watches the service account token secrets to look for the legacy bootstrap tokens
+	// without listing the secrets of the managed cluster namespaces
+	saTokenSecretInformerF := informers.NewFilteredSharedInformerFactory(
+		kubeClient,
+		10*time.Minute,
+		metav1.NamespaceAll, func(listOptions *metav1.ListOptions) {
+			listOptions.FieldSelector = fields.OneTermEqualSelector(
+				"type", string(corev1.SecretTypeServiceAccountToken)).String()
+		},
+	)
+	saTokenSecretInformer := saTokenSecretInformerF.Core().V1().Secrets().Informer()
+	if err := saTokenSecretInformer.AddIndexers(
+		cache.Indexers{
+			bootstrap.ServiceAccountTokenSecretsIndexKey: bootstrap.IndexServiceAccountTokenSecretsByServiceAccount,
+		},
+	); err != nil {
+		setupLog.Error(err, "failed to add indexers to serviceaccount token secret informer")
+		exitCode = 1
+		return
+	}
+
 	klusterletWorksInformerF := informerswork.NewFilteredSharedInformerFactory(
 		workClient,
 		10*time.Minute,
@@ -297,6 +320,7 @@ func main() {
 		cache.Indexers{
 			importconfig.KlusterletConfigBootstrapKubeConfigSecretsIndexKey: importconfig.IndexKlusterletConfigByBootstrapKubeConfigSecrets(),
 			importconfig.KlusterletConfigCustomizedCAConfigmapsIndexKey:     importconfig.IndexKlusterletConfigByCustomizedCAConfigmaps(),
+			importconfig.KlusterletConfigExtraManifestsIndexKey:             importconfig.IndexKlusterletConfigByExtraManifests(),
 		},
 	); err != nil {
 		setupLog.Error(err, "failed to add indexers to klusterletconfig informer")
@@ -311,7 +335,7 @@ func main() {
 	managedclusterInformer := managedclusterInformerF.Cluster().V1().ManagedClusters().Informer()
 	if err := managedclusterInformer.AddIndexers(
 		cache.Indexers{
-			importconfig.ManagedClusterKlusterletConfigAnnotationIndexKey: importconfig.IndexManagedClusterByKlusterletconfigAnnotation,
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey: source.IndexManagedClusterByKlusterletconfigAnnotation,
 		},
 	); err != nil {
 		setupLog.Error(err, "failed to add indexers to managedcluster informer")
@@ -372,6 +396,8 @@ func main() {
 			ControllerConfigInformer: controllerConfigInformerF.Core().V1().ConfigMaps().Informer(),
 			ControllerConfigLister:   controllerConfigInformerF.Core().V1().ConfigMaps().Lister(),
 			ManagedClusterInformer:   managedclusterInformer,
+
+			ServiceAccountTokenSecretInformer: saTokenSecretInformer,
 		},
 		componentNamespace,
 		flightctlManager,
@@ -393,12 +419,14 @@ func main() {
 	controllerConfigInformerF.Start(ctx.Done())
 	importSecertInformerF.Start(ctx.Done())
 	autoimportSecretInformerF.Start(ctx.Done())
+	saTokenSecretInformerF.Start(ctx.Done())
 	klusterletWorksInformerF.Start(ctx.Done())
 	hostedWorksInformerF.Start(ctx.Done())
 	klusterletconfigInformerF.Start(ctx.Done())
 	managedclusterInformerF.Start(ctx.Done())
 	importSecertInformerF.WaitForCacheSync(ctx.Done())
 	autoimportSecretInformerF.WaitForCacheSync(ctx.Done())
+	saTokenSecretInformerF.WaitForCacheSync(ctx.Done())
 	klusterletWorksInformerF.WaitForCacheSync(ctx.Done())
 	hostedWorksInformerF.WaitForCacheSync(ctx.Done())
 	klusterletconfigInformerF.WaitForCacheSync(ctx.Done())
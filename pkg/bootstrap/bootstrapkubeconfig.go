@@ -20,12 +20,10 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apiserver/pkg/storage/names"
 	"k8s.io/client-go/kubernetes"
-	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
-	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	"k8s.io/client-go/tools/cache"
 	certutil "k8s.io/client-go/util/cert"
 	"k8s.io/klog/v2"
 	"k8s.io/utils/ptr"
@@ -56,41 +54,12 @@ const (
 // create kubeconfig for bootstrap
 func CreateBootstrapKubeConfig(ctxClusterName string,
 	kubeAPIServer, proxyURL, ca string, caData, token []byte) ([]byte, error) {
-
-	// CA file and CA data cannot be set simultaneously
-	if len(caData) > 0 {
-		ca = ""
-	}
-
-	bootstrapConfig := clientcmdapi.Config{
-		// Define a cluster stanza based on the bootstrap kubeconfig.
-		Clusters: map[string]*clientcmdapi.Cluster{
-			ctxClusterName: {
-				Server:                   kubeAPIServer,
-				InsecureSkipTLSVerify:    false,
-				CertificateAuthority:     ca,
-				CertificateAuthorityData: caData,
-				ProxyURL:                 proxyURL,
-			}},
-		// Define auth based on the obtained client cert.
-		AuthInfos: map[string]*clientcmdapi.AuthInfo{"default-auth": {
-			Token: string(token),
-		}},
-		// Define a context that connects the auth info and cluster, and set it as the default
-		Contexts: map[string]*clientcmdapi.Context{"default-context": {
-			Cluster:   ctxClusterName,
-			AuthInfo:  "default-auth",
-			Namespace: "default",
-		}},
-		CurrentContext: "default-context",
-	}
-
-	boostrapConfigData, err := runtime.Encode(clientcmdlatest.Codec, &bootstrapConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	return boostrapConfigData, err
+	return CreateBootstrapKubeConfigWithEndpoints(ctxClusterName, []KubeAPIServerEndpointConfig{{
+		KubeAPIServer: kubeAPIServer,
+		ProxyURL:      proxyURL,
+		CA:            ca,
+		CAData:        caData,
+	}}, token)
 }
 
 // GetKubeAPIServerConfig returns the expected apiserver url, proxy url, ca file and ca data
@@ -108,7 +77,7 @@ func GetKubeAPIServerConfig(ctx context.Context, clientHolder *helpers.ClientHol
 	proxy, _ := GetProxySettings(klusterletConfig)
 
 	// get the apiserver address
-	url, err := GetKubeAPIServerAddress(ctx, clientHolder.RuntimeClient, klusterletConfig)
+	url, err := GetKubeAPIServerAddress(ctx, clientHolder, klusterletConfig)
 	if err != nil {
 		return "", "", "", nil, err
 	}
@@ -179,17 +148,18 @@ func RequestSAToken(ctx context.Context, kubeClient kubernetes.Interface, saName
 	return []byte(tokenRequest.Status.Token), tokenCreation, expiration, nil
 }
 
-// GetBootstrapToken lists the secrets from the managed cluster namespace to look for the managed cluster
-// bootstrap token firstly (compatibility with the ocp that version is less than 4.11), if there is no
-// valid token found, uses tokenrequest to request token.
-func GetBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface,
+// GetBootstrapToken looks for the managed cluster bootstrap token from the service account token secrets
+// firstly (compatibility with the ocp that version is less than 4.11), if there is no valid token found, uses
+// tokenrequest to request token. The secrets are got from the secretIndexer if it is provided, otherwise they
+// are listed from the managed cluster namespace.
+func GetBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface, secretIndexer cache.Indexer,
 	saName, secretNamespace string, tokenExpirationSeconds int64) ([]byte, []byte, []byte, error) {
-	secrets, err := kubeClient.CoreV1().Secrets(secretNamespace).List(ctx, metav1.ListOptions{})
+	secrets, err := GetServiceAccountTokenSecretCandidates(ctx, kubeClient, secretIndexer, saName, secretNamespace)
 	if err != nil {
 		return nil, nil, nil, err
 	}
 
-	for _, secret := range secrets.Items {
+	for _, secret := range secrets {
 		if secret.Type != corev1.SecretTypeServiceAccountToken {
 			continue
 		}
@@ -236,7 +206,10 @@ func GetBootstrapToken(ctx context.Context, kubeClient kubernetes.Interface,
 	return RequestSAToken(ctx, kubeClient, saName, secretNamespace, tokenExpirationSeconds)
 }
 
-func GetKubeAPIServerAddress(ctx context.Context, client client.Client,
+// GetKubeAPIServerAddress returns the hub kube apiserver URL. The URL in the klusterletConfig is used if it is
+// specified, otherwise it is got from the Infrastructure on the OCP, or discovered from the kube-public/cluster-info
+// configmap, the kube-system/kubeadm-config configmap or the in-cluster config on the non-OCP.
+func GetKubeAPIServerAddress(ctx context.Context, clientHolder *helpers.ClientHolder,
 	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) (string, error) {
 
 	if klusterletConfig != nil && klusterletConfig.Spec.HubKubeAPIServerConfig != nil &&
@@ -252,11 +225,16 @@ func GetKubeAPIServerAddress(ctx context.Context, client client.Client,
 	}
 
 	if !helpers.DeployOnOCP {
-		return "", fmt.Errorf("failed get Hub kube apiserver on non-OCP cluster")
+		discovered, err := discoverHubKubeAPIServer(ctx, clientHolder.KubeClient)
+		if err != nil {
+			return "", err
+		}
+		klog.V(5).Infof("Using the hub kube apiserver %s discovered from the %s", discovered.url, discovered.source)
+		return discovered.url, nil
 	}
 
 	infraConfig := &ocinfrav1.Infrastructure{}
-	err := client.Get(ctx, types.NamespacedName{Name: clusterSingletonName}, infraConfig)
+	err := clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: clusterSingletonName}, infraConfig)
 	if err == nil {
 		return infraConfig.Status.APIServerURL, nil
 	}
@@ -381,8 +359,17 @@ func getCustomCAData(ctx context.Context, clientHolder *helpers.ClientHolder,
 
 func autoDetectCAData(ctx context.Context, clientHolder *helpers.ClientHolder, kubeAPIServer string,
 	caNamespace string) ([]byte, error) {
-	// get caBundle from the kube-root-ca.crt configmap in the pod namespace for non-ocp case.
+	// get caBundle from the kube-public/cluster-info configmap if the apiserver is discovered from it, otherwise
+	// from the kube-root-ca.crt configmap in the pod namespace for non-ocp case.
 	if !helpers.DeployOnOCP {
+		discovered, err := discoverFromClusterInfo(ctx, clientHolder.KubeClient)
+		if err != nil {
+			return nil, err
+		}
+		if discovered != nil && discovered.url == kubeAPIServer && len(discovered.caData) > 0 {
+			klog.V(5).Info(fmt.Sprintf("Using the ca of the %s as the bootstrap ca", discovered.source))
+			return discovered.caData, nil
+		}
 		return getKubeRootCABundle(ctx, clientHolder, caNamespace)
 	}
 
@@ -743,7 +743,10 @@ func TestGetKubeAPIServerAddress(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got, err := GetKubeAPIServerAddress(context.Background(), tt.args.client, tt.args.klusterletConfig)
+			got, err := GetKubeAPIServerAddress(context.Background(), &helpers.ClientHolder{
+				KubeClient:    kubefake.NewSimpleClientset(),
+				RuntimeClient: tt.args.client,
+			}, tt.args.klusterletConfig)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("getKubeAPIServerAddress() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1599,7 +1602,7 @@ func TestGetBootstrapToken(t *testing.T) {
 				return false, nil, nil
 			})
 
-			token, creation, expiration, err := GetBootstrapToken(ctx, fakeKubeClient, tt.saName, tt.secretNamespace, tt.tokenExpirationSeconds)
+			token, creation, expiration, err := GetBootstrapToken(ctx, fakeKubeClient, nil, tt.saName, tt.secretNamespace, tt.tokenExpirationSeconds)
 
 			if err != nil {
 				t.Errorf("GetBootstrapToken() error = %v", err)
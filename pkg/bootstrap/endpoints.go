@@ -0,0 +1,378 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
+	certutil "k8s.io/client-go/util/cert"
+	"k8s.io/klog/v2"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+const (
+	bootstrapKubeconfigContext = "default-context"
+	bootstrapKubeconfigAuth    = "default-auth"
+
+	// EndpointBootstrapKubeConfigSecretPrefix is the name prefix of the bootstrap kubeconfig secrets of the hub
+	// kube apiserver endpoints, the name of the secret of the first endpoint ends with 0.
+	EndpointBootstrapKubeConfigSecretPrefix = constants.DefaultBootstrapHubKubeConfigSecretName + "-endpoint-"
+)
+
+// HubKubeAPIServerEndpoint is an endpoint of the hub kube apiserver that is specified with the
+// HubKubeAPIServerEndpointsAnnotation of the KlusterletConfig.
+type HubKubeAPIServerEndpoint struct {
+	// URL is the URL of the hub kube apiserver.
+	URL string `json:"url"`
+	// ProxyURL is the URL of the proxy to connect to the hub kube apiserver, no proxy is used if it is empty.
+	ProxyURL string `json:"proxyURL,omitempty"`
+	// CABundle is the base64 encoded PEM CA bundle of the hub kube apiserver, the CA is auto detected if it
+	// is empty.
+	CABundle []byte `json:"caBundle,omitempty"`
+}
+
+// KubeAPIServerEndpointConfig is the apiserver url, proxy url, ca file and ca data of an endpoint in the
+// bootstrap kubeconfig.
+type KubeAPIServerEndpointConfig struct {
+	KubeAPIServer string
+	ProxyURL      string
+	CA            string
+	CAData        []byte
+}
+
+func (c KubeAPIServerEndpointConfig) equal(o KubeAPIServerEndpointConfig) bool {
+	return c.KubeAPIServer == o.KubeAPIServer && c.ProxyURL == o.ProxyURL && c.CA == o.CA &&
+		bytes.Equal(c.CAData, o.CAData)
+}
+
+// ParseHubKubeAPIServerEndpoints parses the value of the HubKubeAPIServerEndpointsAnnotation, it returns an
+// error if an endpoint has an invalid URL, proxy URL or CA bundle, or the URL of an endpoint is duplicated.
+func ParseHubKubeAPIServerEndpoints(val string) ([]HubKubeAPIServerEndpoint, error) {
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	endpoints := []HubKubeAPIServerEndpoint{}
+	if err := json.Unmarshal([]byte(val), &endpoints); err != nil {
+		return nil, fmt.Errorf("failed to parse the hub kube apiserver endpoints: %v", err)
+	}
+
+	urls := map[string]bool{}
+	for _, endpoint := range endpoints {
+		if u, err := url.Parse(endpoint.URL); err != nil || u.Scheme != "https" || len(u.Host) == 0 {
+			return nil, fmt.Errorf("the hub kube apiserver endpoint URL %q is not a valid https URL", endpoint.URL)
+		}
+		if urls[endpoint.URL] {
+			return nil, fmt.Errorf("the hub kube apiserver endpoint URL %q is duplicated", endpoint.URL)
+		}
+		urls[endpoint.URL] = true
+
+		if len(endpoint.ProxyURL) > 0 {
+			if u, err := url.Parse(endpoint.ProxyURL); err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+				return nil, fmt.Errorf("the proxy URL %q of the hub kube apiserver endpoint %s is invalid",
+					endpoint.ProxyURL, endpoint.URL)
+			}
+		}
+
+		if len(endpoint.CABundle) > 0 {
+			if _, err := certutil.ParseCertsPEM(endpoint.CABundle); err != nil {
+				return nil, fmt.Errorf("the CA bundle of the hub kube apiserver endpoint %s is invalid: %v",
+					endpoint.URL, err)
+			}
+		}
+	}
+
+	return endpoints, nil
+}
+
+// GetKubeAPIServerEndpointConfigs returns the configs of the endpoints in the bootstrap kubeconfig. If the hub
+// kube apiserver endpoints are specified, one config is returned for each of them in order, otherwise the only
+// config is the one returned by GetKubeAPIServerConfig.
+func GetKubeAPIServerEndpointConfigs(ctx context.Context, clientHolder *helpers.ClientHolder, ns string,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig, endpoints []HubKubeAPIServerEndpoint,
+	selfManaged bool) ([]KubeAPIServerEndpointConfig, error) {
+	if len(endpoints) == 0 {
+		kubeAPIServer, proxyURL, ca, caData, err := GetKubeAPIServerConfig(ctx, clientHolder, ns,
+			klusterletConfig, selfManaged)
+		if err != nil {
+			return nil, err
+		}
+		return []KubeAPIServerEndpointConfig{{
+			KubeAPIServer: kubeAPIServer,
+			ProxyURL:      proxyURL,
+			CA:            ca,
+			CAData:        caData,
+		}}, nil
+	}
+
+	configs := []KubeAPIServerEndpointConfig{}
+	for _, endpoint := range endpoints {
+		caData := endpoint.CABundle
+		if len(caData) == 0 {
+			endpointCAData, err := getEndpointCAData(ctx, clientHolder, endpoint.URL, ns, klusterletConfig)
+			if err != nil {
+				return nil, err
+			}
+			caData = endpointCAData
+		}
+
+		merged, err := mergeCertificateData(caData)
+		if err != nil {
+			return nil, err
+		}
+
+		configs = append(configs, KubeAPIServerEndpointConfig{
+			KubeAPIServer: endpoint.URL,
+			ProxyURL:      endpoint.ProxyURL,
+			CAData:        merged,
+		})
+	}
+	return configs, nil
+}
+
+// getEndpointCAData returns the CA data of an endpoint that has no CA bundle with the server verification strategy
+// of the KlusterletConfig, e.g. no CA data is returned with the UseSystemTruststore strategy. The CA is auto
+// detected if the strategy is not specified.
+func getEndpointCAData(ctx context.Context, clientHolder *helpers.ClientHolder, kubeAPIServer, ns string,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, error) {
+	if klusterletConfig != nil && klusterletConfig.Spec.HubKubeAPIServerConfig != nil {
+		return getKubeAPIServerCADataFromConfig(ctx, clientHolder, kubeAPIServer, ns,
+			klusterletConfig.Spec.HubKubeAPIServerConfig)
+	}
+	return autoDetectCAData(ctx, clientHolder, kubeAPIServer, ns)
+}
+
+// CreateBootstrapKubeConfigWithEndpoints creates the bootstrap kubeconfig with one cluster and context for
+// each endpoint, the context of the first endpoint is the current context.
+func CreateBootstrapKubeConfigWithEndpoints(ctxClusterName string, endpoints []KubeAPIServerEndpointConfig,
+	token []byte) ([]byte, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no hub kube apiserver endpoint is specified")
+	}
+
+	bootstrapConfig := clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{bootstrapKubeconfigAuth: {
+			Token: string(token),
+		}},
+		Contexts:       map[string]*clientcmdapi.Context{},
+		CurrentContext: bootstrapKubeconfigContext,
+	}
+
+	for i, endpoint := range endpoints {
+		clusterName, contextName := ctxClusterName, bootstrapKubeconfigContext
+		if i > 0 {
+			clusterName = fmt.Sprintf("%s-%d", ctxClusterName, i)
+			contextName = fmt.Sprintf("%s-%d", bootstrapKubeconfigContext, i)
+		}
+
+		// CA file and CA data cannot be set simultaneously
+		ca := endpoint.CA
+		if len(endpoint.CAData) > 0 {
+			ca = ""
+		}
+
+		bootstrapConfig.Clusters[clusterName] = &clientcmdapi.Cluster{
+			Server:                   endpoint.KubeAPIServer,
+			InsecureSkipTLSVerify:    false,
+			CertificateAuthority:     ca,
+			CertificateAuthorityData: endpoint.CAData,
+			ProxyURL:                 endpoint.ProxyURL,
+		}
+		bootstrapConfig.Contexts[contextName] = &clientcmdapi.Context{
+			Cluster:   clusterName,
+			AuthInfo:  bootstrapKubeconfigAuth,
+			Namespace: "default",
+		}
+	}
+
+	return runtime.Encode(clientcmdlatest.Codec, &bootstrapConfig)
+}
+
+// SplitBootstrapKubeConfig splits the bootstrap kubeconfig into one bootstrap kubeconfig for each of its endpoints,
+// the kubeconfig of the endpoint of the current context is the first one.
+func SplitBootstrapKubeConfig(kubeconfigData []byte) ([][]byte, error) {
+	_, _, _, _, token, ctxClusterName, err := helpers.ParseKubeConfigData(kubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+	endpoints, err := ParseBootstrapKubeConfigEndpoints(kubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigs := [][]byte{}
+	for _, endpoint := range endpoints {
+		kubeconfig, err := CreateBootstrapKubeConfigWithEndpoints(ctxClusterName,
+			[]KubeAPIServerEndpointConfig{endpoint}, []byte(token))
+		if err != nil {
+			return nil, err
+		}
+		kubeconfigs = append(kubeconfigs, kubeconfig)
+	}
+	return kubeconfigs, nil
+}
+
+// JoinBootstrapKubeConfigs joins the bootstrap kubeconfigs of the endpoints that are split by
+// SplitBootstrapKubeConfig into one bootstrap kubeconfig, the endpoint of the first one is the current context.
+func JoinBootstrapKubeConfigs(kubeconfigs [][]byte) ([]byte, error) {
+	if len(kubeconfigs) == 0 {
+		return nil, fmt.Errorf("no bootstrap kubeconfig is specified")
+	}
+
+	var requiredToken, requiredCtxClusterName string
+	endpoints := []KubeAPIServerEndpointConfig{}
+	for i, kubeconfig := range kubeconfigs {
+		kubeAPIServer, proxyURL, ca, caData, token, ctxClusterName, err := helpers.ParseKubeConfigData(kubeconfig)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			requiredToken, requiredCtxClusterName = token, ctxClusterName
+		}
+		if token != requiredToken || ctxClusterName != requiredCtxClusterName {
+			return nil, fmt.Errorf("the bootstrap kubeconfigs have different tokens or cluster names")
+		}
+		endpoints = append(endpoints, KubeAPIServerEndpointConfig{
+			KubeAPIServer: kubeAPIServer,
+			ProxyURL:      proxyURL,
+			CA:            ca,
+			CAData:        caData,
+		})
+	}
+
+	return CreateBootstrapKubeConfigWithEndpoints(requiredCtxClusterName, endpoints, []byte(requiredToken))
+}
+
+// setEndpointBootstrapKubeConfigs renders one bootstrap kubeconfig secret for each of the endpoints of the
+// bootstrap kubeconfig and enables the MultipleHubs feature, since the registration agent only uses the current
+// context of a bootstrap kubeconfig. The agent bootstraps with the secrets in order and switches to the next one
+// once it loses the connection to the hub over the hub connection timeout. Nothing is changed if the bootstrap
+// kubeconfig has only one endpoint.
+func setEndpointBootstrapKubeConfigs(cc *chart.KlusterletChartConfig) error {
+	config, err := clientcmd.Load([]byte(cc.BootstrapHubKubeConfig))
+	if err != nil || len(config.Contexts) < 2 {
+		return nil
+	}
+
+	kubeconfigs, err := SplitBootstrapKubeConfig([]byte(cc.BootstrapHubKubeConfig))
+	if err != nil {
+		return fmt.Errorf("failed to split the bootstrap kubeconfig: %w", err)
+	}
+
+	secrets := []operatorv1.KubeConfigSecret{}
+	bootstrapKubeConfigs := []chart.BootStrapKubeConfig{}
+	for i, kubeconfig := range kubeconfigs {
+		name := fmt.Sprintf("%s%d", EndpointBootstrapKubeConfigSecretPrefix, i)
+		secrets = append(secrets, operatorv1.KubeConfigSecret{Name: name})
+		bootstrapKubeConfigs = append(bootstrapKubeConfigs, chart.BootStrapKubeConfig{
+			Name:       name,
+			KubeConfig: string(kubeconfig),
+		})
+	}
+
+	enableMultipleHubsFeatureGate(cc)
+	cc.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs = operatorv1.BootstrapKubeConfigs{
+		Type: operatorv1.LocalSecrets,
+		LocalSecrets: &operatorv1.LocalSecretsConfig{
+			KubeConfigSecrets: secrets,
+		},
+	}
+	cc.MultiHubBootstrapHubKubeConfigs = bootstrapKubeConfigs
+	return nil
+}
+
+// ParseBootstrapKubeConfigEndpoints returns the configs of all of the endpoints in the bootstrap kubeconfig,
+// the endpoint of the current context is the first one.
+func ParseBootstrapKubeConfigEndpoints(kubeconfigData []byte) ([]KubeAPIServerEndpointConfig, error) {
+	config, err := clientcmd.Load(kubeconfigData)
+	if err != nil {
+		return nil, err
+	}
+
+	contextNames := []string{}
+	for name := range config.Contexts {
+		if name != config.CurrentContext {
+			contextNames = append(contextNames, name)
+		}
+	}
+	sort.Strings(contextNames)
+	contextNames = append([]string{config.CurrentContext}, contextNames...)
+
+	endpoints := []KubeAPIServerEndpointConfig{}
+	for _, name := range contextNames {
+		context, ok := config.Contexts[name]
+		if !ok {
+			return nil, fmt.Errorf("failed to get context %s", name)
+		}
+		cluster, ok := config.Clusters[context.Cluster]
+		if !ok {
+			return nil, fmt.Errorf("failed to get cluster %s of context %s", context.Cluster, name)
+		}
+		endpoints = append(endpoints, KubeAPIServerEndpointConfig{
+			KubeAPIServer: cluster.Server,
+			ProxyURL:      cluster.ProxyURL,
+			CA:            cluster.CertificateAuthority,
+			CAData:        cluster.CertificateAuthorityData,
+		})
+	}
+	return endpoints, nil
+}
+
+// ValidateBootstrapKubeconfigEndpoints validates the endpoints of the bootstrap kubeconfig with the required
+// endpoints and the context cluster name. The endpoints are compared as a set, so the bootstrap kubeconfig is
+// still valid if only the order of the endpoints is changed.
+func ValidateBootstrapKubeconfigEndpoints(clusterName string,
+	endpoints []KubeAPIServerEndpointConfig, ctxClusterName string,
+	requiredEndpoints []KubeAPIServerEndpointConfig, requiredCtxClusterName string) bool {
+	if len(endpoints) != len(requiredEndpoints) {
+		klog.Infof("KubeAPIServer endpoints are invalid for the managed cluster %s: %d endpoints, %d required",
+			clusterName, len(endpoints), len(requiredEndpoints))
+		return false
+	}
+
+	if len(endpoints) == 1 {
+		return ValidateBootstrapKubeconfig(clusterName,
+			endpoints[0].KubeAPIServer, endpoints[0].ProxyURL, endpoints[0].CA, endpoints[0].CAData, ctxClusterName,
+			requiredEndpoints[0].KubeAPIServer, requiredEndpoints[0].ProxyURL, requiredEndpoints[0].CA,
+			requiredEndpoints[0].CAData, requiredCtxClusterName)
+	}
+
+	for _, required := range requiredEndpoints {
+		found := false
+		for _, endpoint := range endpoints {
+			if endpoint.equal(required) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			klog.Infof("KubeAPIServer endpoint %s is invalid for the managed cluster %s",
+				required.KubeAPIServer, clusterName)
+			return false
+		}
+	}
+
+	if ctxClusterName != requiredCtxClusterName {
+		klog.Infof("Context cluster name is invalid for the managed cluster %s: %s", clusterName, ctxClusterName)
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,333 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	apifeature "open-cluster-management.io/api/feature"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestParseHubKubeAPIServerEndpoints(t *testing.T) {
+	certData, _, err := testinghelpers.NewRootCA("test ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	endpointsWithCA, _ := json.Marshal([]HubKubeAPIServerEndpoint{
+		{URL: "https://api1.example.com:6443", CABundle: certData},
+		{URL: "https://api2.example.com:6443", ProxyURL: "http://proxy.example.com:3128"},
+	})
+
+	cases := []struct {
+		name              string
+		val               string
+		expectedEndpoints []HubKubeAPIServerEndpoint
+		expectedErr       bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			val:  string(endpointsWithCA),
+			expectedEndpoints: []HubKubeAPIServerEndpoint{
+				{URL: "https://api1.example.com:6443", CABundle: certData},
+				{URL: "https://api2.example.com:6443", ProxyURL: "http://proxy.example.com:3128"},
+			},
+		},
+		{
+			name:        "invalid json",
+			val:         "https://api1.example.com:6443",
+			expectedErr: true,
+		},
+		{
+			name:        "not https",
+			val:         `[{"url":"http://api1.example.com:6443"}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "duplicated url",
+			val:         `[{"url":"https://api1.example.com:6443"},{"url":"https://api1.example.com:6443"}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid proxy url",
+			val:         `[{"url":"https://api1.example.com:6443","proxyURL":"proxy"}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid ca bundle",
+			val:         `[{"url":"https://api1.example.com:6443","caBundle":"aW52YWxpZA=="}]`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			endpoints, err := ParseHubKubeAPIServerEndpoints(c.val)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if !reflect.DeepEqual(endpoints, c.expectedEndpoints) {
+				t.Errorf("expected endpoints %v, but got %v", c.expectedEndpoints, endpoints)
+			}
+		})
+	}
+}
+
+func TestValidateBootstrapKubeconfigEndpoints(t *testing.T) {
+	endpoint1 := KubeAPIServerEndpointConfig{KubeAPIServer: "https://api1.example.com:6443", CAData: []byte("ca1")}
+	endpoint2 := KubeAPIServerEndpointConfig{KubeAPIServer: "https://api2.example.com:6443", CAData: []byte("ca2")}
+	endpoint3 := KubeAPIServerEndpointConfig{KubeAPIServer: "https://api3.example.com:6443", CAData: []byte("ca3")}
+
+	cases := []struct {
+		name              string
+		endpoints         []KubeAPIServerEndpointConfig
+		requiredEndpoints []KubeAPIServerEndpointConfig
+		ctxClusterName    string
+		expected          bool
+	}{
+		{
+			name:              "single endpoint",
+			endpoints:         []KubeAPIServerEndpointConfig{endpoint1},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint1},
+			ctxClusterName:    "default-cluster",
+			expected:          true,
+		},
+		{
+			name:              "reordered endpoints",
+			endpoints:         []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint2, endpoint1},
+			ctxClusterName:    "default-cluster",
+			expected:          true,
+		},
+		{
+			name:              "endpoint added",
+			endpoints:         []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint1, endpoint2, endpoint3},
+			ctxClusterName:    "default-cluster",
+			expected:          false,
+		},
+		{
+			name:              "endpoint replaced",
+			endpoints:         []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint1, endpoint3},
+			ctxClusterName:    "default-cluster",
+			expected:          false,
+		},
+		{
+			name:      "ca changed",
+			endpoints: []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint1, {
+				KubeAPIServer: endpoint2.KubeAPIServer,
+				CAData:        []byte("new-ca2"),
+			}},
+			ctxClusterName: "default-cluster",
+			expected:       false,
+		},
+		{
+			name:              "context cluster name changed",
+			endpoints:         []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			requiredEndpoints: []KubeAPIServerEndpointConfig{endpoint1, endpoint2},
+			ctxClusterName:    "old-cluster",
+			expected:          false,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			valid := ValidateBootstrapKubeconfigEndpoints("cluster1", c.endpoints, c.ctxClusterName,
+				c.requiredEndpoints, "default-cluster")
+			if valid != c.expected {
+				t.Errorf("expected %v, but got %v", c.expected, valid)
+			}
+		})
+	}
+}
+
+func TestCreateBootstrapKubeConfigWithEndpoints(t *testing.T) {
+	endpoints := []KubeAPIServerEndpointConfig{
+		{KubeAPIServer: "https://api1.example.com:6443", CA: "/ca.crt"},
+		{KubeAPIServer: "https://api2.example.com:6443", ProxyURL: "http://proxy.example.com:3128", CAData: []byte("ca2")},
+		{KubeAPIServer: "https://api3.example.com:6443", CA: "/ca.crt", CAData: []byte("ca3")},
+	}
+	kubeconfigData, err := CreateBootstrapKubeConfigWithEndpoints("default-cluster", endpoints, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	parsed, err := ParseBootstrapKubeConfigEndpoints(kubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	// the CA file is ignored if the CA data is set
+	endpoints[2].CA = ""
+	if !reflect.DeepEqual(parsed, endpoints) {
+		t.Errorf("expected endpoints %v, but got %v", endpoints, parsed)
+	}
+
+	if _, err := CreateBootstrapKubeConfigWithEndpoints("default-cluster", nil, []byte("token")); err == nil {
+		t.Errorf("expected error without endpoints")
+	}
+}
+
+func TestSplitBootstrapKubeConfig(t *testing.T) {
+	endpoints := []KubeAPIServerEndpointConfig{
+		{KubeAPIServer: "https://api1.example.com:6443", CAData: []byte("ca1")},
+		{KubeAPIServer: "https://api2.example.com:6443", ProxyURL: "http://proxy.example.com:3128", CAData: []byte("ca2")},
+	}
+	kubeconfigData, err := CreateBootstrapKubeConfigWithEndpoints("default-cluster", endpoints, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	kubeconfigs, err := SplitBootstrapKubeConfig(kubeconfigData)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(kubeconfigs) != 2 {
+		t.Fatalf("expected 2 kubeconfigs, but got %d", len(kubeconfigs))
+	}
+	for i, kubeconfig := range kubeconfigs {
+		server, _, _, _, token, ctxClusterName, err := helpers.ParseKubeConfigData(kubeconfig)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if server != endpoints[i].KubeAPIServer || token != "token" || ctxClusterName != "default-cluster" {
+			t.Errorf("unexpected kubeconfig of the endpoint %d: %s", i, string(kubeconfig))
+		}
+	}
+
+	joined, err := JoinBootstrapKubeConfigs(kubeconfigs)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(joined, kubeconfigData) {
+		t.Errorf("expected the joined kubeconfig %s, but got %s", string(kubeconfigData), string(joined))
+	}
+
+	other, err := CreateBootstrapKubeConfigWithEndpoints("default-cluster", endpoints[:1], []byte("other"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if _, err := JoinBootstrapKubeConfigs([][]byte{kubeconfigs[1], other}); err == nil {
+		t.Errorf("expected error for the kubeconfigs with different tokens")
+	}
+}
+
+func TestGenerateWithEndpointBootstrapKubeConfigs(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+	t.Setenv(constants.TLSProfileSyncImageEnvVarName,
+		"quay.io/open-cluster-management/managedcluster-import-controller:latest")
+
+	kubeconfigData, err := CreateBootstrapKubeConfigWithEndpoints("default-cluster", []KubeAPIServerEndpointConfig{
+		{KubeAPIServer: "https://api1.example.com:6443", CAData: []byte("ca1")},
+		{KubeAPIServer: "https://api2.example.com:6443", CAData: []byte("ca2")},
+	}, []byte("token"))
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	clientHolder := &helpers.ClientHolder{
+		KubeClient:          kubeClient,
+		RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		ImageRegistryClient: imageregistry.NewClient(kubeClient),
+	}
+	manifestsBytes, _, _, err := NewKlusterletManifestsConfig(operatorv1.InstallModeDefault, "test", kubeconfigData).
+		WithoutImagePullSecretGenerate().
+		WithManagedCluster(&clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}).
+		Generate(context.TODO(), clientHolder)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	secrets := map[string][]byte{}
+	var klusterlet *operatorv1.Klusterlet
+	for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+		switch obj := helpers.MustCreateObject(yaml).(type) {
+		case *corev1.Secret:
+			secrets[obj.Name] = obj.Data["kubeconfig"]
+		case *operatorv1.Klusterlet:
+			klusterlet = obj
+		}
+	}
+
+	if _, ok := secrets[constants.DefaultBootstrapHubKubeConfigSecretName]; ok {
+		t.Errorf("expected the bootstrap kubeconfig is split into the endpoint secrets")
+	}
+	for i, server := range []string{"https://api1.example.com:6443", "https://api2.example.com:6443"} {
+		kubeconfig, ok := secrets[fmt.Sprintf("%s%d", EndpointBootstrapKubeConfigSecretPrefix, i)]
+		if !ok {
+			t.Fatalf("expected the bootstrap kubeconfig secret of the endpoint %d, but got %v", i, secrets)
+		}
+		if actual, _, _, _, _, _, _ := helpers.ParseKubeConfigData(kubeconfig); actual != server {
+			t.Errorf("expected the server %s of the endpoint %d, but got %s", server, i, actual)
+		}
+	}
+
+	if klusterlet == nil {
+		t.Fatalf("expected the klusterlet is rendered")
+	}
+	bootstrapKubeConfigs := klusterlet.Spec.RegistrationConfiguration.BootstrapKubeConfigs
+	if bootstrapKubeConfigs.Type != operatorv1.LocalSecrets || bootstrapKubeConfigs.LocalSecrets == nil ||
+		len(bootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets) != 2 {
+		t.Errorf("expected the local secrets of the endpoints, but got %v", bootstrapKubeConfigs)
+	}
+	multipleHubsEnabled := false
+	for _, f := range klusterlet.Spec.RegistrationConfiguration.FeatureGates {
+		if f.Feature == string(apifeature.MultipleHubs) && f.Mode == operatorv1.FeatureGateModeTypeEnable {
+			multipleHubsEnabled = true
+		}
+	}
+	if !multipleHubsEnabled {
+		t.Errorf("expected the MultipleHubs feature is enabled")
+	}
+}
+
+func TestGetKubeAPIServerEndpointConfigsWithSystemTruststore(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset()
+	clientHolder := &helpers.ClientHolder{
+		KubeClient:    kubeClient,
+		RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).Build(),
+	}
+	klusterletConfig := &klusterletconfigv1alpha1.KlusterletConfig{
+		Spec: klusterletconfigv1alpha1.KlusterletConfigSpec{
+			HubKubeAPIServerConfig: &klusterletconfigv1alpha1.KubeAPIServerConfig{
+				ServerVerificationStrategy: klusterletconfigv1alpha1.ServerVerificationStrategyUseSystemTruststore,
+			},
+		},
+	}
+
+	configs, err := GetKubeAPIServerEndpointConfigs(context.TODO(), clientHolder, "test", klusterletConfig,
+		[]HubKubeAPIServerEndpoint{
+			{URL: "https://api1.example.com:6443"},
+			{URL: "https://api2.example.com:6443"},
+		}, false)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, config := range configs {
+		if len(config.CAData) != 0 || len(config.CA) != 0 {
+			t.Errorf("expected no CA with the system truststore, but got %v", config)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// WithExtraManifests sets the ConfigMaps and Secrets in the import controller namespace whose YAML manifests are
+// appended to the klusterlet manifests. They are ignored in the Hosted and SingletonHosted modes since the
+// manifests are applied on the hosting cluster.
+func (c *KlusterletManifestsConfig) WithExtraManifests(refs []helpers.ManifestReference) *KlusterletManifestsConfig {
+	c.extraManifests = refs
+	return c
+}
+
+// extraManifestsSource is the data and the version of a ConfigMap or Secret of the extra manifests
+type extraManifestsSource struct {
+	ref             helpers.ManifestReference
+	resourceVersion string
+	data            map[string][]byte
+}
+
+func getExtraManifestsSources(ctx context.Context, clientHolder *helpers.ClientHolder,
+	refs []helpers.ManifestReference) ([]extraManifestsSource, error) {
+	ns := os.Getenv(constants.PodNamespaceEnvVarName)
+	sources := []extraManifestsSource{}
+	for _, ref := range refs {
+		source := extraManifestsSource{ref: ref, data: map[string][]byte{}}
+		switch ref.Kind {
+		case helpers.ManifestReferenceKindConfigMap:
+			cm, err := clientHolder.KubeClient.CoreV1().ConfigMaps(ns).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the extra manifests %s: %v", ref, err)
+			}
+			source.resourceVersion = cm.ResourceVersion
+			for key, val := range cm.Data {
+				source.data[key] = []byte(val)
+			}
+		case helpers.ManifestReferenceKindSecret:
+			secret, err := clientHolder.KubeClient.CoreV1().Secrets(ns).Get(ctx, ref.Name, metav1.GetOptions{})
+			if err != nil {
+				return nil, fmt.Errorf("failed to get the extra manifests %s: %v", ref, err)
+			}
+			source.resourceVersion = secret.ResourceVersion
+			source.data = secret.Data
+		default:
+			return nil, fmt.Errorf("unsupported extra manifests %s", ref)
+		}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+// getExtraManifests returns the YAML manifests in the data of the given ConfigMaps and Secrets, the manifests of
+// each ConfigMap or Secret are taken in the order of their keys. It returns an error if a manifest cannot be
+// applied on the managed cluster during the import. Like the klusterlet manifests, the namespaces are put before
+// the other manifests once they are aggregated.
+func getExtraManifests(ctx context.Context, clientHolder *helpers.ClientHolder,
+	refs []helpers.ManifestReference) ([][]byte, error) {
+	sources, err := getExtraManifestsSources(ctx, clientHolder, refs)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := [][]byte{}
+	for _, source := range sources {
+		keys := make([]string, 0, len(source.data))
+		for key := range source.data {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			reader := utilyaml.NewYAMLReader(bufio.NewReader(bytes.NewReader(source.data[key])))
+			for {
+				raw, err := reader.Read()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return nil, fmt.Errorf("failed to read the extra manifests in %s of %s: %v", key, source.ref, err)
+				}
+				raw = bytes.TrimSpace(raw)
+				if len(raw) == 0 {
+					continue
+				}
+
+				content := map[string]interface{}{}
+				if err := yaml.Unmarshal(raw, &content); err != nil {
+					return nil, fmt.Errorf("failed to parse the extra manifests in %s of %s: %v", key, source.ref, err)
+				}
+				if len(content) == 0 {
+					// the comments only
+					continue
+				}
+				obj := &unstructured.Unstructured{Object: content}
+				if err := helpers.ValidateImportObject(raw); err != nil {
+					return nil, fmt.Errorf("invalid extra manifest %s %s in %s of %s: %v",
+						obj.GetKind(), obj.GetName(), key, source.ref, err)
+				}
+				objects = append(objects, raw)
+			}
+		}
+	}
+	return objects, nil
+}
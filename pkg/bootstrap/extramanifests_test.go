@@ -0,0 +1,195 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+const (
+	extraNamespaceYaml = `apiVersion: v1
+kind: Namespace
+metadata:
+  name: extra
+`
+	extraConfigMapYaml = `# the proxy settings
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: proxy
+  namespace: extra
+data:
+  proxy: http://proxy.example.com:3128
+---
+# only comments
+`
+	extraNetworkPolicyYaml = `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: allow-egress
+  namespace: open-cluster-management-agent
+spec:
+  podSelector: {}
+  policyTypes:
+  - Egress
+`
+	extraServiceYaml = `apiVersion: v1
+kind: Service
+metadata:
+  name: test
+  namespace: extra
+`
+)
+
+func TestKlusterletConfigGenerateWithExtraManifests(t *testing.T) {
+	t.Setenv(constants.PodNamespaceEnvVarName, "multicluster-engine")
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+	extraConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "extra-cm",
+			Namespace:       "multicluster-engine",
+			ResourceVersion: "1",
+		},
+		Data: map[string]string{
+			"2-configmap.yaml": extraConfigMapYaml,
+			"1-namespace.yaml": extraNamespaceYaml,
+		},
+	}
+	extraSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "extra-secret",
+			Namespace:       "multicluster-engine",
+			ResourceVersion: "1",
+		},
+		Data: map[string][]byte{
+			"networkpolicy.yaml": []byte(extraNetworkPolicyYaml),
+		},
+	}
+	unsupportedConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "unsupported",
+			Namespace: "multicluster-engine",
+		},
+		Data: map[string]string{
+			"service.yaml": extraServiceYaml,
+		},
+	}
+
+	cases := []struct {
+		name           string
+		mode           operatorv1.InstallMode
+		extraManifests []helpers.ManifestReference
+		expectedErr    string
+		validateFunc   func(t *testing.T, objs []runtime.Object)
+	}{
+		{
+			name: "default",
+			mode: operatorv1.InstallModeDefault,
+			extraManifests: []helpers.ManifestReference{
+				{Kind: helpers.ManifestReferenceKindSecret, Name: "extra-secret"},
+				{Kind: helpers.ManifestReferenceKindConfigMap, Name: "extra-cm"},
+			},
+			validateFunc: func(t *testing.T, objs []runtime.Object) {
+				ns, ok := objs[0].(*corev1.Namespace)
+				if !ok || ns.Name != constants.DefaultKlusterletNamespace {
+					t.Errorf("expected the klusterlet namespace is the first object, but got %v", objs[0])
+				}
+
+				// the namespaces are applied before the other extra manifests
+				extraObjs := objs[len(objs)-3:]
+				if ns, ok := extraObjs[0].(*corev1.Namespace); !ok || ns.Name != "extra" {
+					t.Errorf("expected the extra namespace, but got %v", extraObjs[0])
+				}
+				found := map[string]bool{}
+				for _, obj := range extraObjs[1:] {
+					switch o := obj.(type) {
+					case *networkingv1.NetworkPolicy:
+						found[o.Name] = true
+					case *corev1.ConfigMap:
+						found[o.Name] = true
+					}
+				}
+				if !found["allow-egress"] || !found["proxy"] {
+					t.Errorf("expected the extra network policy and configmap, but got %v", extraObjs[1:])
+				}
+			},
+		},
+		{
+			name: "hosted",
+			mode: operatorv1.InstallModeHosted,
+			extraManifests: []helpers.ManifestReference{
+				{Kind: helpers.ManifestReferenceKindConfigMap, Name: "extra-cm"},
+			},
+			validateFunc: func(t *testing.T, objs []runtime.Object) {
+				for _, obj := range objs {
+					if cm, ok := obj.(*corev1.ConfigMap); ok && cm.Name == "proxy" {
+						t.Errorf("expected the extra manifests are ignored in the hosted mode")
+					}
+				}
+			},
+		},
+		{
+			name: "unsupported kind",
+			mode: operatorv1.InstallModeDefault,
+			extraManifests: []helpers.ManifestReference{
+				{Kind: helpers.ManifestReferenceKindConfigMap, Name: "unsupported"},
+			},
+			expectedErr: "unsupported kind Service",
+		},
+		{
+			name: "not found",
+			mode: operatorv1.InstallModeSingleton,
+			extraManifests: []helpers.ManifestReference{
+				{Kind: helpers.ManifestReferenceKindSecret, Name: "nonexistent"},
+			},
+			expectedErr: "failed to get the extra manifests secret/nonexistent",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset(extraConfigMap, extraSecret, unsupportedConfigMap)
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			config := NewKlusterletManifestsConfig(c.mode, "test", []byte("bootstrap kubeconfig")).
+				WithoutImagePullSecretGenerate().
+				WithExtraManifests(c.extraManifests)
+			manifestsBytes, _, _, err := config.Generate(context.TODO(), clientHolder)
+			if len(c.expectedErr) != 0 {
+				if err == nil || !strings.Contains(err.Error(), c.expectedErr) {
+					t.Fatalf("expected error %q, but got %v", c.expectedErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			objs := []runtime.Object{}
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				objs = append(objs, helpers.MustCreateObject(yaml))
+			}
+			c.validateFunc(t, objs)
+		})
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/klog/v2"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// the cluster-info configmap is published by the kubeadm (and kind) in the kube-public namespace, it
+	// includes a kubeconfig with the hub kube apiserver URL and CA
+	clusterInfoNamespace     = "kube-public"
+	clusterInfoConfigMapName = "cluster-info"
+	clusterInfoKubeconfigKey = "kubeconfig"
+
+	// the kubeadm-config configmap is created by the kubeadm in the kube-system namespace, it includes the
+	// control plane endpoint of the hub kube apiserver
+	kubeadmConfigNamespace         = "kube-system"
+	kubeadmConfigConfigMapName     = "kubeadm-config"
+	kubeadmClusterConfigurationKey = "ClusterConfiguration"
+	kubeadmDefaultAPIServerPort    = "6443"
+)
+
+// inClusterConfig returns the in-cluster config, it is a variable for testing
+var inClusterConfig = rest.InClusterConfig
+
+// discoveredHubKubeAPIServer is the hub kube apiserver URL and CA that are discovered on a non-OCP hub
+type discoveredHubKubeAPIServer struct {
+	url    string
+	caData []byte
+	source string
+}
+
+// discoverHubKubeAPIServer discovers the kube apiserver URL and CA of a non-OCP hub. It looks for them in the
+// kube-public/cluster-info configmap firstly, then the control plane endpoint in the kube-system/kubeadm-config
+// configmap, and falls back to the in-cluster config. The CA is empty if it is not found with the URL.
+func discoverHubKubeAPIServer(ctx context.Context, kubeClient kubernetes.Interface) (*discoveredHubKubeAPIServer, error) {
+	discovered, err := discoverFromClusterInfo(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if discovered != nil {
+		return discovered, nil
+	}
+
+	discovered, err = discoverFromKubeadmConfig(ctx, kubeClient)
+	if err != nil {
+		return nil, err
+	}
+	if discovered != nil {
+		return discovered, nil
+	}
+
+	config, err := inClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the hub kube apiserver, please use klusterletConfig to set "+
+			"the hub kubeAPIServer URL: %v", err)
+	}
+	discovered = &discoveredHubKubeAPIServer{
+		url:    config.Host,
+		caData: config.CAData,
+		source: "in-cluster config",
+	}
+	if len(discovered.caData) == 0 && len(config.CAFile) != 0 {
+		if caData, err := os.ReadFile(config.CAFile); err == nil {
+			discovered.caData = caData
+		}
+	}
+	return discovered, nil
+}
+
+func discoverFromClusterInfo(ctx context.Context, kubeClient kubernetes.Interface) (*discoveredHubKubeAPIServer, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(clusterInfoNamespace).Get(ctx, clusterInfoConfigMapName,
+		metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	kubeconfigData, ok := cm.Data[clusterInfoKubeconfigKey]
+	if !ok {
+		return nil, nil
+	}
+
+	kubeconfig, err := clientcmd.Load([]byte(kubeconfigData))
+	if err != nil {
+		klog.Warningf("failed to load the kubeconfig of %s/%s: %v", clusterInfoNamespace, clusterInfoConfigMapName, err)
+		return nil, nil
+	}
+
+	// the kubeconfig of the cluster-info has only one cluster without context
+	for _, cluster := range kubeconfig.Clusters {
+		if cluster == nil || len(cluster.Server) == 0 {
+			continue
+		}
+		return &discoveredHubKubeAPIServer{
+			url:    cluster.Server,
+			caData: cluster.CertificateAuthorityData,
+			source: fmt.Sprintf("%s/%s", clusterInfoNamespace, clusterInfoConfigMapName),
+		}, nil
+	}
+
+	return nil, nil
+}
+
+func discoverFromKubeadmConfig(ctx context.Context, kubeClient kubernetes.Interface) (*discoveredHubKubeAPIServer, error) {
+	cm, err := kubeClient.CoreV1().ConfigMaps(kubeadmConfigNamespace).Get(ctx, kubeadmConfigConfigMapName,
+		metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	clusterConfiguration := struct {
+		ControlPlaneEndpoint string `json:"controlPlaneEndpoint"`
+	}{}
+	if err := yaml.Unmarshal([]byte(cm.Data[kubeadmClusterConfigurationKey]), &clusterConfiguration); err != nil {
+		klog.Warningf("failed to parse the %s of %s/%s: %v", kubeadmClusterConfigurationKey,
+			kubeadmConfigNamespace, kubeadmConfigConfigMapName, err)
+		return nil, nil
+	}
+
+	endpoint := strings.TrimSpace(clusterConfiguration.ControlPlaneEndpoint)
+	if len(endpoint) == 0 {
+		return nil, nil
+	}
+	if _, _, err := net.SplitHostPort(endpoint); err != nil {
+		endpoint = net.JoinHostPort(endpoint, kubeadmDefaultAPIServerPort)
+	}
+
+	return &discoveredHubKubeAPIServer{
+		url:    fmt.Sprintf("https://%s", endpoint),
+		source: fmt.Sprintf("%s/%s", kubeadmConfigNamespace, kubeadmConfigConfigMapName),
+	}, nil
+}
@@ -0,0 +1,212 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func newClusterInfoConfigMap(server string, caData []byte) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterInfoConfigMapName,
+			Namespace: clusterInfoNamespace,
+		},
+		Data: map[string]string{
+			clusterInfoKubeconfigKey: fmt.Sprintf(`apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    certificate-authority-data: %s
+    server: %s
+  name: ""
+contexts: null
+current-context: ""
+users: null
+`, base64.StdEncoding.EncodeToString(caData), server),
+		},
+	}
+}
+
+func newKubeadmConfigConfigMap(controlPlaneEndpoint string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      kubeadmConfigConfigMapName,
+			Namespace: kubeadmConfigNamespace,
+		},
+		Data: map[string]string{
+			kubeadmClusterConfigurationKey: fmt.Sprintf(`apiVersion: kubeadm.k8s.io/v1beta3
+kind: ClusterConfiguration
+clusterName: kind
+controlPlaneEndpoint: %s
+kubernetesVersion: v1.30.0
+`, controlPlaneEndpoint),
+		},
+	}
+}
+
+func TestDiscoverHubKubeAPIServer(t *testing.T) {
+	cases := []struct {
+		name           string
+		objs           []runtime.Object
+		inClusterErr   bool
+		expectedURL    string
+		expectedCAData []byte
+		expectedErr    bool
+	}{
+		{
+			name:           "from cluster-info",
+			objs:           []runtime.Object{newClusterInfoConfigMap("https://kind-control-plane:6443", []byte("ca"))},
+			expectedURL:    "https://kind-control-plane:6443",
+			expectedCAData: []byte("ca"),
+		},
+		{
+			name: "cluster-info takes precedence over kubeadm-config",
+			objs: []runtime.Object{
+				newClusterInfoConfigMap("https://kind-control-plane:6443", []byte("ca")),
+				newKubeadmConfigConfigMap("api.example.com:443"),
+			},
+			expectedURL:    "https://kind-control-plane:6443",
+			expectedCAData: []byte("ca"),
+		},
+		{
+			name:        "from kubeadm-config",
+			objs:        []runtime.Object{newKubeadmConfigConfigMap("api.example.com:443")},
+			expectedURL: "https://api.example.com:443",
+		},
+		{
+			name:        "from kubeadm-config without port",
+			objs:        []runtime.Object{newKubeadmConfigConfigMap("api.example.com")},
+			expectedURL: "https://api.example.com:6443",
+		},
+		{
+			name: "invalid cluster-info",
+			objs: []runtime.Object{
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{Name: clusterInfoConfigMapName, Namespace: clusterInfoNamespace},
+					Data:       map[string]string{clusterInfoKubeconfigKey: "invalid"},
+				},
+				newKubeadmConfigConfigMap("api.example.com:443"),
+			},
+			expectedURL: "https://api.example.com:443",
+		},
+		{
+			name:           "from in-cluster config",
+			objs:           []runtime.Object{newKubeadmConfigConfigMap("")},
+			expectedURL:    "https://10.96.0.1:443",
+			expectedCAData: []byte("in-cluster-ca"),
+		},
+		{
+			name:         "not found",
+			inClusterErr: true,
+			expectedErr:  true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			inClusterConfig = func() (*rest.Config, error) {
+				if c.inClusterErr {
+					return nil, rest.ErrNotInCluster
+				}
+				return &rest.Config{
+					Host:            "https://10.96.0.1:443",
+					TLSClientConfig: rest.TLSClientConfig{CAData: []byte("in-cluster-ca")},
+				}, nil
+			}
+			defer func() { inClusterConfig = rest.InClusterConfig }()
+
+			discovered, err := discoverHubKubeAPIServer(context.TODO(), kubefake.NewSimpleClientset(c.objs...))
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if discovered.url != c.expectedURL {
+				t.Errorf("expected url %s, but got %s", c.expectedURL, discovered.url)
+			}
+			if !reflect.DeepEqual(discovered.caData, c.expectedCAData) {
+				t.Errorf("expected ca data %q, but got %q", c.expectedCAData, discovered.caData)
+			}
+		})
+	}
+}
+
+func TestGetKubeAPIServerConfigOnNonOCP(t *testing.T) {
+	helpers.DeployOnOCP = false
+	defer func() { helpers.DeployOnOCP = true }()
+
+	clusterInfoCAData, _, err := testinghelpers.NewRootCA("cluster info ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	kubeRootCAData, _, err := testinghelpers.NewRootCA("kube root ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	kubeRootCA := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-root-ca.crt", Namespace: "cluster1"},
+		Data:       map[string]string{caCRTKey: string(kubeRootCAData)},
+	}
+
+	cases := []struct {
+		name           string
+		objs           []runtime.Object
+		expectedURL    string
+		expectedCAData []byte
+	}{
+		{
+			name: "use the ca of the cluster-info",
+			objs: []runtime.Object{
+				newClusterInfoConfigMap("https://kind-control-plane:6443", clusterInfoCAData),
+				kubeRootCA,
+			},
+			expectedURL:    "https://kind-control-plane:6443",
+			expectedCAData: clusterInfoCAData,
+		},
+		{
+			name:           "use the kube-root-ca.crt",
+			objs:           []runtime.Object{newKubeadmConfigConfigMap("api.example.com:443"), kubeRootCA},
+			expectedURL:    "https://api.example.com:443",
+			expectedCAData: kubeRootCAData,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:    kubefake.NewSimpleClientset(c.objs...),
+				RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).Build(),
+			}
+			url, _, _, caData, err := GetKubeAPIServerConfig(context.TODO(), clientHolder, "cluster1", nil, false)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if url != c.expectedURL {
+				t.Errorf("expected url %s, but got %s", c.expectedURL, url)
+			}
+			if !reflect.DeepEqual(caData, c.expectedCAData) {
+				t.Errorf("expected ca data %q, but got %q", c.expectedCAData, caData)
+			}
+		})
+	}
+}
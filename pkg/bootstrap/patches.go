@@ -0,0 +1,204 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"encoding/json"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// KlusterletManifestPatchTypeJSON is the JSON patch (RFC 6902)
+	KlusterletManifestPatchTypeJSON = "json"
+	// KlusterletManifestPatchTypeStrategic is the strategic merge patch
+	KlusterletManifestPatchTypeStrategic = "strategic"
+)
+
+// patchScheme includes all of the kinds of the rendered klusterlet manifests, it is used to validate the patched
+// manifests and to look up the patch strategies of the strategic merge patches.
+var patchScheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(patchScheme)
+	_ = operatorv1.Install(patchScheme)
+}
+
+// KlusterletManifestPatch is a patch of the rendered klusterlet manifests
+type KlusterletManifestPatch struct {
+	Target KlusterletManifestPatchTarget `json:"target"`
+	// Type is json or strategic, the default is strategic
+	Type  string          `json:"type,omitempty"`
+	Patch json.RawMessage `json:"patch"`
+}
+
+// KlusterletManifestPatchTarget identifies the patched manifests, the manifests in all of the namespaces are
+// patched if the namespace is not set.
+type KlusterletManifestPatchTarget struct {
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace,omitempty"`
+}
+
+func (t KlusterletManifestPatchTarget) String() string {
+	if len(t.Namespace) == 0 {
+		return fmt.Sprintf("%s %s", t.Kind, t.Name)
+	}
+	return fmt.Sprintf("%s %s/%s", t.Kind, t.Namespace, t.Name)
+}
+
+// KlusterletManifestPatchError is returned by Generate if the patches cannot be applied to the rendered klusterlet
+// manifests, it cannot be resolved without changing the patches.
+type KlusterletManifestPatchError struct {
+	Err error
+}
+
+func (e *KlusterletManifestPatchError) Error() string {
+	return fmt.Sprintf("failed to patch the klusterlet manifests: %v", e.Err)
+}
+
+func (e *KlusterletManifestPatchError) Unwrap() error {
+	return e.Err
+}
+
+// WithManifestPatches sets the patches that are applied in order to the rendered klusterlet manifests, the CRDs
+// and the extra manifests are not patched.
+func (c *KlusterletManifestsConfig) WithManifestPatches(patches []KlusterletManifestPatch) *KlusterletManifestsConfig {
+	c.manifestPatches = patches
+	return c
+}
+
+// ParseKlusterletManifestPatches parses the ordered patches in JSON or YAML format, an empty value means there is
+// no patch.
+func ParseKlusterletManifestPatches(val string) ([]KlusterletManifestPatch, error) {
+	if len(val) == 0 {
+		return nil, nil
+	}
+
+	patches := []KlusterletManifestPatch{}
+	if err := yaml.Unmarshal([]byte(val), &patches); err != nil {
+		return nil, fmt.Errorf("failed to parse the klusterlet manifest patches: %v", err)
+	}
+
+	for i := range patches {
+		patch := &patches[i]
+		if len(patch.Target.Kind) == 0 || len(patch.Target.Name) == 0 {
+			return nil, fmt.Errorf("the kind and name of the target of the patch %d are required", i)
+		}
+		if len(patch.Patch) == 0 {
+			return nil, fmt.Errorf("the patch %d of %s is empty", i, patch.Target)
+		}
+
+		switch patch.Type {
+		case KlusterletManifestPatchTypeJSON:
+			if _, err := jsonpatch.DecodePatch(patch.Patch); err != nil {
+				return nil, fmt.Errorf("invalid json patch %d of %s: %v", i, patch.Target, err)
+			}
+		case KlusterletManifestPatchTypeStrategic, "":
+			patch.Type = KlusterletManifestPatchTypeStrategic
+			content := map[string]interface{}{}
+			if err := json.Unmarshal(patch.Patch, &content); err != nil {
+				return nil, fmt.Errorf("invalid strategic merge patch %d of %s: %v", i, patch.Target, err)
+			}
+		default:
+			return nil, fmt.Errorf("unsupported type %q of the patch %d of %s, it should be %s or %s",
+				patch.Type, i, patch.Target, KlusterletManifestPatchTypeJSON, KlusterletManifestPatchTypeStrategic)
+		}
+	}
+	return patches, nil
+}
+
+// applyKlusterletManifestPatches applies the patches in order to the given YAML manifests. It returns an error if
+// a patch does not match any manifest or the patched manifest is invalid, e.g. it has an unknown field, or its
+// kind, name or namespace is changed.
+func applyKlusterletManifestPatches(objects [][]byte, patches []KlusterletManifestPatch) ([][]byte, error) {
+	if len(patches) == 0 {
+		return objects, nil
+	}
+
+	patched := make([][]byte, len(objects))
+	copy(patched, objects)
+	for i, patch := range patches {
+		matched := false
+		for j, obj := range patched {
+			original := &unstructured.Unstructured{}
+			if err := yaml.Unmarshal(obj, &original.Object); err != nil || len(original.Object) == 0 {
+				continue
+			}
+			if original.GetKind() != patch.Target.Kind || original.GetName() != patch.Target.Name {
+				continue
+			}
+			if len(patch.Target.Namespace) != 0 && original.GetNamespace() != patch.Target.Namespace {
+				continue
+			}
+
+			matched = true
+			result, err := applyKlusterletManifestPatch(original, patch)
+			if err != nil {
+				return nil, &KlusterletManifestPatchError{
+					Err: fmt.Errorf("patch %d of %s: %v", i, patch.Target, err),
+				}
+			}
+			patched[j] = result
+		}
+
+		if !matched {
+			return nil, &KlusterletManifestPatchError{
+				Err: fmt.Errorf("patch %d of %s: the target is not found", i, patch.Target),
+			}
+		}
+	}
+	return patched, nil
+}
+
+func applyKlusterletManifestPatch(original *unstructured.Unstructured, patch KlusterletManifestPatch) ([]byte, error) {
+	gvk := original.GroupVersionKind()
+	typed, err := patchScheme.New(gvk)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported kind %s: %v", gvk, err)
+	}
+
+	originalJSON, err := original.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var patchedJSON []byte
+	switch patch.Type {
+	case KlusterletManifestPatchTypeJSON:
+		jsonPatch, err := jsonpatch.DecodePatch(patch.Patch)
+		if err != nil {
+			return nil, err
+		}
+		if patchedJSON, err = jsonPatch.Apply(originalJSON); err != nil {
+			return nil, err
+		}
+	default:
+		if patchedJSON, err = strategicpatch.StrategicMergePatch(originalJSON, patch.Patch, typed); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &unstructured.Unstructured{}
+	if err := result.UnmarshalJSON(patchedJSON); err != nil {
+		return nil, fmt.Errorf("the patched manifest is invalid: %v", err)
+	}
+	if result.GroupVersionKind() != gvk || result.GetName() != original.GetName() ||
+		result.GetNamespace() != original.GetNamespace() {
+		return nil, fmt.Errorf("the apiVersion, kind, name and namespace cannot be patched")
+	}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructuredWithValidation(
+		result.Object, typed, true); err != nil {
+		return nil, fmt.Errorf("the patched manifest is invalid: %v", err)
+	}
+
+	return yaml.JSONToYAML(patchedJSON)
+}
@@ -0,0 +1,253 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestParseKlusterletManifestPatches(t *testing.T) {
+	cases := []struct {
+		name          string
+		val           string
+		expectedCount int
+		expectedErr   bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "json",
+			val: `[{"target":{"kind":"Deployment","name":"klusterlet"},"type":"json",` +
+				`"patch":[{"op":"add","path":"/metadata/annotations","value":{"a":"b"}}]}]`,
+			expectedCount: 1,
+		},
+		{
+			name: "yaml with the default type",
+			val: `
+- target:
+    kind: Deployment
+    name: klusterlet
+    namespace: open-cluster-management-agent
+  patch:
+    metadata:
+      annotations:
+        a: b
+- target:
+    kind: ClusterRole
+    name: klusterlet-bootstrap-kubeconfig
+  type: json
+  patch:
+  - op: remove
+    path: /rules/0
+`,
+			expectedCount: 2,
+		},
+		{
+			name:        "invalid format",
+			val:         "invalid",
+			expectedErr: true,
+		},
+		{
+			name:        "no target name",
+			val:         `[{"target":{"kind":"Deployment"},"patch":{}}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "no patch",
+			val:         `[{"target":{"kind":"Deployment","name":"klusterlet"}}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported type",
+			val:         `[{"target":{"kind":"Deployment","name":"klusterlet"},"type":"merge","patch":{}}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid json patch",
+			val:         `[{"target":{"kind":"Deployment","name":"klusterlet"},"type":"json","patch":{"op":"add"}}]`,
+			expectedErr: true,
+		},
+		{
+			name:        "invalid strategic merge patch",
+			val:         `[{"target":{"kind":"Deployment","name":"klusterlet"},"patch":[]}]`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			patches, err := ParseKlusterletManifestPatches(c.val)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if len(patches) != c.expectedCount {
+				t.Errorf("expected %d patches, but got %d", c.expectedCount, len(patches))
+			}
+			for _, patch := range patches {
+				if patch.Type != KlusterletManifestPatchTypeJSON && patch.Type != KlusterletManifestPatchTypeStrategic {
+					t.Errorf("unexpected patch type %q", patch.Type)
+				}
+			}
+		})
+	}
+}
+
+func TestKlusterletConfigGenerateWithManifestPatches(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+	cases := []struct {
+		name         string
+		mode         operatorv1.InstallMode
+		patches      string
+		expectedErr  bool
+		validateFunc func(t *testing.T, objs []runtime.Object)
+	}{
+		{
+			name: "strategic merge patch and json patch",
+			mode: operatorv1.InstallModeDefault,
+			patches: `
+- target:
+    kind: Deployment
+    name: klusterlet
+  patch:
+    metadata:
+      annotations:
+        example.com/patched: "true"
+    spec:
+      template:
+        spec:
+          securityContext:
+            seccompProfile:
+              type: RuntimeDefault
+- target:
+    kind: ClusterRole
+    name: klusterlet-bootstrap-kubeconfig
+  type: json
+  patch:
+  - op: add
+    path: /metadata/labels
+    value:
+      example.com/patched: "true"
+`,
+			validateFunc: func(t *testing.T, objs []runtime.Object) {
+				var deploymentPatched, clusterRolePatched bool
+				for _, obj := range objs {
+					switch o := obj.(type) {
+					case *appsv1.Deployment:
+						if o.Name != "klusterlet" {
+							continue
+						}
+						deploymentPatched = o.Annotations["example.com/patched"] == "true" &&
+							o.Spec.Template.Spec.SecurityContext != nil &&
+							o.Spec.Template.Spec.SecurityContext.SeccompProfile != nil &&
+							o.Spec.Template.Spec.SecurityContext.SeccompProfile.Type == corev1.SeccompProfileTypeRuntimeDefault
+						if len(o.Spec.Template.Spec.Containers) == 0 {
+							t.Errorf("expected the containers of the klusterlet deployment are kept")
+						}
+					case *rbacv1.ClusterRole:
+						if o.Name == "klusterlet-bootstrap-kubeconfig" {
+							clusterRolePatched = o.Labels["example.com/patched"] == "true"
+						}
+					}
+				}
+				if !deploymentPatched {
+					t.Errorf("expected the klusterlet deployment is patched")
+				}
+				if !clusterRolePatched {
+					t.Errorf("expected the bootstrap clusterrole is patched")
+				}
+			},
+		},
+		{
+			name: "singleton",
+			mode: operatorv1.InstallModeSingleton,
+			patches: `[{"target":{"kind":"Namespace","name":"open-cluster-management-agent"},` +
+				`"patch":{"metadata":{"labels":{"example.com/patched":"true"}}}}]`,
+			validateFunc: func(t *testing.T, objs []runtime.Object) {
+				ns, ok := objs[0].(*corev1.Namespace)
+				if !ok || ns.Labels["example.com/patched"] != "true" {
+					t.Errorf("expected the agent namespace is patched, but got %v", objs[0])
+				}
+			},
+		},
+		{
+			name:        "target not found",
+			mode:        operatorv1.InstallModeDefault,
+			patches:     `[{"target":{"kind":"Deployment","name":"nonexistent"},"patch":{"metadata":{"labels":{"a":"b"}}}}]`,
+			expectedErr: true,
+		},
+		{
+			name: "unknown field",
+			mode: operatorv1.InstallModeDefault,
+			patches: `[{"target":{"kind":"Deployment","name":"klusterlet"},` +
+				`"patch":{"spec":{"template":{"spec":{"unknownField":true}}}}}]`,
+			expectedErr: true,
+		},
+		{
+			name: "name changed",
+			mode: operatorv1.InstallModeDefault,
+			patches: `[{"target":{"kind":"Deployment","name":"klusterlet"},"type":"json",` +
+				`"patch":[{"op":"replace","path":"/metadata/name","value":"changed"}]}]`,
+			expectedErr: true,
+		},
+		{
+			name: "json patch failure",
+			mode: operatorv1.InstallModeDefault,
+			patches: `[{"target":{"kind":"Deployment","name":"klusterlet"},"type":"json",` +
+				`"patch":[{"op":"remove","path":"/spec/nonexistent"}]}]`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			patches, err := ParseKlusterletManifestPatches(c.patches)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			manifestsBytes, _, _, err := NewKlusterletManifestsConfig(c.mode, "test", []byte("bootstrap kubeconfig")).
+				WithoutImagePullSecretGenerate().
+				WithManifestPatches(patches).
+				Generate(context.TODO(), clientHolder)
+			if c.expectedErr {
+				var patchErr *KlusterletManifestPatchError
+				if !errors.As(err, &patchErr) {
+					t.Fatalf("expected the klusterlet manifest patch error, but got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			objs := []runtime.Object{}
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				objs = append(objs, helpers.MustCreateObject(yaml))
+			}
+			c.validateFunc(t, objs)
+		})
+	}
+}
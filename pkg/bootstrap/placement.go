@@ -0,0 +1,71 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/yaml"
+)
+
+// WithKlusterletOperatorPlacement sets the affinity and the topology spread constraints of the klusterlet operator
+// from the KlusterletConfig, they are used over the managed cluster annotations in the Default and Singleton modes.
+// The nil and empty values are ignored. They are not applied to the agents, since the klusterlet operator places
+// the agents with the nodeSelector and tolerations of the Klusterlet nodePlacement only.
+func (c *KlusterletManifestsConfig) WithKlusterletOperatorPlacement(affinity *corev1.Affinity,
+	topologySpreadConstraints []corev1.TopologySpreadConstraint) *KlusterletManifestsConfig {
+	c.klusterletAffinity = affinity
+	c.klusterletTopologySpreadConstraints = topologySpreadConstraints
+	return c
+}
+
+// injectTopologySpreadConstraints finds the klusterlet-operator Deployment in the rendered objects and sets its
+// topology spread constraints, since they are not supported by the klusterlet chart. The label selector of a
+// constraint is the selector of the Deployment if it is not set. Returns the modified objects slice.
+func injectTopologySpreadConstraints(
+	objects [][]byte,
+	constraints []corev1.TopologySpreadConstraint,
+) ([][]byte, error) {
+	if len(constraints) == 0 {
+		return objects, nil
+	}
+
+	for i, obj := range objects {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(obj, u); err != nil {
+			continue
+		}
+		if u.GetKind() != deploymentKind || u.GetName() != klusterletDeploymentName {
+			continue
+		}
+
+		deployment := &appsv1.Deployment{}
+		if err := yaml.Unmarshal(obj, deployment); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal klusterlet Deployment: %w", err)
+		}
+
+		deployment.Spec.Template.Spec.TopologySpreadConstraints = []corev1.TopologySpreadConstraint{}
+		for _, constraint := range constraints {
+			constraint = *constraint.DeepCopy()
+			if constraint.LabelSelector == nil {
+				constraint.LabelSelector = deployment.Spec.Selector.DeepCopy()
+			}
+			deployment.Spec.Template.Spec.TopologySpreadConstraints = append(
+				deployment.Spec.Template.Spec.TopologySpreadConstraints, constraint)
+		}
+
+		modified, err := yaml.Marshal(deployment)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal modified klusterlet Deployment: %w", err)
+		}
+
+		objects[i] = modified
+		break
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,172 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestKlusterletConfigGenerateWithPlacement(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+	noGPUAffinity := &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{
+					{
+						MatchExpressions: []corev1.NodeSelectorRequirement{
+							{Key: "nvidia.com/gpu.present", Operator: corev1.NodeSelectorOpDoesNotExist},
+						},
+					},
+				},
+			},
+		},
+	}
+	zoneConstraints := []corev1.TopologySpreadConstraint{
+		{
+			MaxSkew:           1,
+			TopologyKey:       "topology.kubernetes.io/zone",
+			WhenUnsatisfiable: corev1.ScheduleAnyway,
+		},
+	}
+
+	cases := []struct {
+		name                string
+		mode                operatorv1.InstallMode
+		annotations         map[string]string
+		affinity            *corev1.Affinity
+		constraints         []corev1.TopologySpreadConstraint
+		expectedErr         bool
+		expectedAffinity    bool
+		expectedTopologyKey string
+	}{
+		{
+			name: "default",
+			mode: operatorv1.InstallModeDefault,
+		},
+		{
+			name: "managed cluster annotations",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/affinity": `{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":` +
+					`{"nodeSelectorTerms":[{"matchExpressions":[{"key":"nvidia.com/gpu.present","operator":"DoesNotExist"}]}]}}}`,
+				"open-cluster-management/topologySpreadConstraints": `[{"maxSkew":1,"topologyKey":"kubernetes.io/hostname",` +
+					`"whenUnsatisfiable":"DoNotSchedule"}]`,
+			},
+			expectedAffinity:    true,
+			expectedTopologyKey: "kubernetes.io/hostname",
+		},
+		{
+			name: "klusterletconfig over managed cluster annotations",
+			mode: operatorv1.InstallModeSingleton,
+			annotations: map[string]string{
+				"open-cluster-management/topologySpreadConstraints": `[{"maxSkew":1,"topologyKey":"kubernetes.io/hostname",` +
+					`"whenUnsatisfiable":"DoNotSchedule"}]`,
+			},
+			affinity:            noGPUAffinity,
+			constraints:         zoneConstraints,
+			expectedAffinity:    true,
+			expectedTopologyKey: "topology.kubernetes.io/zone",
+		},
+		{
+			name: "invalid affinity annotation",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/affinity": `{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{}}}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid topology spread constraints",
+			mode: operatorv1.InstallModeDefault,
+			constraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, WhenUnsatisfiable: corev1.DoNotSchedule},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			manifestsBytes, _, valuesBytes, err := NewKlusterletManifestsConfig(c.mode, "test",
+				[]byte("bootstrap kubeconfig")).
+				WithoutImagePullSecretGenerate().
+				WithManagedCluster(&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test",
+						Annotations: c.annotations,
+					},
+				}).
+				WithKlusterletOperatorPlacement(c.affinity, c.constraints).
+				Generate(context.TODO(), clientHolder)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if c.expectedErr {
+				return
+			}
+
+			var deployment *appsv1.Deployment
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				if obj, ok := helpers.MustCreateObject(yaml).(*appsv1.Deployment); ok {
+					deployment = obj
+				}
+			}
+			if deployment == nil {
+				t.Fatalf("expected the klusterlet operator is rendered")
+			}
+
+			podSpec := deployment.Spec.Template.Spec
+			if (podSpec.Affinity != nil && podSpec.Affinity.NodeAffinity != nil) != c.expectedAffinity {
+				t.Errorf("expected node affinity %v, but got %v", c.expectedAffinity, podSpec.Affinity)
+			}
+
+			values := &chart.KlusterletChartConfig{}
+			if err := yaml.Unmarshal(valuesBytes, values); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if (values.Affinity.NodeAffinity != nil) != c.expectedAffinity {
+				t.Errorf("expected node affinity %v in values, but got %v", c.expectedAffinity, values.Affinity)
+			}
+
+			if len(c.expectedTopologyKey) == 0 {
+				if len(podSpec.TopologySpreadConstraints) != 0 {
+					t.Errorf("expected no topology spread constraints, but got %v", podSpec.TopologySpreadConstraints)
+				}
+				return
+			}
+			if len(podSpec.TopologySpreadConstraints) != 1 ||
+				podSpec.TopologySpreadConstraints[0].TopologyKey != c.expectedTopologyKey {
+				t.Fatalf("expected the topology key %s, but got %v", c.expectedTopologyKey,
+					podSpec.TopologySpreadConstraints)
+			}
+			selector := podSpec.TopologySpreadConstraints[0].LabelSelector
+			if selector == nil || selector.MatchLabels["app"] != "klusterlet" {
+				t.Errorf("expected the label selector of the deployment, but got %v", selector)
+			}
+		})
+	}
+}
@@ -84,6 +84,29 @@ type KlusterletManifestsConfig struct {
 	chartConfig      *chart.KlusterletChartConfig
 	managedCluster   *clusterv1.ManagedCluster
 	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+	extraManifests   []helpers.ManifestReference
+	manifestPatches  []KlusterletManifestPatch
+	// the klusterlet resources, replica count and placement of the KlusterletConfig
+	klusterletResources                 *corev1.ResourceRequirements
+	klusterletReplicaCount              *int
+	klusterletAffinity                  *corev1.Affinity
+	klusterletTopologySpreadConstraints []corev1.TopologySpreadConstraint
+	// the key prefixes of the managed cluster labels that are synced to the agent resources
+	syncLabelPrefixes []string
+}
+
+// KlusterletSettingsError is returned by Generate if the klusterlet resources, replica count, affinity or topology
+// spread constraints are invalid, it cannot be resolved without changing the settings.
+type KlusterletSettingsError struct {
+	Err error
+}
+
+func (e *KlusterletSettingsError) Error() string {
+	return fmt.Sprintf("invalid klusterlet settings: %v", e.Err)
+}
+
+func (e *KlusterletSettingsError) Unwrap() error {
+	return e.Err
 }
 
 func NewKlusterletManifestsConfig(installMode operatorv1.InstallMode,
@@ -172,6 +195,16 @@ func (c *KlusterletManifestsConfig) WithKlusterletConfig(klusterletConfig *klust
 	return c
 }
 
+// WithKlusterletResources sets the resource requirements of the klusterlet operator and agents and the replica count
+// of the klusterlet operator from the KlusterletConfig, they are used over the managed cluster annotations in the
+// Default and Singleton modes. The nil values are ignored.
+func (c *KlusterletManifestsConfig) WithKlusterletResources(resources *corev1.ResourceRequirements,
+	replicaCount *int) *KlusterletManifestsConfig {
+	c.klusterletResources = resources
+	c.klusterletReplicaCount = replicaCount
+	return c
+}
+
 func (c *KlusterletManifestsConfig) WithoutImagePullSecretGenerate() *KlusterletManifestsConfig {
 	c.chartConfig.Images.ImageCredentials.CreateImageCredentials = false
 	return c
@@ -188,11 +221,16 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	installMode := c.chartConfig.Klusterlet.Mode
 	clusterName := c.chartConfig.Klusterlet.ClusterName
 
-	// For image, image pull secret, nodePlacement, we use configurations in klusterletConfig over
-	// configurations in managed cluster annotations.
+	// For image, image pull secret, nodePlacement, resources, replica count, affinity and topology spread
+	// constraints, we use configurations in klusterletConfig over configurations in managed cluster annotations.
 	var kcRegistries []klusterletconfigv1alpha1.Registries
 	var kcNodePlacement *operatorv1.NodePlacement
 	var kcImagePullSecret corev1.ObjectReference
+	var kcResources *corev1.ResourceRequirements
+	var kcReplicaCount *int
+	var kcAffinity *corev1.Affinity
+	var kcTopologySpreadConstraints []corev1.TopologySpreadConstraint
+	var kcSyncLabelPrefixes []string
 	var appliedManifestWorkEvictionGracePeriod string
 
 	switch installMode {
@@ -205,6 +243,11 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 			kcImagePullSecret = c.klusterletConfig.Spec.PullSecret
 			appliedManifestWorkEvictionGracePeriod = c.klusterletConfig.Spec.AppliedManifestWorkEvictionGracePeriod
 		}
+		kcResources = c.klusterletResources
+		kcReplicaCount = c.klusterletReplicaCount
+		kcAffinity = c.klusterletAffinity
+		kcTopologySpreadConstraints = c.klusterletTopologySpreadConstraints
+		kcSyncLabelPrefixes = c.syncLabelPrefixes
 	default:
 		return nil, nil, nil, fmt.Errorf("invalid install mode: %s", installMode)
 	}
@@ -257,6 +300,86 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 	c.chartConfig.Tolerations = tolerations
 	c.chartConfig.Klusterlet.NodePlacement.Tolerations = tolerations
 
+	// Resources, the specified limits and requests override the default ones of the operator, and the agents use
+	// the same resource requirements as the operator once they are specified
+	resources := kcResources
+	if resources == nil {
+		resources, err = helpers.GetResourcesFromManagedClusterAnnotations(managedClusterAnnotations)
+		if err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{
+				Err: fmt.Errorf("get klusterlet resources for cluster %s failed: %v", clusterName, err),
+			}
+		}
+	}
+	if resources != nil {
+		merged := mergeResourceRequirements(c.chartConfig.Resources, *resources)
+		if err := helpers.ValidateResourceRequirements(merged); err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{Err: fmt.Errorf("invalid klusterlet resources %v", err)}
+		}
+		c.chartConfig.Resources = merged
+		c.chartConfig.Klusterlet.ResourceRequirement = &operatorv1.ResourceRequirement{
+			Type:                 operatorv1.ResourceQosClassResourceRequirement,
+			ResourceRequirements: merged.DeepCopy(),
+		}
+	}
+
+	// ReplicaCount of the operator
+	replicaCount := kcReplicaCount
+	if replicaCount == nil {
+		replicaCount, err = helpers.GetReplicaCountFromManagedClusterAnnotations(managedClusterAnnotations)
+		if err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{
+				Err: fmt.Errorf("get klusterlet replicas for cluster %s failed: %v", clusterName, err),
+			}
+		}
+	}
+	if replicaCount != nil {
+		if err := helpers.ValidateReplicaCount(*replicaCount); err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{Err: fmt.Errorf("invalid klusterlet replicas %v", err)}
+		}
+		c.chartConfig.ReplicaCount = *replicaCount
+	}
+
+	// Affinity of the operator, the agents are placed by the operator with the nodeSelector and tolerations only
+	affinity := kcAffinity
+	if affinity == nil {
+		affinity, err = helpers.GetAffinityFromManagedClusterAnnotations(managedClusterAnnotations)
+		if err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{
+				Err: fmt.Errorf("get affinity for cluster %s failed: %v", clusterName, err),
+			}
+		}
+	}
+	if err := helpers.ValidateAffinity(affinity); err != nil {
+		return nil, nil, nil, &KlusterletSettingsError{Err: fmt.Errorf("invalid affinity %v", err)}
+	}
+	if affinity != nil {
+		c.chartConfig.Affinity = *affinity
+	}
+
+	// TopologySpreadConstraints of the operator, they are injected into the rendered operator deployment
+	topologySpreadConstraints := kcTopologySpreadConstraints
+	if len(topologySpreadConstraints) == 0 {
+		topologySpreadConstraints, err = helpers.GetTopologySpreadConstraintsFromManagedClusterAnnotations(
+			managedClusterAnnotations)
+		if err != nil {
+			return nil, nil, nil, &KlusterletSettingsError{
+				Err: fmt.Errorf("get topologySpreadConstraints for cluster %s failed: %v", clusterName, err),
+			}
+		}
+	}
+	if err := helpers.ValidateTopologySpreadConstraints(topologySpreadConstraints); err != nil {
+		return nil, nil, nil, &KlusterletSettingsError{Err: fmt.Errorf("invalid topologySpreadConstraints %v", err)}
+	}
+
+	// Sync the managed cluster labels with the prefixes to the agent resources, the labels are set on the klusterlet
+	// and the operator syncs them
+	var syncLabels map[string]string
+	if len(kcSyncLabelPrefixes) != 0 {
+		c.chartConfig.EnableSyncLabels = true
+		syncLabels = getSyncLabels(c.managedCluster, kcSyncLabelPrefixes)
+	}
+
 	c.chartConfig.Klusterlet.Name, c.chartConfig.Klusterlet.Namespace = getKlusterletNamespaceName(
 		c.klusterletConfig, clusterName, managedClusterAnnotations, installMode)
 
@@ -335,20 +458,7 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		}
 
 		// If MultipleHubs feature is not set in featureGates field, set it to enable.
-		var multipleHubFeatureSet bool
-		for _, f := range c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates {
-			if f.Feature == string(apifeature.MultipleHubs) {
-				multipleHubFeatureSet = true
-			}
-		}
-
-		if !multipleHubFeatureSet {
-			c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates = append(c.chartConfig.Klusterlet.RegistrationConfiguration.FeatureGates,
-				operatorv1.FeatureGate{
-					Feature: string(apifeature.MultipleHubs),
-					Mode:    operatorv1.FeatureGateModeTypeEnable,
-				})
-		}
+		enableMultipleHubsFeatureGate(c.chartConfig)
 		c.chartConfig.Klusterlet.RegistrationConfiguration.BootstrapKubeConfigs = *c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.DeepCopy()
 
 		// Only append the current hub KubeConfigSecret if the strategy is IncludeCurrentHub
@@ -375,6 +485,11 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 			})
 		}
 		c.chartConfig.MultiHubBootstrapHubKubeConfigs = bootstrapKubeConfigSecrets
+	} else if !localCluster {
+		// the klusterlet fails over between the hub kube apiserver endpoints with the MultipleHubs feature
+		if err := setEndpointBootstrapKubeConfigs(c.chartConfig); err != nil {
+			return nil, nil, nil, err
+		}
 	}
 
 	// Set MCE reserved clusterclaims
@@ -418,11 +533,37 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		return nil, nil, nil, err
 	}
 
+	objects, err = injectKlusterletLabels(objects, syncLabels)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the extra manifests are appended after the klusterlet manifests
+	var extraManifestsBytes []byte
+	if len(c.extraManifests) != 0 &&
+		(installMode == operatorv1.InstallModeDefault || installMode == operatorv1.InstallModeSingleton) {
+		extraObjects, err := getExtraManifests(ctx, clientHolder, c.extraManifests)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		extraManifestsBytes = AggregateObjects(extraObjects)
+	}
+
 	if c.chartConfig.NoOperator {
+		objects, err = applyKlusterletManifestPatches(objects, c.manifestPatches)
+		if err != nil {
+			return nil, nil, nil, err
+		}
 		manifestsBytes := AggregateObjects(objects)
+		manifestsBytes = append(manifestsBytes, extraManifestsBytes...)
 		return manifestsBytes, nil, valuesBytes, nil
 	}
 
+	objects, err = injectTopologySpreadConstraints(objects, topologySpreadConstraints)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
 	additionalManifestsBytes, err := filesToTemplateBytes(additionalClusterRoleFiles, c.chartConfig)
 	if err != nil {
 		return nil, nil, nil, err
@@ -445,12 +586,44 @@ func (c *KlusterletManifestsConfig) Generate(ctx context.Context,
 		additionalManifestsBytes = append(additionalManifestsBytes, tlsRBACBytes...)
 	}
 
+	// the patches are applied to both the rendered objects and the additional manifests
+	if len(c.manifestPatches) != 0 {
+		additionalObjects := helpers.SplitYamls(additionalManifestsBytes)
+		patchedObjects, err := applyKlusterletManifestPatches(
+			append(append([][]byte{}, objects...), additionalObjects...), c.manifestPatches)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		objects = patchedObjects[:len(objects)]
+		additionalManifestsBytes = []byte{}
+		for _, obj := range patchedObjects[len(objects):] {
+			additionalManifestsBytes = append(additionalManifestsBytes, []byte(constants.YamlSperator)...)
+			additionalManifestsBytes = append(additionalManifestsBytes, obj...)
+		}
+	}
+
 	crdBytes := AggregateObjects(crds)
 	manifestsBytes := AggregateObjects(objects)
 	manifestsBytes = append(manifestsBytes, additionalManifestsBytes...)
+	manifestsBytes = append(manifestsBytes, extraManifestsBytes...)
 	return manifestsBytes, crdBytes, valuesBytes, nil
 }
 
+// enableMultipleHubsFeatureGate enables the MultipleHubs feature gate of the registration agent if it is not set
+func enableMultipleHubsFeatureGate(cc *chart.KlusterletChartConfig) {
+	for _, f := range cc.Klusterlet.RegistrationConfiguration.FeatureGates {
+		if f.Feature == string(apifeature.MultipleHubs) {
+			return
+		}
+	}
+
+	cc.Klusterlet.RegistrationConfiguration.FeatureGates = append(cc.Klusterlet.RegistrationConfiguration.FeatureGates,
+		operatorv1.FeatureGate{
+			Feature: string(apifeature.MultipleHubs),
+			Mode:    operatorv1.FeatureGateModeTypeEnable,
+		})
+}
+
 func setClusterClaimConfiguation(cc *chart.KlusterletChartConfig, kc *klusterletconfigv1alpha1.KlusterletConfig) {
 	defaultConfiguation := &operatorv1.ClusterClaimConfiguration{
 		ReservedClusterClaimSuffixes: reservedClusterClaimSuffixes,
@@ -557,6 +730,35 @@ func AggregateObjects(objects [][]byte) []byte {
 	return manifests.Bytes()
 }
 
+// mergeResourceRequirements returns the default resource requirements overridden by the specified limits and
+// requests, it is the same as how the chart values are merged. A default request that is greater than the
+// specified limit is lowered to the limit, e.g. the memory request is 32Mi if only the 32Mi memory limit is
+// specified.
+func mergeResourceRequirements(defaults, specified corev1.ResourceRequirements) corev1.ResourceRequirements {
+	merged := *defaults.DeepCopy()
+	for name, quantity := range specified.Limits {
+		if merged.Limits == nil {
+			merged.Limits = corev1.ResourceList{}
+		}
+		merged.Limits[name] = quantity.DeepCopy()
+
+		if _, ok := specified.Requests[name]; ok {
+			continue
+		}
+		if request, ok := merged.Requests[name]; ok && request.Cmp(quantity) > 0 {
+			merged.Requests[name] = quantity.DeepCopy()
+		}
+	}
+	for name, quantity := range specified.Requests {
+		if merged.Requests == nil {
+			merged.Requests = corev1.ResourceList{}
+		}
+		merged.Requests[name] = quantity.DeepCopy()
+	}
+	merged.Claims = specified.Claims
+	return merged
+}
+
 // installNoOperator return true if operator is not to be installed.
 func installNoOperator(mode operatorv1.InstallMode, config *klusterletconfigv1alpha1.KlusterletConfig) bool {
 	if mode == operatorv1.InstallModeHosted || mode == operatorv1.InstallModeSingletonHosted {
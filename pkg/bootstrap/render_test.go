@@ -2,6 +2,7 @@ package bootstrap
 
 import (
 	"context"
+	"errors"
 	"os"
 	"strings"
 	"testing"
@@ -21,6 +22,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	schedulingv1 "k8s.io/api/scheduling/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
@@ -1720,3 +1722,242 @@ func TestGetGRCPCaBundleFromConfigMap(t *testing.T) {
 		})
 	}
 }
+
+func TestKlusterletConfigGenerateWithResources(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+
+	kcResources := &corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("8Gi"),
+		},
+		Requests: corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("128Mi"),
+			corev1.ResourceCPU:    resource.MustParse("100m"),
+		},
+	}
+	kcReplicas := 2
+
+	cases := []struct {
+		name                string
+		mode                operatorv1.InstallMode
+		annotations         map[string]string
+		resources           *corev1.ResourceRequirements
+		replicas            *int
+		expectedErr         bool
+		expectedResources   corev1.ResourceRequirements
+		expectedReplicas    int32
+		expectedRequirement bool
+	}{
+		{
+			name: "default",
+			mode: operatorv1.InstallModeDefault,
+			expectedResources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+					corev1.ResourceCPU:    resource.MustParse("50m"),
+				},
+			},
+			expectedReplicas: 1,
+		},
+		{
+			name: "managed cluster annotations",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/klusterlet-resources": `{"requests":{"cpu":"10m","memory":"32Mi"}}`,
+				"open-cluster-management/klusterlet-replicas":  "3",
+			},
+			expectedResources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("32Mi"),
+					corev1.ResourceCPU:    resource.MustParse("10m"),
+				},
+			},
+			expectedReplicas:    3,
+			expectedRequirement: true,
+		},
+		{
+			name: "klusterletconfig over managed cluster annotations",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/klusterlet-resources": `{"requests":{"cpu":"10m","memory":"32Mi"}}`,
+				"open-cluster-management/klusterlet-replicas":  "3",
+			},
+			resources:           kcResources,
+			replicas:            &kcReplicas,
+			expectedResources:   *kcResources,
+			expectedReplicas:    2,
+			expectedRequirement: true,
+		},
+		{
+			name:                "singleton",
+			mode:                operatorv1.InstallModeSingleton,
+			resources:           kcResources,
+			replicas:            &kcReplicas,
+			expectedResources:   *kcResources,
+			expectedReplicas:    2,
+			expectedRequirement: true,
+		},
+		{
+			name: "cpu limit",
+			mode: operatorv1.InstallModeDefault,
+			resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("500m"),
+				},
+			},
+			expectedResources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+					corev1.ResourceCPU:    resource.MustParse("500m"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+					corev1.ResourceCPU:    resource.MustParse("50m"),
+				},
+			},
+			expectedReplicas:    1,
+			expectedRequirement: true,
+		},
+		{
+			name: "memory limit lower than the default request",
+			mode: operatorv1.InstallModeDefault,
+			resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("32Mi"),
+				},
+			},
+			expectedResources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("32Mi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("32Mi"),
+					corev1.ResourceCPU:    resource.MustParse("50m"),
+				},
+			},
+			expectedReplicas:    1,
+			expectedRequirement: true,
+		},
+		{
+			name: "requests greater than the default limits",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/klusterlet-resources": `{"requests":{"memory":"4Gi"}}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid annotation",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/klusterlet-resources": `{"requests":{"cpu":"invalid"}}`,
+			},
+			expectedErr: true,
+		},
+		{
+			name: "requests greater than limits",
+			mode: operatorv1.InstallModeDefault,
+			resources: &corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("64Mi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("128Mi"),
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid replicas",
+			mode: operatorv1.InstallModeDefault,
+			annotations: map[string]string{
+				"open-cluster-management/klusterlet-replicas": "0",
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			manifestsBytes, _, valuesBytes, err := NewKlusterletManifestsConfig(c.mode, "test",
+				[]byte("bootstrap kubeconfig")).
+				WithoutImagePullSecretGenerate().
+				WithManagedCluster(&v1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "test",
+						Annotations: c.annotations,
+					},
+				}).
+				WithKlusterletResources(c.resources, c.replicas).
+				Generate(context.TODO(), clientHolder)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if c.expectedErr {
+				var settingsErr *KlusterletSettingsError
+				if !errors.As(err, &settingsErr) {
+					t.Errorf("expected a KlusterletSettingsError, but got %v", err)
+				}
+				return
+			}
+
+			var deployment *appv1.Deployment
+			var klusterlet *operatorv1.Klusterlet
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				switch obj := helpers.MustCreateObject(yaml).(type) {
+				case *appv1.Deployment:
+					deployment = obj
+				case *operatorv1.Klusterlet:
+					klusterlet = obj
+				}
+			}
+			if deployment == nil || klusterlet == nil {
+				t.Fatalf("expected the klusterlet operator and klusterlet are rendered")
+			}
+
+			if *deployment.Spec.Replicas != c.expectedReplicas {
+				t.Errorf("expected replicas %d, but got %d", c.expectedReplicas, *deployment.Spec.Replicas)
+			}
+			if !equality.Semantic.DeepEqual(deployment.Spec.Template.Spec.Containers[0].Resources, c.expectedResources) {
+				t.Errorf("expected operator resources %v, but got %v",
+					c.expectedResources, deployment.Spec.Template.Spec.Containers[0].Resources)
+			}
+
+			if !c.expectedRequirement {
+				if klusterlet.Spec.ResourceRequirement != nil &&
+					klusterlet.Spec.ResourceRequirement.Type != operatorv1.ResourceQosClassDefault {
+					t.Errorf("expected no agent resource requirement, but got %v", klusterlet.Spec.ResourceRequirement)
+				}
+			} else if klusterlet.Spec.ResourceRequirement == nil ||
+				klusterlet.Spec.ResourceRequirement.Type != operatorv1.ResourceQosClassResourceRequirement ||
+				!equality.Semantic.DeepEqual(*klusterlet.Spec.ResourceRequirement.ResourceRequirements,
+					c.expectedResources) {
+				t.Errorf("expected agent resources %v, but got %v", c.expectedResources, klusterlet.Spec.ResourceRequirement)
+			}
+
+			values := &chart.KlusterletChartConfig{}
+			if err := yaml.Unmarshal(valuesBytes, values); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if values.ReplicaCount != int(c.expectedReplicas) {
+				t.Errorf("expected replicaCount %d in values, but got %d", c.expectedReplicas, values.ReplicaCount)
+			}
+			if !equality.Semantic.DeepEqual(values.Resources, c.expectedResources) {
+				t.Errorf("expected resources %v in values, but got %v", c.expectedResources, values.Resources)
+			}
+		})
+	}
+}
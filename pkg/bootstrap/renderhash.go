@@ -0,0 +1,233 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/fs"
+	"os"
+	"runtime/debug"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	klusterletchart "open-cluster-management.io/ocm/deploy/klusterlet/chart"
+	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
+
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+// renderHashVersion identifies the klusterlet manifests rendering of the running controller, it is derived from the
+// build revision of the controller and the embedded manifests and chart templates, so the existing hashes are
+// invalidated once the controller is upgraded.
+var renderHashVersion = sync.OnceValue(func() string {
+	h := sha256.New()
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "vcs.revision" {
+				h.Write([]byte(setting.Value))
+			}
+		}
+	}
+
+	for _, files := range []fs.FS{ManifestFiles, klusterletchart.ChartFiles} {
+		// the embedded files are always readable
+		_ = fs.WalkDir(files, ".", func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := fs.ReadFile(files, path)
+			if err != nil {
+				return err
+			}
+			h.Write([]byte(path))
+			h.Write(data)
+			return nil
+		})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+})
+
+// renderClusterAnnotations returns the keys of the managed cluster annotations that are read by Generate
+func renderClusterAnnotations() []string {
+	return append(helpers.KlusterletSettingsAnnotations(),
+		imageregistry.ClusterImageRegistriesAnnotation,
+		constants.KlusterletNamespaceAnnotation,
+		apiconstants.AnnotationKlusterletConfig,
+	)
+}
+
+// renderInputs includes all of the inputs of the klusterlet manifests rendering
+type renderInputs struct {
+	Version                             string                                         `json:"version"`
+	ChartConfig                         *chart.KlusterletChartConfig                   `json:"chartConfig"`
+	KlusterletConfig                    *klusterletconfigv1alpha1.KlusterletConfigSpec `json:"klusterletConfig,omitempty"`
+	ClusterLabels                       map[string]string                              `json:"clusterLabels,omitempty"`
+	ClusterAnnotations                  map[string]string                              `json:"clusterAnnotations,omitempty"`
+	Env                                 map[string]string                              `json:"env"`
+	NetworkPolicies                     bool                                           `json:"networkPolicies"`
+	ImagePullSecret                     *secretVersion                                 `json:"imagePullSecret,omitempty"`
+	BootstrapKubeConfigSecret           []secretVersion                                `json:"bootstrapKubeConfigSecrets,omitempty"`
+	ExtraManifests                      []extraManifestsVersion                        `json:"extraManifests,omitempty"`
+	ManifestPatches                     []KlusterletManifestPatch                      `json:"manifestPatches,omitempty"`
+	KlusterletResources                 *corev1.ResourceRequirements                   `json:"klusterletResources,omitempty"`
+	KlusterletReplicaCount              *int                                           `json:"klusterletReplicaCount,omitempty"`
+	KlusterletAffinity                  *corev1.Affinity                               `json:"klusterletAffinity,omitempty"`
+	KlusterletTopologySpreadConstraints []corev1.TopologySpreadConstraint              `json:"klusterletTopologySpreadConstraints,omitempty"`
+	SyncLabelPrefixes                   []string                                       `json:"syncLabelPrefixes,omitempty"`
+}
+
+type extraManifestsVersion struct {
+	Kind            string `json:"kind"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+	// Data is only set for the object that is not from the API server
+	Data map[string][]byte `json:"data,omitempty"`
+}
+
+type secretVersion struct {
+	Namespace       string `json:"namespace"`
+	Name            string `json:"name"`
+	ResourceVersion string `json:"resourceVersion"`
+	// Data is only set for the secret that is not from the API server
+	Data map[string][]byte `json:"data,omitempty"`
+}
+
+// RenderHash returns the hash of all of the inputs of the klusterlet manifests rendering, including the
+// configurations of this KlusterletManifestsConfig (e.g. the manifest patches), the merged KlusterletConfig, the
+// managed cluster labels and annotations that are read by Generate, the image env vars and the versions of the
+// referenced secrets and extra manifests. The manifests do not need to be rendered again if the hash is not changed. It must be called before
+// Generate, and it returns an empty hash if the inputs cannot be tracked, e.g. the gRPC registration driver that
+// reads the hub route and services.
+func (c *KlusterletManifestsConfig) RenderHash(ctx context.Context, clientHolder *helpers.ClientHolder) (string, error) {
+	if c.klusterletConfig != nil && c.klusterletConfig.Spec.RegistrationDriver != nil &&
+		c.klusterletConfig.Spec.RegistrationDriver.AuthType == grpcAuthType {
+		return "", nil
+	}
+
+	inputs := renderInputs{
+		Version:         renderHashVersion(),
+		ChartConfig:     c.chartConfig,
+		NetworkPolicies: helpers.EnableKlusterletNetworkPolicies,
+		Env:             map[string]string{},
+		ManifestPatches: c.manifestPatches,
+		// the klusterlet settings of the managed cluster annotations are included in the cluster annotations
+		KlusterletResources:                 c.klusterletResources,
+		KlusterletReplicaCount:              c.klusterletReplicaCount,
+		KlusterletAffinity:                  c.klusterletAffinity,
+		KlusterletTopologySpreadConstraints: c.klusterletTopologySpreadConstraints,
+		SyncLabelPrefixes:                   c.syncLabelPrefixes,
+	}
+	for _, env := range []string{
+		constants.RegistrationOperatorImageEnvVarName,
+		constants.RegistrationImageEnvVarName,
+		constants.WorkImageEnvVarName,
+		constants.TLSProfileSyncImageEnvVarName,
+		constants.DefaultImagePullSecretEnvVarName,
+		constants.PodNamespaceEnvVarName,
+	} {
+		inputs.Env[env] = os.Getenv(env)
+	}
+
+	// the labels and annotations that are written by the controllers, e.g. the auto-import attempts, are not
+	// included, so they do not cause the manifests to be rendered again
+	var managedClusterAnnotations map[string]string
+	if c.managedCluster != nil {
+		managedClusterAnnotations = c.managedCluster.GetAnnotations()
+		inputs.ClusterAnnotations = map[string]string{}
+		for _, key := range renderClusterAnnotations() {
+			if value, ok := managedClusterAnnotations[key]; ok {
+				inputs.ClusterAnnotations[key] = value
+			}
+		}
+		inputs.ClusterLabels = map[string]string{}
+		if len(c.syncLabelPrefixes) != 0 {
+			inputs.ClusterLabels = getSyncLabels(c.managedCluster, c.syncLabelPrefixes)
+		}
+		for _, key := range []string{vendorLabel, apiconstants.SelfManagedClusterLabelKey} {
+			if value, ok := c.managedCluster.GetLabels()[key]; ok {
+				inputs.ClusterLabels[key] = value
+			}
+		}
+	}
+
+	if c.klusterletConfig != nil {
+		inputs.KlusterletConfig = &c.klusterletConfig.Spec
+
+		if c.klusterletConfig.Spec.MultipleHubsConfig != nil &&
+			c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.Type == operatorv1.LocalSecrets &&
+			c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets != nil {
+			ns := os.Getenv(constants.PodNamespaceEnvVarName)
+			for _, s := range c.klusterletConfig.Spec.MultipleHubsConfig.BootstrapKubeConfigs.LocalSecrets.KubeConfigSecrets {
+				secret, err := clientHolder.KubeClient.CoreV1().Secrets(ns).Get(ctx, s.Name, metav1.GetOptions{})
+				if err != nil {
+					return "", err
+				}
+				inputs.BootstrapKubeConfigSecret = append(inputs.BootstrapKubeConfigSecret, secretVersion{
+					Namespace:       secret.Namespace,
+					Name:            secret.Name,
+					ResourceVersion: secret.ResourceVersion,
+				})
+			}
+		}
+	}
+
+	if len(c.extraManifests) != 0 && (c.chartConfig.Klusterlet.Mode == operatorv1.InstallModeDefault ||
+		c.chartConfig.Klusterlet.Mode == operatorv1.InstallModeSingleton) {
+		sources, err := getExtraManifestsSources(ctx, clientHolder, c.extraManifests)
+		if err != nil {
+			return "", err
+		}
+		for _, source := range sources {
+			version := extraManifestsVersion{
+				Kind:            source.ref.Kind,
+				Name:            source.ref.Name,
+				ResourceVersion: source.resourceVersion,
+			}
+			if len(source.resourceVersion) == 0 {
+				version.Data = source.data
+			}
+			inputs.ExtraManifests = append(inputs.ExtraManifests, version)
+		}
+	}
+
+	if c.chartConfig.Images.ImageCredentials.CreateImageCredentials {
+		// the image pull secret of the klusterletconfig is only used in the Default and Singleton modes
+		var kcImagePullSecret corev1.ObjectReference
+		if c.klusterletConfig != nil && (c.chartConfig.Klusterlet.Mode == operatorv1.InstallModeDefault ||
+			c.chartConfig.Klusterlet.Mode == operatorv1.InstallModeSingleton) {
+			kcImagePullSecret = c.klusterletConfig.Spec.PullSecret
+		}
+		imagePullSecret, err := getImagePullSecret(ctx, clientHolder, kcImagePullSecret, managedClusterAnnotations)
+		if err != nil {
+			return "", err
+		}
+		if imagePullSecret != nil {
+			inputs.ImagePullSecret = &secretVersion{
+				Namespace:       imagePullSecret.Namespace,
+				Name:            imagePullSecret.Name,
+				ResourceVersion: imagePullSecret.ResourceVersion,
+			}
+			if len(imagePullSecret.ResourceVersion) == 0 {
+				inputs.ImagePullSecret.Data = imagePullSecret.Data
+			}
+		}
+	}
+
+	data, err := json.Marshal(inputs)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:]), nil
+}
@@ -0,0 +1,158 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"testing"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestRenderHash(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "pull-secret")
+	t.Setenv(constants.PodNamespaceEnvVarName, "multicluster-engine")
+
+	pullSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "pull-secret",
+			Namespace:       "multicluster-engine",
+			ResourceVersion: "1",
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	}
+	kubeClient := kubefake.NewSimpleClientset(pullSecret)
+	clientHolder := &helpers.ClientHolder{
+		KubeClient:          kubeClient,
+		ImageRegistryClient: imageregistry.NewClient(kubeClient),
+	}
+
+	newConfig := func(bootstrapKubeConfig string, cluster *clusterv1.ManagedCluster,
+		kc *klusterletconfigv1alpha1.KlusterletConfig) *KlusterletManifestsConfig {
+		return NewKlusterletManifestsConfig(operatorv1.InstallModeDefault, "cluster1", []byte(bootstrapKubeConfig)).
+			WithManagedCluster(cluster).
+			WithKlusterletConfig(kc)
+	}
+	renderHash := func(config *KlusterletManifestsConfig) string {
+		hash, err := config.RenderHash(context.TODO(), clientHolder)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return hash
+	}
+
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+	kc := &klusterletconfigv1alpha1.KlusterletConfig{}
+	hash := renderHash(newConfig("kubeconfig", cluster, kc))
+	if len(hash) == 0 {
+		t.Fatalf("expected the render hash is not empty")
+	}
+	if h := renderHash(newConfig("kubeconfig", cluster.DeepCopy(), kc.DeepCopy())); h != hash {
+		t.Errorf("expected the render hash is not changed, but got %s", h)
+	}
+
+	if h := renderHash(newConfig("kubeconfig2", cluster, kc)); h == hash {
+		t.Errorf("expected the render hash is changed with the bootstrap kubeconfig")
+	}
+
+	annotatedCluster := cluster.DeepCopy()
+	annotatedCluster.Annotations = map[string]string{"open-cluster-management/nodeSelector": `{"a":"b"}`}
+	if h := renderHash(newConfig("kubeconfig", annotatedCluster, kc)); h == hash {
+		t.Errorf("expected the render hash is changed with the cluster annotations")
+	}
+
+	controllerAnnotatedCluster := cluster.DeepCopy()
+	controllerAnnotatedCluster.Annotations = map[string]string{
+		constants.AutoImportAttemptsAnnotation:      "1",
+		constants.AutoImportNextRetryTimeAnnotation: "2026-10-17T00:00:00Z",
+	}
+	controllerAnnotatedCluster.Labels = map[string]string{"feature.open-cluster-management.io/addon-work-manager": "available"}
+	if h := renderHash(newConfig("kubeconfig", controllerAnnotatedCluster, kc)); h != hash {
+		t.Errorf("expected the render hash is not changed with the labels and annotations that are not rendered")
+	}
+
+	vendorCluster := cluster.DeepCopy()
+	vendorCluster.Labels = map[string]string{"vendor": "OpenShift"}
+	if h := renderHash(newConfig("kubeconfig", vendorCluster, kc)); h == hash {
+		t.Errorf("expected the render hash is changed with the vendor label")
+	}
+
+	labeledCluster := cluster.DeepCopy()
+	labeledCluster.Labels = map[string]string{"env": "prod"}
+	syncHash := renderHash(newConfig("kubeconfig", cluster, kc).WithSyncLabels([]string{"env"}))
+	if h := renderHash(newConfig("kubeconfig", labeledCluster, kc).WithSyncLabels([]string{"env"})); h == syncHash {
+		t.Errorf("expected the render hash is changed with the synced labels")
+	}
+	if h := renderHash(newConfig("kubeconfig", labeledCluster, kc)); h != hash {
+		t.Errorf("expected the render hash is not changed with the labels that are not synced")
+	}
+
+	if renderHashVersion() != renderHashVersion() || len(renderHashVersion()) == 0 {
+		t.Errorf("expected the render hash version is stable, but got %s", renderHashVersion())
+	}
+
+	changedKC := kc.DeepCopy()
+	changedKC.Spec.AppliedManifestWorkEvictionGracePeriod = "10m"
+	if h := renderHash(newConfig("kubeconfig", cluster, changedKC)); h == hash {
+		t.Errorf("expected the render hash is changed with the klusterletconfig")
+	}
+
+	t.Setenv(constants.WorkImageEnvVarName, "quay.io/open-cluster-management/work:changed")
+	if h := renderHash(newConfig("kubeconfig", cluster, kc)); h == hash {
+		t.Errorf("expected the render hash is changed with the image env vars")
+	}
+	hash = renderHash(newConfig("kubeconfig", cluster, kc))
+
+	pullSecret.ResourceVersion = "2"
+	if _, err := kubeClient.CoreV1().Secrets("multicluster-engine").Update(context.TODO(), pullSecret,
+		metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if h := renderHash(newConfig("kubeconfig", cluster, kc)); h == hash {
+		t.Errorf("expected the render hash is changed with the image pull secret")
+	}
+
+	hash = renderHash(newConfig("kubeconfig", cluster, kc))
+
+	extraManifests := []helpers.ManifestReference{{Kind: helpers.ManifestReferenceKindConfigMap, Name: "extra"}}
+	extraConfigMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            "extra",
+			Namespace:       "multicluster-engine",
+			ResourceVersion: "1",
+		},
+	}
+	if _, err := kubeClient.CoreV1().ConfigMaps("multicluster-engine").Create(context.TODO(), extraConfigMap,
+		metav1.CreateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	extraHash := renderHash(newConfig("kubeconfig", cluster, kc).WithExtraManifests(extraManifests))
+	if extraHash == hash {
+		t.Errorf("expected the render hash is changed with the extra manifests")
+	}
+	extraConfigMap.ResourceVersion = "2"
+	if _, err := kubeClient.CoreV1().ConfigMaps("multicluster-engine").Update(context.TODO(), extraConfigMap,
+		metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if h := renderHash(newConfig("kubeconfig", cluster, kc).WithExtraManifests(extraManifests)); h == extraHash {
+		t.Errorf("expected the render hash is changed with the extra manifests version")
+	}
+
+	grpcKC := kc.DeepCopy()
+	grpcKC.Spec.RegistrationDriver = &operatorv1.RegistrationDriver{AuthType: grpcAuthType}
+	if h := renderHash(newConfig("kubeconfig", cluster, grpcKC)); len(h) != 0 {
+		t.Errorf("expected the render hash is empty with the grpc registration driver, but got %s", h)
+	}
+}
@@ -21,6 +21,7 @@ import (
 const klusterletDeploymentName = "klusterlet"
 
 const (
+	vendorLabel             = "vendor"
 	vendorLabelOpenShift    = "OpenShift"
 	deploymentKind          = "Deployment"
 	tlsProfileSyncContainer = "tls-profile-sync"
@@ -31,7 +32,7 @@ func isManagedClusterOpenShift(mc *clusterv1.ManagedCluster) bool {
 	if mc == nil {
 		return false
 	}
-	return mc.Labels["vendor"] == vendorLabelOpenShift
+	return mc.Labels[vendorLabel] == vendorLabelOpenShift
 }
 
 // getTLSProfileSyncImage returns the tls-profile-sync sidecar image, applying registry
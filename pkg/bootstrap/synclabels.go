@@ -0,0 +1,99 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const klusterletKind = "Klusterlet"
+
+// WithSyncLabels enables the klusterlet to sync the labels of the managed cluster that have one of the given key
+// prefixes to all of the agent resources, it is only used in the Default and Singleton modes.
+func (c *KlusterletManifestsConfig) WithSyncLabels(prefixes []string) *KlusterletManifestsConfig {
+	c.syncLabelPrefixes = prefixes
+	return c
+}
+
+// ParseSyncLabelPrefixes parses the comma separated label key prefixes, e.g. "cloud,env,example.com/", an empty
+// value means the labels are not synced.
+func ParseSyncLabelPrefixes(val string) ([]string, error) {
+	prefixes := []string{}
+	for _, prefix := range strings.Split(val, ",") {
+		prefix = strings.TrimSpace(prefix)
+		if len(prefix) == 0 {
+			continue
+		}
+		if errMsgs := validation.IsQualifiedName(strings.TrimSuffix(prefix, "/")); len(errMsgs) != 0 {
+			return nil, fmt.Errorf("invalid label key prefix %q: %s", prefix, strings.Join(errMsgs, ";"))
+		}
+		prefixes = append(prefixes, prefix)
+	}
+	if len(prefixes) == 0 {
+		return nil, nil
+	}
+	return prefixes, nil
+}
+
+// getSyncLabels returns the labels of the managed cluster that have one of the given key prefixes
+func getSyncLabels(managedCluster *clusterv1.ManagedCluster, prefixes []string) map[string]string {
+	labels := map[string]string{}
+	if managedCluster == nil {
+		return labels
+	}
+
+	for key, value := range managedCluster.GetLabels() {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				labels[key] = value
+				break
+			}
+		}
+	}
+	return labels
+}
+
+// injectKlusterletLabels finds the Klusterlet in the rendered objects and adds the given labels to it, since they
+// are not supported by the klusterlet chart. The klusterlet operator syncs the labels of the Klusterlet to the agent
+// resources once the label sync is enabled. Returns the modified objects slice.
+func injectKlusterletLabels(objects [][]byte, labels map[string]string) ([][]byte, error) {
+	if len(labels) == 0 {
+		return objects, nil
+	}
+
+	for i, obj := range objects {
+		u := &unstructured.Unstructured{}
+		if err := yaml.Unmarshal(obj, u); err != nil {
+			continue
+		}
+		if u.GetKind() != klusterletKind {
+			continue
+		}
+
+		klusterletLabels := u.GetLabels()
+		if klusterletLabels == nil {
+			klusterletLabels = map[string]string{}
+		}
+		for key, value := range labels {
+			klusterletLabels[key] = value
+		}
+		u.SetLabels(klusterletLabels)
+
+		modified, err := yaml.Marshal(u.Object)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal modified Klusterlet: %w", err)
+		}
+
+		objects[i] = modified
+		break
+	}
+
+	return objects, nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
+)
+
+func TestParseSyncLabelPrefixes(t *testing.T) {
+	cases := []struct {
+		name             string
+		val              string
+		expectedPrefixes []string
+		expectedErr      bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:             "prefixes",
+			val:              "cloud, env,,example.com/",
+			expectedPrefixes: []string{"cloud", "env", "example.com/"},
+		},
+		{
+			name:        "invalid prefix",
+			val:         "cloud,env=prod",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			prefixes, err := ParseSyncLabelPrefixes(c.val)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if !reflect.DeepEqual(prefixes, c.expectedPrefixes) {
+				t.Errorf("expected prefixes %v, but got %v", c.expectedPrefixes, prefixes)
+			}
+		})
+	}
+}
+
+func TestKlusterletConfigGenerateWithSyncLabels(t *testing.T) {
+	t.Setenv(constants.DefaultImagePullSecretEnvVarName, "")
+	t.Setenv(constants.TLSProfileSyncImageEnvVarName,
+		"quay.io/open-cluster-management/managedcluster-import-controller:latest")
+
+	cases := []struct {
+		name             string
+		mode             operatorv1.InstallMode
+		prefixes         []string
+		expectedSync     bool
+		expectedLabels   map[string]string
+		unexpectedLabels []string
+	}{
+		{
+			name:             "default",
+			mode:             operatorv1.InstallModeDefault,
+			unexpectedLabels: []string{"cloud", "env"},
+		},
+		{
+			name:             "sync labels with prefixes",
+			mode:             operatorv1.InstallModeSingleton,
+			prefixes:         []string{"cloud", "example.com/"},
+			expectedSync:     true,
+			expectedLabels:   map[string]string{"cloud": "Amazon", "example.com/team": "infra"},
+			unexpectedLabels: []string{"env", "vendor"},
+		},
+		{
+			name:             "hosted mode",
+			mode:             operatorv1.InstallModeHosted,
+			prefixes:         []string{"cloud"},
+			unexpectedLabels: []string{"cloud", "env"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			clientHolder := &helpers.ClientHolder{
+				KubeClient:          kubeClient,
+				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+				ImageRegistryClient: imageregistry.NewClient(kubeClient),
+			}
+
+			manifestsBytes, _, _, err := NewKlusterletManifestsConfig(c.mode, "test", []byte("bootstrap kubeconfig")).
+				WithoutImagePullSecretGenerate().
+				WithManagedCluster(&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Labels: map[string]string{
+							"cloud":            "Amazon",
+							"vendor":           "OpenShift",
+							"env":              "prod",
+							"example.com/team": "infra",
+						},
+					},
+				}).
+				WithSyncLabels(c.prefixes).
+				Generate(context.TODO(), clientHolder)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			var klusterlet *operatorv1.Klusterlet
+			var deployment *appsv1.Deployment
+			for _, yaml := range helpers.SplitYamls(manifestsBytes) {
+				switch obj := helpers.MustCreateObject(yaml).(type) {
+				case *operatorv1.Klusterlet:
+					klusterlet = obj
+				case *appsv1.Deployment:
+					deployment = obj
+				}
+			}
+			if klusterlet == nil {
+				t.Fatalf("expected the klusterlet is rendered")
+			}
+
+			for key, value := range c.expectedLabels {
+				if klusterlet.Labels[key] != value {
+					t.Errorf("expected label %s=%s, but got %v", key, value, klusterlet.Labels)
+				}
+			}
+			for _, key := range c.unexpectedLabels {
+				if _, ok := klusterlet.Labels[key]; ok {
+					t.Errorf("unexpected label %s, got %v", key, klusterlet.Labels)
+				}
+			}
+
+			if deployment == nil {
+				return
+			}
+			syncEnabled := strings.Contains(strings.Join(deployment.Spec.Template.Spec.Containers[0].Args, " "),
+				"--enable-sync-labels")
+			if syncEnabled != c.expectedSync {
+				t.Errorf("expected sync labels %v, but got args %v", c.expectedSync,
+					deployment.Spec.Template.Spec.Containers[0].Args)
+			}
+		})
+	}
+}
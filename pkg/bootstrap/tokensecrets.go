@@ -0,0 +1,79 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+const (
+	// ServiceAccountTokenSecretsIndexKey is the index key of the service account token secrets, the secrets are
+	// indexed by the namespace and name of their service accounts.
+	ServiceAccountTokenSecretsIndexKey = "serviceaccount-token-secrets"
+)
+
+// IndexServiceAccountTokenSecretsByServiceAccount indexes the service account token secrets by the
+// <namespace>/<service account name> of their service accounts.
+func IndexServiceAccountTokenSecretsByServiceAccount(obj interface{}) ([]string, error) {
+	secret, ok := obj.(*corev1.Secret)
+	if !ok {
+		return nil, fmt.Errorf("not a secret object")
+	}
+
+	if secret.Type != corev1.SecretTypeServiceAccountToken {
+		return nil, nil
+	}
+
+	saName := secret.Annotations[corev1.ServiceAccountNameKey]
+	if len(saName) == 0 {
+		return nil, nil
+	}
+
+	return []string{serviceAccountTokenSecretsIndexValue(secret.Namespace, saName)}, nil
+}
+
+// GetServiceAccountTokenSecretCandidates returns the secrets that may hold the tokens of the service account.
+// The secrets are got from the secretIndexer if it is provided, otherwise all of the secrets in the namespace
+// are listed from the API server. The callers should check the type and name of the returned secrets.
+func GetServiceAccountTokenSecretCandidates(ctx context.Context, kubeClient kubernetes.Interface,
+	secretIndexer cache.Indexer, saName, secretNamespace string) ([]*corev1.Secret, error) {
+	if secretIndexer == nil {
+		secretList, err := kubeClient.CoreV1().Secrets(secretNamespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+
+		secrets := make([]*corev1.Secret, 0, len(secretList.Items))
+		for i := range secretList.Items {
+			secrets = append(secrets, &secretList.Items[i])
+		}
+		return secrets, nil
+	}
+
+	objs, err := secretIndexer.ByIndex(ServiceAccountTokenSecretsIndexKey,
+		serviceAccountTokenSecretsIndexValue(secretNamespace, saName))
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := make([]*corev1.Secret, 0, len(objs))
+	for _, obj := range objs {
+		secret, ok := obj.(*corev1.Secret)
+		if !ok {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+func serviceAccountTokenSecretsIndexValue(namespace, saName string) string {
+	return fmt.Sprintf("%s/%s", namespace, saName)
+}
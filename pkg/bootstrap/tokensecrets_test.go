@@ -0,0 +1,81 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package bootstrap
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestGetServiceAccountTokenSecretCandidates(t *testing.T) {
+	newSecret := func(name, saName string, secretType corev1.SecretType) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "cluster1"},
+			Type:       secretType,
+		}
+		if len(saName) != 0 {
+			secret.Annotations = map[string]string{corev1.ServiceAccountNameKey: saName}
+		}
+		return secret
+	}
+	secrets := []*corev1.Secret{
+		newSecret("cluster1-bootstrap-sa-token-abcde", "cluster1-bootstrap-sa", corev1.SecretTypeServiceAccountToken),
+		newSecret("other-token-abcde", "other", corev1.SecretTypeServiceAccountToken),
+		newSecret("opaque", "cluster1-bootstrap-sa", corev1.SecretTypeOpaque),
+	}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		ServiceAccountTokenSecretsIndexKey: IndexServiceAccountTokenSecretsByServiceAccount,
+	})
+	for _, secret := range secrets {
+		if _, err := kubeClient.CoreV1().Secrets("cluster1").Create(context.TODO(), secret,
+			metav1.CreateOptions{}); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		if err := indexer.Add(secret); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+
+	cases := []struct {
+		name          string
+		secretIndexer cache.Indexer
+		expectedNames []string
+	}{
+		{
+			name:          "list the namespace",
+			expectedNames: []string{"cluster1-bootstrap-sa-token-abcde", "opaque", "other-token-abcde"},
+		},
+		{
+			name:          "get from the indexer",
+			secretIndexer: indexer,
+			expectedNames: []string{"cluster1-bootstrap-sa-token-abcde"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			candidates, err := GetServiceAccountTokenSecretCandidates(context.TODO(), kubeClient, c.secretIndexer,
+				"cluster1-bootstrap-sa", "cluster1")
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			names := []string{}
+			for _, secret := range candidates {
+				names = append(names, secret.Name)
+			}
+			if !reflect.DeepEqual(names, c.expectedNames) {
+				t.Errorf("expected %v, but got %v", c.expectedNames, names)
+			}
+		})
+	}
+}
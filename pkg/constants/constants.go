@@ -26,6 +26,16 @@ const (
 	LabelAutoImportRestore = "cluster.open-cluster-management.io/restore-auto-import-secret"
 )
 
+const (
+	// RosaImportStateSecretName is the name of the secret in the cluster namespace to persist the state of the
+	// import user that is created on a rosa cluster during the auto import, so the import user can be cleaned
+	// up after the import controller restarts.
+	RosaImportStateSecretName = "auto-import-rosa-state"
+
+	// RosaImportStateLabel is the label key of the rosa import state secrets
+	RosaImportStateLabel = "import.open-cluster-management.io/rosa-import-state"
+)
+
 const (
 	// ControllerConfigConfigMapName is the name of the ConfigMap including configuration for the
 	// import controller
@@ -39,9 +49,169 @@ const (
 	// AutoImportStrategy is specified in the import-controller-config ConfigMap.
 	DefaultAutoImportStrategy = "ImportOnly"
 
+	// AutoImportStrategyAnnotation is the annotation of the ManagedCluster or KlusterletConfig to specify the
+	// AutoImportStrategy of a cluster, it takes precedence over the autoImportStrategy of the
+	// import-controller-config ConfigMap. The annotation of the ManagedCluster takes precedence over the
+	// annotation of the KlusterletConfig.
+	AutoImportStrategyAnnotation = "import.open-cluster-management.io/auto-import-strategy"
+
+	// ImportRollbackAnnotation is the annotation of the ManagedCluster to enable the rollback of a failed import,
+	// if its value is true, the objects created on the managed cluster by a failed import attempt are deleted.
+	ImportRollbackAnnotation = "import.open-cluster-management.io/rollback-on-failure"
+
+	// ImportPriorityAnnotation is the annotation of the ManagedCluster to specify the import priority of the
+	// cluster in integer, the clusters with higher priority are processed first when the controllers that import
+	// the managed clusters are backlogged.
+	ImportPriorityAnnotation = "import.open-cluster-management.io/priority"
+
+	// DefaultImportPriority is the import priority of the clusters without the import priority annotation
+	DefaultImportPriority = 0
+	// DefaultSelfManagedClusterImportPriority is the import priority of the self managed cluster without the
+	// import priority annotation
+	DefaultSelfManagedClusterImportPriority = 100
+
+	// BootstrapTokenLifetimeKey is the data key in the import-controller-config ConfigMap used to specify the
+	// lifetime of the bootstrap token in Go duration format, it is not less than MinBootstrapTokenLifetime.
+	BootstrapTokenLifetimeKey = "bootstrapTokenLifetime"
+
+	// BootstrapTokenRefreshThresholdKey is the data key in the import-controller-config ConfigMap used to specify
+	// the bootstrap token is refreshed once its remaining lifetime is less than the threshold, in Go duration
+	// format. It must be less than the token lifetime, by default it is 1/5 of the token lifetime.
+	BootstrapTokenRefreshThresholdKey = "bootstrapTokenRefreshThreshold"
+
+	// BootstrapTokenLifetimeAnnotation is the annotation of the KlusterletConfig to specify the lifetime of the
+	// bootstrap token, it takes precedence over the bootstrapTokenLifetime of the import-controller-config
+	// ConfigMap. It is also recorded on the import secret with the lifetime of the token in use.
+	BootstrapTokenLifetimeAnnotation = "import.open-cluster-management.io/bootstrap-token-lifetime"
+
+	// BootstrapTokenRefreshThresholdAnnotation is the annotation of the KlusterletConfig to specify the refresh
+	// threshold of the bootstrap token, it takes precedence over the bootstrapTokenRefreshThreshold of the
+	// import-controller-config ConfigMap.
+	BootstrapTokenRefreshThresholdAnnotation = "import.open-cluster-management.io/bootstrap-token-refresh-threshold"
+
+	// MinBootstrapTokenLifetime is the minimum lifetime of the bootstrap token, it is the minimum expiration
+	// of the TokenRequest.
+	MinBootstrapTokenLifetime = 10 * time.Minute
+
+	// RotateBootstrapTokenAnnotation is the annotation of the ManagedCluster to rotate the bootstrap token of the
+	// cluster on demand. Once the annotation is added, the bootstrap serviceaccount of the cluster is recreated
+	// to revoke all of its tokens, and the import secret is regenerated with a new token, then the annotation
+	// is removed.
+	RotateBootstrapTokenAnnotation = "import.open-cluster-management.io/rotate-bootstrap-token"
+
+	// BootstrapTokenRevokedAnnotation is the annotation of the ManagedCluster to record the time when the bootstrap
+	// tokens of the cluster are revoked for the rotation request, so the bootstrap serviceaccount is recreated only
+	// once for each request. It is removed together with the RotateBootstrapTokenAnnotation.
+	BootstrapTokenRevokedAnnotation = "import.open-cluster-management.io/bootstrap-token-revoked-at"
+
+	// HubKubeAPIServerEndpointsAnnotation is the annotation of the KlusterletConfig to specify an ordered list of
+	// the hub kube apiserver endpoints in JSON, e.g. [{"url":"https://api1:6443","proxyURL":"","caBundle":""}].
+	// The bootstrap hub kubeconfig includes one context for each endpoint, the context of the first endpoint is
+	// the current context. It takes precedence over the hub kube apiserver URL of the KlusterletConfig spec.
+	HubKubeAPIServerEndpointsAnnotation = "import.open-cluster-management.io/hub-kube-apiserver-endpoints"
+
+	// ImportSecretRenderHashAnnotation is the annotation of the import secret to record the hash of the inputs
+	// that the klusterlet manifests in the import secret are rendered with. The manifests are not rendered again
+	// until the hash is changed.
+	ImportSecretRenderHashAnnotation = "import.open-cluster-management.io/render-hash"
+
+	// LegacyBootstrapTokenMigrationKey is the data key in the import-controller-config ConfigMap used to migrate
+	// the managed clusters off the legacy service account token secrets when the value is true. The bootstrap
+	// tokens of the legacy secrets are replaced with the TokenRequest tokens, and the legacy secrets are deleted
+	// once the clusters have joined.
+	LegacyBootstrapTokenMigrationKey = "legacyBootstrapTokenMigration"
+
+	// KlusterletDriftDetectionIntervalKey is the data key in the import-controller-config ConfigMap used to
+	// enable the klusterlet drift detection for the imported clusters with the ImportOnly strategy, its value
+	// is the interval of the detection in Go duration format.
+	KlusterletDriftDetectionIntervalKey = "klusterletDriftDetectionInterval"
+
+	// ExtraManifestsKey is the data key in the import-controller-config ConfigMap used to specify the extra
+	// manifests that are appended to the import.yaml of all of the managed clusters. The value is a comma
+	// separated list of the ConfigMaps and Secrets in the import controller namespace in the format of
+	// configmap/<name> or secret/<name>, the YAML manifests in their data are appended.
+	ExtraManifestsKey = "extraManifests"
+
+	// ExtraManifestsAnnotation is the annotation of the KlusterletConfig to specify the extra manifests that are
+	// appended to the import.yaml of the managed clusters using the KlusterletConfig, in the same format as the
+	// extraManifests of the import-controller-config ConfigMap.
+	ExtraManifestsAnnotation = "import.open-cluster-management.io/extra-manifests"
+
+	// KlusterletManifestPatchesAnnotation is the annotation of the KlusterletConfig to specify the ordered JSON
+	// (RFC 6902) or strategic merge patches that are applied to the rendered klusterlet manifests. The value is a
+	// JSON or YAML list of the patches, each of them has a target (kind, name and optional namespace), a type
+	// (json or strategic) and a patch.
+	KlusterletManifestPatchesAnnotation = "import.open-cluster-management.io/klusterlet-manifest-patches"
+
+	// KlusterletResourcesAnnotation is the annotation of the KlusterletConfig to specify the resource requirements
+	// of the klusterlet operator and agents in JSON or YAML format, e.g. {"requests":{"cpu":"10m","memory":"32Mi"}}.
+	// The specified limits and requests override the default ones.
+	KlusterletResourcesAnnotation = "import.open-cluster-management.io/klusterlet-resources"
+
+	// KlusterletReplicasAnnotation is the annotation of the KlusterletConfig to specify the replica count of the
+	// klusterlet operator, the replica count of the agents is still determined by the klusterlet operator.
+	KlusterletReplicasAnnotation = "import.open-cluster-management.io/klusterlet-replicas"
+
+	// KlusterletAffinityAnnotation is the annotation of the KlusterletConfig to specify the affinity of the
+	// klusterlet operator in JSON or YAML format. It is not applied to the agents, which are placed by the
+	// klusterlet operator with the nodeSelector and tolerations only.
+	KlusterletAffinityAnnotation = "import.open-cluster-management.io/klusterlet-affinity"
+
+	// KlusterletTopologySpreadConstraintsAnnotation is the annotation of the KlusterletConfig to specify the list of
+	// the topology spread constraints of the klusterlet operator in JSON or YAML format. The label selector of a
+	// constraint is the selector of the klusterlet operator deployment if it is not set. It is not applied to the
+	// agents either.
+	KlusterletTopologySpreadConstraintsAnnotation = "import.open-cluster-management.io/klusterlet-topology-spread-constraints"
+
+	// KlusterletSyncLabelsAnnotation is the annotation of the KlusterletConfig to enable the klusterlet to sync the
+	// labels of the ManagedCluster to all of the agent resources. The value is a comma separated list of the label
+	// key prefixes, only the labels that have one of the prefixes are synced, e.g. cloud,env,example.com/
+	KlusterletSyncLabelsAnnotation = "import.open-cluster-management.io/klusterlet-sync-labels"
+
 	// ClusterImportConfig is to enable to generate the cluster import config secret for CAPI cluster
 	// importing when the value is true, otherwise do not generate the secret.
 	ClusterImportConfig = "clusterImportConfig"
+
+	// AutoImportMaxAttemptsKey is the data key in the import-controller-config ConfigMap used to specify
+	// the maximum number of attempts to import a managed cluster with the auto-import secret, 0 means unlimited.
+	AutoImportMaxAttemptsKey = "autoImportMaxAttempts"
+
+	// AutoImportRetryBaseDelayKey is the data key in the import-controller-config ConfigMap used to specify
+	// the delay before the first retry, the delay is doubled after each failed attempt.
+	AutoImportRetryBaseDelayKey = "autoImportRetryBaseDelay"
+
+	// AutoImportRetryMaxDelayKey is the data key in the import-controller-config ConfigMap used to specify
+	// the maximum delay between two attempts.
+	AutoImportRetryMaxDelayKey = "autoImportRetryMaxDelay"
+
+	// MaxInFlightImportsKey is the data key in the import-controller-config ConfigMap used to specify the
+	// maximum number of the imports that are in flight across the fleet, 0 means unlimited.
+	MaxInFlightImportsKey = "maxInFlightImports"
+
+	// MaxInFlightHiveImportsKey, MaxInFlightRosaImportsKey and MaxInFlightAutoImportSecretImportsKey are the
+	// data keys in the import-controller-config ConfigMap used to specify the maximum number of the imports that
+	// are in flight for each import source, 0 means unlimited.
+	MaxInFlightHiveImportsKey             = "maxInFlightHiveImports"
+	MaxInFlightRosaImportsKey             = "maxInFlightRosaImports"
+	MaxInFlightAutoImportSecretImportsKey = "maxInFlightAutoImportSecretImports"
+
+	DefaultAutoImportMaxAttempts    = 0
+	DefaultAutoImportRetryBaseDelay = 10 * time.Second
+	DefaultAutoImportRetryMaxDelay  = 10 * time.Minute
+)
+
+const (
+	// ImportSourceHive is the import source of the clusters that are imported with the hive ClusterDeployment
+	ImportSourceHive = "hive"
+	// ImportSourceRosa is the import source of the clusters that are imported with the auto-import/rosa or
+	// auto-import/ocm auto-import secret
+	ImportSourceRosa = "rosa"
+	// ImportSourceAutoImportSecret is the import source of the clusters that are imported with the other
+	// auto-import secrets
+	ImportSourceAutoImportSecret = "auto-import-secret"
+
+	// ImportSlotRetryInterval is the interval to retry a cluster that is waiting for an import slot
+	ImportSlotRetryInterval = 10 * time.Second
 )
 
 /* #nosec */
@@ -87,7 +257,6 @@ const (
 	ImportSecretTokenExpiration        = "expiration"
 	DefaultSecretTokenExpirationSecond = 360 * 24 * 60 * 60 // 360 days
 	ImportSecretTokenCreation          = "creation"
-	DefaultSecretTokenRefreshThreshold = 360 * 24 * time.Hour / 5 // 72 days
 )
 
 // NOSONAR-END
@@ -113,6 +282,20 @@ const (
 	// AddonEnableHostedModeAnnotation is the annotation on the ManagedCluster to indicate
 	// whether the hosted mode addons should be enabled.
 	AddonEnableHostedModeAnnotation string = "addon.open-cluster-management.io/enable-hosted-mode-addons"
+
+	// AutoImportAttemptsAnnotation is added on the ManagedCluster by the import controller to record the
+	// number of failed attempts to import the cluster with the auto-import secret. It is removed once the
+	// importing resources are applied, and users can remove it to restart the attempts.
+	AutoImportAttemptsAnnotation string = "import.open-cluster-management.io/auto-import-attempts"
+
+	// AutoImportSecretVersionAnnotation is added on the ManagedCluster by the import controller to record the
+	// UID and resourceVersion of the auto-import secret that the failed attempts are made with, the attempts
+	// are restarted once the auto-import secret is recreated or updated.
+	AutoImportSecretVersionAnnotation string = "import.open-cluster-management.io/auto-import-secret-version"
+
+	// AutoImportNextRetryTimeAnnotation is added on the ManagedCluster by the import controller to record
+	// the time (RFC3339) of the next attempt to import the cluster with the auto-import secret.
+	AutoImportNextRetryTimeAnnotation string = "import.open-cluster-management.io/auto-import-next-retry-time"
 )
 
 const (
@@ -142,16 +325,60 @@ const (
 	ConditionReasonManagedClusterImporting        = "ManagedClusterImporting"
 	ConditionReasonManagedClusterImportFailed     = "ManagedClusterImportFailed"
 	ConditionReasonManagedClusterImported         = "ManagedClusterImported"
+	// ConditionReasonManagedClusterWaitingForImportSlot indicates the import is admitted after the number of
+	// the imports in flight drops below the configured caps
+	ConditionReasonManagedClusterWaitingForImportSlot = "WaitingForImportSlot"
 
 	ConditionReasonManagedClusterDetaching      = "ManagedClusterDetaching"
 	ConditionReasonManagedClusterForceDetaching = "ManagedClusterForceDetaching"
 )
 
+const (
+	// ConditionKlusterletDrifted is the condition type of managed cluster to indicate whether the live klusterlet
+	// objects on the managed cluster drift from the rendered import.yaml
+	ConditionKlusterletDrifted = "KlusterletDrifted"
+
+	ConditionReasonKlusterletDriftDetected = "KlusterletDriftDetected"
+	ConditionReasonKlusterletInSync        = "KlusterletInSync"
+	ConditionReasonKlusterletDriftUnknown  = "KlusterletDriftUnknown"
+
+	EventReasonKlusterletDrifted = "KlusterletDrifted"
+)
+
+const (
+	// ConditionKlusterletManifestsPatched is the condition type of managed cluster to indicate whether the patches
+	// of the KlusterletConfig are applied to the klusterlet manifests of the import.yaml
+	ConditionKlusterletManifestsPatched = "KlusterletManifestsPatched"
+
+	ConditionReasonKlusterletManifestsPatchApplied = "KlusterletManifestsPatchApplied"
+	ConditionReasonKlusterletManifestsPatchFailed  = "KlusterletManifestsPatchFailed"
+
+	EventReasonKlusterletManifestsPatchFailed = "KlusterletManifestsPatchFailed"
+)
+
+const (
+	// ConditionKlusterletSettingsValid is the condition type of managed cluster to indicate whether the klusterlet
+	// resources, replica count, affinity, topology spread constraints and hub kube apiserver endpoints of the
+	// import.yaml are valid, the condition is only reported once the settings are invalid
+	ConditionKlusterletSettingsValid = "KlusterletSettingsValid"
+
+	ConditionReasonKlusterletSettingsInvalid = "KlusterletSettingsInvalid"
+
+	EventReasonKlusterletSettingsInvalid = "KlusterletSettingsInvalid"
+)
+
+const (
+	EventReasonBootstrapTokenRotated  = "BootstrapTokenRotated"
+	EventReasonBootstrapTokenExpiring = "BootstrapTokenExpiring"
+	EventReasonLegacyTokenMigrated    = "LegacyBootstrapTokenMigrated"
+)
+
 const (
 	EventReasonManagedClusterImportFailed = "Failed"
 	EventReasonManagedClusterImported     = "Imported"
 	EventReasonManagedClusterImporting    = "Importing"
 	EventReasonManagedClusterWait         = "WaitForImporting"
+	EventReasonManagedClusterWaitSlot     = "WaitingForImportSlot"
 
 	EventReasonManagedClusterDetaching      = "Detaching"
 	EventReasonManagedClusterForceDetaching = "ForceDetaching"
@@ -159,6 +386,10 @@ const (
 
 /* #nosec */
 const (
+	// AutoImportSecretRetryKey is the optional key of the auto-import secret to specify the number of retries
+	// after the first failed attempt, it overrides the autoImportMaxAttempts of the import-controller-config.
+	AutoImportSecretRetryKey string = "autoImportRetry"
+
 	AutoImportSecretKubeConfig    corev1.SecretType = "auto-import/kubeconfig" // #nosec G101
 	AutoImportSecretKubeConfigKey string            = "kubeconfig"             // #nosec G101
 
@@ -174,12 +405,50 @@ const (
 	AutoImportSecretRosaConfigClientIDKey     string            = "client_id"
 	AutoImportSecretRosaConfigClientSecretKey string            = "client_secret"
 	AutoImportSecretRosaConfigRetryTimesKey   string            = "retry_times"
+	AutoImportSecretRosaStateProviderIDKey    string            = "provider_id"
+	AutoImportSecretRosaStateUserIDKey        string            = "user_id"
+	AutoImportSecretRosaStateRetryCountKey    string            = "retry_count"
 	AutoImportSecretRosaConfigAuthMethodKey   string            = "auth_method"
+	// AutoImportSecretRosaStateBreakGlassCredentialIDKey is the key of the rosa import state secret that records
+	// the break-glass credential requested for the import, so it can be revoked after the controller restarts.
+	AutoImportSecretRosaStateBreakGlassCredentialIDKey string = "break_glass_credential_id"
 	// The definitions of the auth methods follow the same approach as in discovery:
 	// https://github.com/stolostron/discovery/blob/13cb209687bf963b58232eb96b25cf0d20d111ec/controllers/discoveryconfig_controller.go#L251
 	// TODO: @xuezhaojun, in long term, the offline-token should be removed, and only use service-account, see more details in Jira 10404.
 	AutoImportSecretRosaConfigAuthMethodOfflineToken   string = "offline-token"
 	AutoImportSecretRosaConfigAuthMethodServiceAccount string = "service-account"
+
+	// AutoImportSecretOCMConfig is the provider-neutral type of the auto-import secret for the clusters that are
+	// managed by the OpenShift Cluster Manager API (ROSA, OSD and ARO-HCP), it has the same keys as the
+	// auto-import/rosa secret and an additional product key.
+	AutoImportSecretOCMConfig           corev1.SecretType = "auto-import/ocm"
+	AutoImportSecretOCMConfigProductKey string            = "product"
+	AutoImportSecretOCMProductROSA      string            = "rosa"
+	AutoImportSecretOCMProductOSD       string            = "osd"
+	AutoImportSecretOCMProductARO       string            = "aro"
+
+	AutoImportSecretEKSConfig             corev1.SecretType = "auto-import/eks"
+	AutoImportSecretEKSAccessKeyIDKey     string            = "aws_access_key_id"
+	AutoImportSecretEKSSecretAccessKeyKey string            = "aws_secret_access_key"
+	AutoImportSecretEKSSessionTokenKey    string            = "aws_session_token"
+	AutoImportSecretEKSRegionKey          string            = "region"
+	AutoImportSecretEKSClusterNameKey     string            = "cluster_name"
+	AutoImportSecretEKSServerKey          string            = "server"
+	AutoImportSecretEKSCAKey              string            = "ca"
+
+	AutoImportSecretOIDCConfig          corev1.SecretType = "auto-import/oidc"
+	AutoImportSecretOIDCServerKey       string            = "server"
+	AutoImportSecretOIDCCAKey           string            = "ca"
+	AutoImportSecretOIDCTokenURLKey     string            = "token_url"
+	AutoImportSecretOIDCClientIDKey     string            = "client_id"
+	AutoImportSecretOIDCClientSecretKey string            = "client_secret"
+	AutoImportSecretOIDCScopeKey        string            = "scope"
+
+	AutoImportSecretClientCert          corev1.SecretType = "auto-import/clientcert"
+	AutoImportSecretClientCertServerKey string            = "server"
+	AutoImportSecretClientCertCertKey   string            = "tls.crt"
+	AutoImportSecretClientCertKeyKey    string            = "tls.key"
+	AutoImportSecretClientCertCAKey     string            = "ca.crt"
 )
 
 const (
@@ -91,7 +91,7 @@ func RunAgentRegistrationServer(ctx context.Context, port int, clientHolder *hel
 
 		var token []byte
 		if durationStr == "" {
-			token, _, _, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient, AgentRegistrationDefaultBootstrapSAName, ns,
+			token, _, _, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient, nil, AgentRegistrationDefaultBootstrapSAName, ns,
 				constants.DefaultSecretTokenExpirationSecond)
 			if err != nil {
 				http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -69,7 +69,7 @@ func getAPIServerURL(secret *corev1.Secret) (string, error) {
 		}
 		return "", fmt.Errorf("cannot get APIServer URL from secret %s/%s", secret.Namespace, secret.Name)
 
-	case constants.AutoImportSecretRosaConfig:
+	case constants.AutoImportSecretRosaConfig, constants.AutoImportSecretOCMConfig:
 		// TODO： need to call ocm api to get managed cluster api server URL.
 		return "", nil
 
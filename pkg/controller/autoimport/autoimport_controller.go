@@ -7,6 +7,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/openshift/library-go/pkg/operator/events"
 	corev1 "k8s.io/api/core/v1"
@@ -39,6 +40,7 @@ type ReconcileAutoImport struct {
 	mcRecorder             kevents.EventRecorder
 	importHelper           *helpers.ImportHelper
 	rosaKubeConfigGetters  map[string]*helpers.RosaKubeConfigGetter
+	oidcTokenGetters       map[string]*helpers.OIDCTokenGetter
 	importControllerConfig *helpers.ImportControllerConfig
 }
 
@@ -56,8 +58,9 @@ func NewReconcileAutoImport(
 		informerHolder:         informerHolder,
 		recorder:               recorder,
 		mcRecorder:             mcRecorder,
-		importHelper:           helpers.NewImportHelper(informerHolder, recorder, log),
+		importHelper:           helpers.NewImportHelper(informerHolder, recorder, log).WithPermissionPreflightCheck(true),
 		rosaKubeConfigGetters:  make(map[string]*helpers.RosaKubeConfigGetter),
+		oidcTokenGetters:       make(map[string]*helpers.OIDCTokenGetter),
 		importControllerConfig: autoImportStrategyGetter,
 	}
 }
@@ -103,13 +106,22 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.importControllerConfig.GetAutoImportStrategy()
+	autoImportStrategy, err := r.importControllerConfig.GetClusterAutoImportStrategy(managedCluster,
+		r.informerHolder.KlusterletConfigLister)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 	reqLogger.Info("Auto import strategy is fetched", "managedCluster", managedCluster.Name, "AutoImportStrategy", autoImportStrategy)
 	importSucceeded := meta.IsStatusConditionTrue(managedCluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
 	if !immediateImport && autoImportStrategy == apiconstants.AutoImportStrategyImportOnly && importSucceeded {
+		driftDetectionInterval, err := r.importControllerConfig.GetKlusterletDriftDetectionInterval()
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if driftDetectionInterval > 0 {
+			return r.detectKlusterletDrift(ctx, managedCluster, driftDetectionInterval)
+		}
+
 		reqLogger.Info("Auto import is skipped due to the auto import strategy",
 			"managedCluster", managedCluster.Name,
 			"autoImportStrategy", autoImportStrategy,
@@ -128,6 +140,30 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, err
 	}
 
+	retryPolicy, err := r.importControllerConfig.GetAutoImportRetryPolicy()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	retryPolicy = retryPolicy.WithAutoImportSecret(autoImportSecret)
+
+	// the immediate import is always retried, and the pending rollback of the failed attempt is always continued
+	// until it is done
+	attempts, nextRetryTime := helpers.GetAutoImportAttempts(managedCluster, autoImportSecret)
+	rollbackPending := r.importHelper.IsRollbackPending(managedClusterName)
+	if !immediateImport && !rollbackPending && retryPolicy.Exhausted(attempts) {
+		reqLogger.Info("Auto import is stopped due to the retry limit",
+			"managedCluster", managedCluster.Name,
+			"attempts", attempts,
+			"maxAttempts", retryPolicy.MaxAttempts,
+		)
+		return reconcile.Result{}, nil
+	}
+	if wait := time.Until(nextRetryTime); wait > 0 && !rollbackPending {
+		reqLogger.V(5).Info("Wait for the next auto import attempt",
+			"managedCluster", managedCluster.Name, "nextRetryTime", nextRetryTime)
+		return reconcile.Result{RequeueAfter: wait}, nil
+	}
+
 	backupRestore := false
 	if v, ok := autoImportSecret.Labels[constants.LabelAutoImportRestore]; ok && strings.EqualFold(v, "true") {
 		backupRestore = true
@@ -135,27 +171,47 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 
 	generateClientHolderFunc, err := r.getGenerateClientHolderFuncFromAutoImportSecret(managedClusterName, autoImportSecret)
 	if err != nil {
+		condition := helpers.NewManagedClusterImportSucceededCondition(
+			metav1.ConditionFalse,
+			constants.ConditionReasonManagedClusterImportFailed,
+			fmt.Sprintf("AutoImportSecretInvalid %s/%s; %s",
+				autoImportSecret.Namespace, autoImportSecret.Name, err),
+		)
+		exhausted, rErr := r.recordFailedAttempt(ctx, managedCluster, autoImportSecret, retryPolicy, attempts+1,
+			immediateImport, &condition)
+		if rErr != nil {
+			return reconcile.Result{}, rErr
+		}
 		if err := helpers.UpdateManagedClusterImportCondition(
 			r.client,
 			managedCluster,
-			helpers.NewManagedClusterImportSucceededCondition(
-				metav1.ConditionFalse,
-				constants.ConditionReasonManagedClusterImportFailed,
-				fmt.Sprintf("AutoImportSecretInvalid %s/%s; %s",
-					autoImportSecret.Namespace, autoImportSecret.Name, err),
-			),
+			condition,
 			r.mcRecorder,
 		); err != nil {
 			return reconcile.Result{}, err
 		}
 		// return error if the auto import secret invalid
 		reqLogger.Info("Auto import secret invalid", "managedCluster", managedCluster.Name, "error", err)
+		if exhausted {
+			return reconcile.Result{}, nil
+		}
 		return reconcile.Result{}, err
 	}
 
 	r.importHelper = r.importHelper.WithGenerateClientHolderFunc(generateClientHolderFunc)
 	result, condition, modified, iErr := r.importHelper.Import(
 		backupRestore, managedCluster, autoImportSecret)
+	// the result is not zero if the client generator asks to requeue, e.g. the rosa kubeconfig is not ready,
+	// such a failure has its own retry limit and does not take up the attempts.
+	exhausted := false
+	if condition.Reason == constants.ConditionReasonManagedClusterImportFailed && result.IsZero() {
+		var rErr error
+		exhausted, rErr = r.recordFailedAttempt(ctx, managedCluster, autoImportSecret, retryPolicy, attempts+1,
+			immediateImport, &condition)
+		if rErr != nil {
+			return reconcile.Result{}, rErr
+		}
+	}
 	// if resources are applied but NOT modified, will not update the condition, keep the original condition.
 	// This check is to prevent the current controller and import status controller from modifying the
 	// ManagedClusterImportSucceeded condition of the managed cluster in a loop
@@ -174,15 +230,26 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		"result", result, "modified", modified)
 
 	if helpers.ImportingResourcesApplied(&condition) {
+		// restart the attempts for the next import
+		if err := helpers.UpdateAutoImportAttempts(ctx, r.client, managedCluster, autoImportSecret, 0, time.Time{}); err != nil {
+			return reconcile.Result{}, err
+		}
+
 		// clean up the import user when current cluster is rosa
 		if getter, ok := r.rosaKubeConfigGetters[managedClusterName]; ok {
 			if err := getter.Cleanup(); err != nil {
 				return reconcile.Result{}, err
 			}
+			if err := helpers.DeleteRosaImportState(ctx, r.kubeClient, managedClusterName); err != nil {
+				return reconcile.Result{}, err
+			}
 
 			delete(r.rosaKubeConfigGetters, managedClusterName)
 		}
 
+		// the cached oidc token is no longer needed once the importing resources are applied
+		delete(r.oidcTokenGetters, managedClusterName)
+
 		// update the cluster URL before the auto secret is deleted if the importing resources are applied
 		if err := updateClusterURL(ctx, r.client, managedCluster, autoImportSecret); err != nil {
 			reqLogger.Error(err, "Failed to update clusterURL")
@@ -196,9 +263,31 @@ func (r *ReconcileAutoImport) Reconcile(ctx context.Context, request reconcile.R
 		return reconcile.Result{}, nil
 	}
 
+	if exhausted {
+		// stop retrying, the failure is reported by the condition
+		return reconcile.Result{}, nil
+	}
+
 	return result, iErr
 }
 
+// recordFailedAttempt records the failed attempt and the next retry time on the managed cluster, and returns
+// true if there is no attempt left. Once the attempts are exhausted, the condition message is updated to tell
+// that the import will not be retried.
+func (r *ReconcileAutoImport) recordFailedAttempt(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
+	autoImportSecret *corev1.Secret, retryPolicy helpers.ImportRetryPolicy, attempts int, immediateImport bool,
+	condition *metav1.Condition) (bool, error) {
+	if !immediateImport && retryPolicy.Exhausted(attempts) {
+		condition.Message = fmt.Sprintf("%s. Stop retrying after %d attempts", condition.Message, attempts)
+		return true, helpers.UpdateAutoImportAttempts(ctx, r.client, managedCluster, autoImportSecret, attempts,
+			time.Time{})
+	}
+
+	nextRetryTime := time.Now().Add(retryPolicy.Backoff(attempts))
+	return false, helpers.UpdateAutoImportAttempts(ctx, r.client, managedCluster, autoImportSecret, attempts,
+		nextRetryTime)
+}
+
 func (r *ReconcileAutoImport) getGenerateClientHolderFuncFromAutoImportSecret(
 	clusterName string, secret *corev1.Secret) (helpers.GenerateClientHolderFunc, error) {
 	switch secret.Type {
@@ -219,17 +308,62 @@ func (r *ReconcileAutoImport) getGenerateClientHolderFuncFromAutoImportSecret(
 		return helpers.GenerateImportClientFromKubeConfigSecret, nil
 	case constants.AutoImportSecretKubeToken:
 		return helpers.GenerateImportClientFromKubeTokenSecret, nil
-	case constants.AutoImportSecretRosaConfig:
+	case constants.AutoImportSecretClientCert:
+		return helpers.GenerateImportClientFromClientCertSecret, nil
+	case constants.AutoImportSecretEKSConfig:
+		return helpers.GenerateImportClientFromEKSSecret, nil
+	case constants.AutoImportSecretOIDCConfig:
+		getter, ok := r.oidcTokenGetters[clusterName]
+		if !ok {
+			getter = helpers.NewOIDCTokenGetter()
+			r.oidcTokenGetters[clusterName] = getter
+		}
+
+		return func(secret *corev1.Secret) (reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
+			return helpers.GenerateImportClientFromOIDCSecret(getter, secret)
+		}, nil
+	case constants.AutoImportSecretRosaConfig, constants.AutoImportSecretOCMConfig:
+		// the rosa and ocm secrets share the getter, the ocm secret is the provider-neutral form of the rosa secret
+		generate := helpers.GenerateImportClientFromRosaCluster
+		if secret.Type == constants.AutoImportSecretOCMConfig {
+			generate = helpers.GenerateImportClientFromOCMCluster
+		}
+
 		getter, ok := r.rosaKubeConfigGetters[clusterName]
 		if !ok {
 			getter = helpers.NewRosaKubeConfigGetter()
+
+			// resume the import that is interrupted by the controller restart
+			state, found, err := helpers.LoadRosaImportState(context.TODO(), r.kubeClient, clusterName)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				getter.RestoreState(state)
+			}
+
 			r.rosaKubeConfigGetters[clusterName] = getter
 		}
 
 		return func(secret *corev1.Secret) (reconcile.Result, *helpers.ClientHolder, meta.RESTMapper, error) {
-			return helpers.GenerateImportClientFromRosaCluster(getter, secret)
+			result, clientHolder, mapper, err := generate(getter, secret)
+			if sErr := r.persistRosaImportState(secret, getter); sErr != nil {
+				log.Error(sErr, "Failed to persist the rosa import state", "managedCluster", clusterName)
+			}
+			return result, clientHolder, mapper, err
 		}, nil
 	default:
 		return nil, fmt.Errorf("unsupported secret type %s", secret.Type)
 	}
 }
+
+// persistRosaImportState saves the state of the import user created by the getter, or deletes the state once
+// the import user is cleaned up, so the import user can be cleaned up after the controller restarts.
+func (r *ReconcileAutoImport) persistRosaImportState(secret *corev1.Secret,
+	getter *helpers.RosaKubeConfigGetter) error {
+	state, created := getter.State()
+	if !created {
+		return helpers.DeleteRosaImportState(context.TODO(), r.kubeClient, secret.Namespace)
+	}
+	return helpers.SaveRosaImportState(context.TODO(), r.kubeClient, secret, state)
+}
@@ -227,6 +227,106 @@ func TestReconcile(t *testing.T) {
 			expectedConditionStatus: metav1.ConditionFalse,
 			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
 		},
+		{
+			name: "unsupported auto-import-secret type with the last attempt",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithAnnotations(constants.AutoImportAttemptsAnnotation, "9").
+					WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+					Build(),
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "auto-import-secret",
+						Namespace:       managedClusterName,
+						UID:             "uid1",
+						ResourceVersion: "1",
+					},
+					Data: map[string][]byte{
+						"autoImportRetry": []byte("9"),
+					},
+				},
+			},
+			expectedErr:             false,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+		{
+			name: "auto import attempts are exhausted",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithAnnotations(constants.AutoImportAttemptsAnnotation, "2").
+					WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+					Build(),
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "auto-import-secret",
+						Namespace:       managedClusterName,
+						UID:             "uid1",
+						ResourceVersion: "1",
+					},
+					Data: map[string][]byte{
+						"autoImportRetry": []byte("1"),
+					},
+				},
+			},
+			expectedErr: false,
+		},
+		{
+			name: "auto import attempts are restarted with the updated auto-import-secret",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithAnnotations(constants.AutoImportAttemptsAnnotation, "2").
+					WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+					Build(),
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "auto-import-secret",
+						Namespace:       managedClusterName,
+						UID:             "uid1",
+						ResourceVersion: "2",
+					},
+					Data: map[string][]byte{
+						"autoImportRetry": []byte("1"),
+					},
+				},
+			},
+			expectedErr:             true,
+			expectedConditionStatus: metav1.ConditionFalse,
+			expectedConditionReason: constants.ConditionReasonManagedClusterImportFailed,
+		},
+		{
+			name: "wait for the next auto import attempt",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder(managedClusterName).
+					WithAnnotations(constants.AutoImportAttemptsAnnotation, "1").
+					WithAnnotations(constants.AutoImportNextRetryTimeAnnotation,
+						time.Now().Add(time.Hour).UTC().Format(time.RFC3339)).
+					WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+					Build(),
+			},
+			works: []runtime.Object{},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:            "auto-import-secret",
+						Namespace:       managedClusterName,
+						UID:             "uid1",
+						ResourceVersion: "1",
+					},
+					Data: map[string][]byte{},
+				},
+			},
+			expectedErr: false,
+		},
 		{
 			name: "no manifest works",
 			objs: []client.Object{
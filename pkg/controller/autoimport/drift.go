@@ -0,0 +1,80 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package autoimport
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// detectKlusterletDrift compares the live klusterlet objects on the imported managed cluster with the rendered
+// import.yaml using the credentials of the auto-import secret, and reports the drifts with the KlusterletDrifted
+// condition. Nothing is changed on the managed cluster.
+func (r *ReconcileAutoImport) detectKlusterletDrift(ctx context.Context, managedCluster *clusterv1.ManagedCluster,
+	interval time.Duration) (reconcile.Result, error) {
+	reqLogger := log.WithValues("Request.Name", managedCluster.Name)
+
+	autoImportSecret, err := r.informerHolder.AutoImportSecretLister.Secrets(managedCluster.Name).Get(
+		constants.AutoImportSecretName)
+	if errors.IsNotFound(err) {
+		// there are no credentials to access the managed cluster
+		reqLogger.V(5).Info("Auto import secret not found, skip the klusterlet drift detection")
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	switch autoImportSecret.Type {
+	case constants.AutoImportSecretRosaConfig, constants.AutoImportSecretOCMConfig:
+		// the credentials are created on demand for the import, they are not created only to detect the drift
+		reqLogger.V(5).Info("The klusterlet drift detection is not supported", "secretType", autoImportSecret.Type)
+		return reconcile.Result{}, nil
+	}
+
+	importSecret, err := r.informerHolder.ImportSecretLister.Secrets(managedCluster.Name).Get(
+		fmt.Sprintf("%s-%s", managedCluster.Name, constants.ImportSecretNameSuffix))
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	drifts, err := r.klusterletDrifts(ctx, managedCluster.Name, autoImportSecret, importSecret)
+	if err != nil {
+		reqLogger.Info("Failed to detect the klusterlet drift", "error", err)
+	}
+	if err := helpers.UpdateManagedClusterDriftCondition(
+		r.client,
+		managedCluster,
+		helpers.NewKlusterletDriftedCondition(drifts, err),
+		r.mcRecorder,
+	); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	return reconcile.Result{RequeueAfter: interval}, nil
+}
+
+func (r *ReconcileAutoImport) klusterletDrifts(ctx context.Context, clusterName string,
+	autoImportSecret, importSecret *corev1.Secret) ([]string, error) {
+	generateClientHolderFunc, err := r.getGenerateClientHolderFuncFromAutoImportSecret(clusterName, autoImportSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	_, clientHolder, _, err := generateClientHolderFunc(autoImportSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	return helpers.DetectKlusterletDrift(ctx, clientHolder.RuntimeClient, importSecret)
+}
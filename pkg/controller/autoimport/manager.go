@@ -11,6 +11,7 @@ import (
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
@@ -36,11 +37,32 @@ func Add(ctx context.Context,
 	clientHolder *helpers.ClientHolder,
 	informerHolder *source.InformerHolder,
 	mcRecorder kevents.EventRecorder,
-	componentNamespace string) error {
+	componentNamespace string,
+	importAdmission *helpers.ImportAdmission) error {
+
+	// clean up the rosa import users that are left by the previous controller process
+	if err := mgr.Add(&rosaImportStateCleaner{
+		client:     clientHolder.RuntimeClient,
+		kubeClient: clientHolder.KubeClient,
+	}); err != nil {
+		return err
+	}
+
+	r := NewReconcileAutoImport(
+		clientHolder.RuntimeClient,
+		clientHolder.KubeClient,
+		informerHolder,
+		helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
+		mcRecorder,
+		helpers.NewImportControllerConfig(componentNamespace, informerHolder.ControllerConfigLister, log),
+	)
+	r.importHelper.WithImportAdmission(importAdmission, constants.ImportSourceAutoImportSecret)
 
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+			// process the clusters with higher import priority first
+			NewQueue: helpers.NewImportPriorityQueueFunc(clientHolder.RuntimeClient),
 		}).
 		WatchesRawSource( // watch the import secrets
 			source.NewImportSecretSource(informerHolder.ImportSecretInformer,
@@ -92,6 +114,19 @@ func Add(ctx context.Context,
 							return true
 						}
 
+						// handle the removal of the auto-import-attempts annotation to restart the attempts
+						_, oldAttempts := e.ObjectOld.GetAnnotations()[constants.AutoImportAttemptsAnnotation]
+						_, newAttempts := e.ObjectNew.GetAnnotations()[constants.AutoImportAttemptsAnnotation]
+						if oldAttempts && !newAttempts {
+							return true
+						}
+
+						// handle the change of the auto-import-strategy annotation
+						if e.ObjectOld.GetAnnotations()[constants.AutoImportStrategyAnnotation] !=
+							e.ObjectNew.GetAnnotations()[constants.AutoImportStrategyAnnotation] {
+							return true
+						}
+
 						// handle the removal of the disable-auto-import annotation
 						_, oldAutoImportDisabled := e.ObjectOld.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
 						_, newAutoImportDisabled := e.ObjectNew.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
@@ -100,6 +135,13 @@ func Add(ctx context.Context,
 				},
 			),
 		).
+		Watches( // watch the klusterletconfigs for the auto-import-strategy annotation
+			&klusterletconfigv1alpha1.KlusterletConfig{},
+			&source.KlusterletConfigEventHandler{
+				ManagedClusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+			},
+			builder.WithPredicates(source.NewAutoImportStrategyPredicate()),
+		).
 		WatchesRawSource( // watch the klusterlet manifest works
 			source.NewKlusterletWorkSource(informerHolder.KlusterletWorkInformer,
 				&source.ManagedClusterResourceEventHandler{},
@@ -135,14 +177,7 @@ func Add(ctx context.Context,
 				}),
 			),
 		).
-		Complete(NewReconcileAutoImport(
-			clientHolder.RuntimeClient,
-			clientHolder.KubeClient,
-			informerHolder,
-			helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
-			mcRecorder,
-			helpers.NewImportControllerConfig(componentNamespace, informerHolder.ControllerConfigLister, log),
-		))
+		Complete(r)
 
 	return err
 }
@@ -0,0 +1,90 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package autoimport
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+// rosaImportStateCleaner sweeps the rosa import states that are left by the previous controller process at
+// startup. The import users of the states whose import is no longer in progress are cleaned up, the others
+// are resumed by the reconciler.
+type rosaImportStateCleaner struct {
+	client     client.Client
+	kubeClient kubernetes.Interface
+}
+
+func (c *rosaImportStateCleaner) Start(ctx context.Context) error {
+	secrets, err := c.kubeClient.CoreV1().Secrets(metav1.NamespaceAll).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", constants.RosaImportStateLabel, constants.LabelValueTrue),
+	})
+	if err != nil {
+		log.Error(err, "Failed to list the rosa import states")
+		return nil
+	}
+
+	for i := range secrets.Items {
+		stateSecret := &secrets.Items[i]
+		inProgress, err := c.importInProgress(ctx, stateSecret.Namespace)
+		if err != nil {
+			log.Error(err, "Failed to determine the rosa import state", "managedCluster", stateSecret.Namespace)
+			continue
+		}
+		if inProgress {
+			continue
+		}
+
+		if err := helpers.CleanupRosaImportState(ctx, c.kubeClient, stateSecret); err != nil {
+			log.Error(err, "Failed to clean up the rosa import user", "managedCluster", stateSecret.Namespace)
+			continue
+		}
+		log.Info("The leftover rosa import user is cleaned up", "managedCluster", stateSecret.Namespace)
+	}
+
+	return nil
+}
+
+// importInProgress returns true if the reconciler will continue to import the cluster with the rosa or ocm
+// auto-import secret
+func (c *rosaImportStateCleaner) importInProgress(ctx context.Context, clusterName string) (bool, error) {
+	autoImportSecret, err := c.kubeClient.CoreV1().Secrets(clusterName).Get(ctx,
+		constants.AutoImportSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if autoImportSecret.Type != constants.AutoImportSecretRosaConfig &&
+		autoImportSecret.Type != constants.AutoImportSecretOCMConfig {
+		return false, nil
+	}
+
+	cluster := &clusterv1.ManagedCluster{}
+	err = c.client.Get(ctx, types.NamespacedName{Name: clusterName}, cluster)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if !cluster.DeletionTimestamp.IsZero() {
+		return false, nil
+	}
+
+	return !meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded), nil
+}
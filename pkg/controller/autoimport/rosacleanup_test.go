@@ -0,0 +1,98 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package autoimport
+
+import (
+	"context"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestRosaImportInProgress(t *testing.T) {
+	rosaSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-import-secret",
+			Namespace: "test",
+		},
+		Type: constants.AutoImportSecretRosaConfig,
+	}
+
+	cases := []struct {
+		name               string
+		objs               []client.Object
+		secrets            []runtime.Object
+		expectedInProgress bool
+	}{
+		{
+			name: "no auto-import secret",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").Build(),
+			},
+			expectedInProgress: false,
+		},
+		{
+			name: "not a rosa auto-import secret",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").Build(),
+			},
+			secrets: []runtime.Object{
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "auto-import-secret",
+						Namespace: "test",
+					},
+					Type: constants.AutoImportSecretKubeConfig,
+				},
+			},
+			expectedInProgress: false,
+		},
+		{
+			name:               "no cluster",
+			secrets:            []runtime.Object{rosaSecret},
+			expectedInProgress: false,
+		},
+		{
+			name: "cluster is imported",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").WithImportedCondition(true).Build(),
+			},
+			secrets:            []runtime.Object{rosaSecret},
+			expectedInProgress: false,
+		},
+		{
+			name: "cluster is importing",
+			objs: []client.Object{
+				testinghelpers.NewManagedClusterBuilder("test").WithImportingCondition(false).Build(),
+			},
+			secrets:            []runtime.Object{rosaSecret},
+			expectedInProgress: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cleaner := &rosaImportStateCleaner{
+				client:     fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.objs...).Build(),
+				kubeClient: kubefake.NewSimpleClientset(c.secrets...),
+			}
+
+			inProgress, err := cleaner.importInProgress(context.TODO(), "test")
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if inProgress != c.expectedInProgress {
+				t.Errorf("expected in progress %v, but got %v", c.expectedInProgress, inProgress)
+			}
+		})
+	}
+}
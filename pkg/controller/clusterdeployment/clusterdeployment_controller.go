@@ -118,7 +118,8 @@ func (r *ReconcileClusterDeployment) Reconcile(
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.importControllerConfig.GetAutoImportStrategy()
+	autoImportStrategy, err := r.importControllerConfig.GetClusterAutoImportStrategy(managedCluster,
+		r.informerHolder.KlusterletConfigLister)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -11,6 +11,7 @@ import (
 	workv1 "open-cluster-management.io/api/work/v1"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
@@ -40,11 +41,24 @@ func Add(ctx context.Context,
 	clientHolder *helpers.ClientHolder,
 	informerHolder *source.InformerHolder,
 	mcRecorder kevents.EventRecorder,
-	componentNamespace string) error {
+	componentNamespace string,
+	importAdmission *helpers.ImportAdmission) error {
+
+	r := NewReconcileClusterDeployment(
+		clientHolder.RuntimeClient,
+		clientHolder.KubeClient,
+		informerHolder,
+		helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
+		mcRecorder,
+		helpers.NewImportControllerConfig(componentNamespace, informerHolder.ControllerConfigLister, log),
+	)
+	r.importHelper.WithImportAdmission(importAdmission, constants.ImportSourceHive)
 
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+			// process the clusters with higher import priority first
+			NewQueue: helpers.NewImportPriorityQueueFunc(clientHolder.RuntimeClient),
 		}).
 		Watches( // watch the clusterdeployment
 			&hivev1.ClusterDeployment{},
@@ -76,6 +90,12 @@ func Add(ctx context.Context,
 							return true
 						}
 
+						// handle the change of the auto-import-strategy annotation
+						if oldAnnotations[constants.AutoImportStrategyAnnotation] !=
+							newAnnotations[constants.AutoImportStrategyAnnotation] {
+							return true
+						}
+
 						// handle the removal of the disable-auto-import annotation
 						_, oldAutoImportDisabled := oldAnnotations[apiconstants.DisableAutoImportAnnotation]
 						_, newAutoImportDisabled := newAnnotations[apiconstants.DisableAutoImportAnnotation]
@@ -91,6 +111,21 @@ func Add(ctx context.Context,
 				},
 			),
 		).
+		Watches( // watch the klusterletconfigs for the auto-import-strategy annotation
+			&klusterletconfigv1alpha1.KlusterletConfig{},
+			&source.KlusterletConfigEventHandler{
+				ManagedClusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+				MapFunc: func(o client.Object) reconcile.Request {
+					return reconcile.Request{
+						NamespacedName: types.NamespacedName{
+							Namespace: o.GetName(),
+							Name:      o.GetName(),
+						},
+					}
+				},
+			},
+			builder.WithPredicates(source.NewAutoImportStrategyPredicate()),
+		).
 		WatchesRawSource( // watch the import secret
 			source.NewImportSecretSource(informerHolder.ImportSecretInformer, &source.ManagedClusterResourceEventHandler{},
 				predicate.Predicate(predicate.Funcs{
@@ -141,14 +176,7 @@ func Add(ctx context.Context,
 					},
 				})),
 		).
-		Complete(NewReconcileClusterDeployment(
-			clientHolder.RuntimeClient,
-			clientHolder.KubeClient,
-			informerHolder,
-			helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
-			mcRecorder,
-			helpers.NewImportControllerConfig(componentNamespace, informerHolder.ControllerConfigLister, log),
-		))
+		Complete(r)
 
 	return err
 }
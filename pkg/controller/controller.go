@@ -29,6 +29,7 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 	certificatesv1 "k8s.io/api/certificates/v1"
 	kevents "k8s.io/client-go/tools/events"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 )
 
@@ -47,6 +48,11 @@ func AddToManager(ctx context.Context,
 		},
 	}
 
+	// the import admission is shared by the controllers that import the managed clusters, so the caps of the
+	// imports in flight are applied across the fleet
+	importAdmission := helpers.NewImportAdmission(helpers.NewImportControllerConfig(
+		componentNamespace, informerHolder.ControllerConfigLister, logf.Log.WithName("import-admission")))
+
 	AddToManagerFuncs := []struct {
 		ControllerName string
 		Add            func() error
@@ -63,7 +69,9 @@ func AddToManager(ctx context.Context,
 		},
 		{
 			importconfig.ControllerName,
-			func() error { return importconfig.Add(ctx, manager, clientHolder, informerHolder, componentNamespace) },
+			func() error {
+				return importconfig.Add(ctx, manager, clientHolder, informerHolder, mcRecorder, componentNamespace)
+			},
 		},
 		{
 			manifestwork.ControllerName,
@@ -78,13 +86,15 @@ func AddToManager(ctx context.Context,
 		{
 			autoimport.ControllerName,
 			func() error {
-				return autoimport.Add(ctx, manager, clientHolder, informerHolder, mcRecorder, componentNamespace)
+				return autoimport.Add(ctx, manager, clientHolder, informerHolder, mcRecorder, componentNamespace,
+					importAdmission)
 			},
 		},
 		{
 			clusterdeployment.ControllerName,
 			func() error {
-				return clusterdeployment.Add(ctx, manager, clientHolder, informerHolder, mcRecorder, componentNamespace)
+				return clusterdeployment.Add(ctx, manager, clientHolder, informerHolder, mcRecorder,
+					componentNamespace, importAdmission)
 			},
 		},
 		{
@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
@@ -18,6 +19,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apiserver/pkg/storage/names"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/v2"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
@@ -38,27 +40,42 @@ func extractBootstrapKubeConfigDataFromImportSecret(importSecret *corev1.Secret)
 		return nil
 	}
 
+	// the bootstrap kubeconfig is split into one secret for each of the hub kube apiserver endpoints if there
+	// are more than one endpoint
+	endpointKubeconfigs := [][]byte{}
 	for _, yaml := range helpers.SplitYamls(importYaml) {
 		obj := helpers.MustCreateObject(yaml)
 		if secret, ok := obj.(*corev1.Secret); ok {
 			if secret.Name == constants.DefaultBootstrapHubKubeConfigSecretName {
 				return secret.Data["kubeconfig"]
 			}
+			if strings.HasPrefix(secret.Name, bootstrap.EndpointBootstrapKubeConfigSecretPrefix) {
+				endpointKubeconfigs = append(endpointKubeconfigs, secret.Data["kubeconfig"])
+			}
 		}
 	}
 
-	return nil
+	if len(endpointKubeconfigs) == 0 {
+		return nil
+	}
+	kubeconfigData, err := bootstrap.JoinBootstrapKubeConfigs(endpointKubeconfigs)
+	if err != nil {
+		klog.Infof("failed to join the bootstrap kubeconfigs of the endpoints: %v", err)
+		return nil
+	}
+	return kubeconfigData
 }
 
 // validateLegacyServiceAccountToken validates that a legacy serviceaccount token secret exists
 // and contains the expected token value and is not marked as invalid
 func validateLegacyServiceAccountToken(ctx context.Context, kubeClient kubernetes.Interface,
-	saName, secretNamespace, expectedToken string) bool {
+	secretIndexer cache.Indexer, saName, secretNamespace, expectedToken string) bool {
 	if len(expectedToken) == 0 {
 		return false
 	}
 
-	secrets, err := kubeClient.CoreV1().Secrets(secretNamespace).List(ctx, metav1.ListOptions{})
+	secrets, err := bootstrap.GetServiceAccountTokenSecretCandidates(ctx, kubeClient, secretIndexer,
+		saName, secretNamespace)
 	if err != nil {
 		klog.Errorf("failed to list secrets for serviceaccount token validation: %v", err)
 		return false
@@ -70,7 +87,7 @@ func validateLegacyServiceAccountToken(ctx context.Context, kubeClient kubernete
 		prefix = prefix[:names.MaxGeneratedNameLength]
 	}
 
-	for _, secret := range secrets.Items {
+	for _, secret := range secrets {
 		if secret.Type != corev1.SecretTypeServiceAccountToken {
 			continue
 		}
@@ -106,15 +123,24 @@ func validateLegacyServiceAccountToken(ctx context.Context, kubeClient kubernete
 	return false
 }
 
-func validateTokenExpiration(token string, creation, expiration []byte) bool {
+// tokenLifetimeTolerance tolerates the difference between the lifetime of the token issued by the TokenRequest and
+// the lifetime of the bootstrap token policy
+const tokenLifetimeTolerance = time.Minute
+
+func validateTokenExpiration(token string, creation, expiration []byte,
+	tokenPolicy helpers.BootstrapTokenPolicy) bool {
 	if len(token) == 0 {
 		// no token in the kubeconfig
 		return false
 	}
 
 	if len(expiration) == 0 {
-		// token is from the service account token secret - no expiration to validate
-		// Additional secret existence validation will be done by the caller with proper context
+		// token is from the service account token secret - it never expires, so it is not allowed if the lifetime
+		// is customized. Additional secret existence validation will be done by the caller with proper context
+		if tokenPolicy.LifetimeSpecified {
+			klog.Infof("non-expiring token exceeds the required lifetime %v", tokenPolicy.Lifetime)
+			return false
+		}
 		return true
 	}
 	expirationTime, err := time.Parse(time.RFC3339, string(expiration))
@@ -123,7 +149,7 @@ func validateTokenExpiration(token string, creation, expiration []byte) bool {
 		return false
 	}
 
-	refreshThreshold := constants.DefaultSecretTokenRefreshThreshold
+	refreshThreshold := tokenPolicy.RefreshThreshold
 	if len(creation) != 0 {
 		creationTime, err := time.Parse(time.RFC3339, string(creation))
 		if err != nil {
@@ -131,7 +157,18 @@ func validateTokenExpiration(token string, creation, expiration []byte) bool {
 			return false
 		}
 
-		refreshThreshold = expirationTime.Sub(creationTime) / 5
+		tokenLifetime := expirationTime.Sub(creationTime)
+		if tokenLifetime > tokenPolicy.Lifetime+tokenLifetimeTolerance {
+			// the token lives longer than the policy allows, e.g. the lifetime is shortened
+			klog.Infof("token lifetime %v exceeds the required lifetime %v", tokenLifetime, tokenPolicy.Lifetime)
+			return false
+		}
+		if refreshThreshold == 0 {
+			refreshThreshold = tokenLifetime / 5
+		}
+	}
+	if refreshThreshold == 0 {
+		refreshThreshold = tokenPolicy.Lifetime / 5
 	}
 
 	lifetime := time.Until(expirationTime)
@@ -139,8 +176,10 @@ func validateTokenExpiration(token string, creation, expiration []byte) bool {
 }
 
 func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.ClientHolder,
-	managedCluster *clusterv1.ManagedCluster,
-	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig) ([]byte, []byte, []byte, error) {
+	secretIndexer cache.Indexer, managedCluster *clusterv1.ManagedCluster,
+	klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig,
+	hubEndpoints []bootstrap.HubKubeAPIServerEndpoint, tokenPolicy helpers.BootstrapTokenPolicy,
+	rotateToken, migrateLegacyToken bool) ([]byte, []byte, []byte, error) {
 	var bootstrapKubeconfigData, tokenData, tokenCreation, tokenExpiration []byte
 
 	// get the import secret
@@ -149,9 +188,9 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 		return nil, nil, nil, err
 	}
 
-	// get the latest kube apiserver configuration
-	requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, err := bootstrap.GetKubeAPIServerConfig(
-		ctx, clientHolder, managedCluster.Name, klusterletConfig, isSelfManaged(managedCluster))
+	// get the latest kube apiserver configuration of each endpoint
+	requiredEndpoints, err := bootstrap.GetKubeAPIServerEndpointConfigs(ctx, clientHolder, managedCluster.Name,
+		klusterletConfig, hubEndpoints, isSelfManaged(managedCluster))
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -168,24 +207,39 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 
 	// check if the bootstrap kubeconfig and token in the import secret are still valid
 	if kubeconfigData := extractBootstrapKubeConfigDataFromImportSecret(importSecret); len(kubeconfigData) > 0 {
-		kubeAPIServer, proxyURL, ca, caData, tokenString, ctxClusterName, err := helpers.ParseKubeConfigData(kubeconfigData)
+		_, _, _, _, tokenString, ctxClusterName, err := helpers.ParseKubeConfigData(kubeconfigData)
+		var endpoints []bootstrap.KubeAPIServerEndpointConfig
+		if err == nil {
+			endpoints, err = bootstrap.ParseBootstrapKubeConfigEndpoints(kubeconfigData)
+		}
 		if err != nil {
 			klog.Infof("failed to parse the bootstrap hub kubeconfig in the import.yaml. Recreation is required: %v", err)
 		} else {
 			// use the existing token if it is still valid
 			creation := importSecret.Data[constants.ImportSecretTokenCreation]
 			expiration := importSecret.Data[constants.ImportSecretTokenExpiration]
-			valid := validateTokenExpiration(tokenString, creation, expiration)
+			valid := validateTokenExpiration(tokenString, creation, expiration, tokenPolicy)
+
+			if valid && len(expiration) == 0 && migrateLegacyToken {
+				// the legacy token is replaced with a TokenRequest token
+				klog.Infof("migrate the legacy serviceaccount token for managed cluster %s", managedCluster.Name)
+				valid = false
+			}
 
 			// For legacy tokens (no expiration), additionally validate the serviceaccount secret exists and is not marked as invalid
 			if valid && len(expiration) == 0 {
 				saName := helpers.GetBootstrapSAName(managedCluster.Name)
-				valid = validateLegacyServiceAccountToken(ctx, clientHolder.KubeClient, saName, managedCluster.Name, tokenString)
+				valid = validateLegacyServiceAccountToken(ctx, clientHolder.KubeClient, secretIndexer, saName, managedCluster.Name, tokenString)
 				if !valid {
 					klog.Infof("legacy serviceaccount token validation failed for managed cluster %s", managedCluster.Name)
 				}
 			}
 
+			if rotateToken {
+				// the token is revoked, a new token is required
+				valid = false
+			}
+
 			if valid {
 				tokenData = []byte(tokenString)
 				tokenCreation = creation
@@ -196,20 +250,26 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 			}
 
 			// use the kubeconfig if it is still valid
-			if valid := bootstrap.ValidateBootstrapKubeconfig(managedCluster.Name,
-				kubeAPIServer, proxyURL, ca, caData, ctxClusterName,
-				requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, requiredCtxClusterName); valid {
+			if valid := bootstrap.ValidateBootstrapKubeconfigEndpoints(managedCluster.Name,
+				endpoints, ctxClusterName, requiredEndpoints, requiredCtxClusterName); valid {
 				bootstrapKubeconfigData = kubeconfigData
 			}
 		}
 	}
 
-	// retrieve the non-expiring token if available or generate a new one.
+	// retrieve the non-expiring token if available or generate a new one, the legacy tokens are skipped
+	// when they are migrated or the token lifetime is customized.
 	if len(tokenData) == 0 {
 		klog.Infof("create a new token for the managed cluster %s", managedCluster.Name)
-		tokenData, tokenCreation, tokenExpiration, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient,
-			helpers.GetBootstrapSAName(managedCluster.Name),
-			managedCluster.Name, constants.DefaultSecretTokenExpirationSecond)
+		saName := helpers.GetBootstrapSAName(managedCluster.Name)
+		expirationSeconds := int64(tokenPolicy.Lifetime.Seconds())
+		if migrateLegacyToken || tokenPolicy.LifetimeSpecified {
+			tokenData, tokenCreation, tokenExpiration, err = bootstrap.RequestSAToken(ctx, clientHolder.KubeClient,
+				saName, managedCluster.Name, expirationSeconds)
+		} else {
+			tokenData, tokenCreation, tokenExpiration, err = bootstrap.GetBootstrapToken(ctx, clientHolder.KubeClient,
+				secretIndexer, saName, managedCluster.Name, expirationSeconds)
+		}
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -221,8 +281,8 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 	// create a new bootstrap kubeconfig if it is invalid or missing
 	if len(bootstrapKubeconfigData) == 0 {
 		klog.Infof("create a new bootstrap kubeconfig for the managed cluster %s", managedCluster.Name)
-		bootstrapKubeconfigData, err = bootstrap.CreateBootstrapKubeConfig(requiredCtxClusterName,
-			requiredKubeAPIServer, requiredProxyURL, requiredCA, requiredCAData, tokenData)
+		bootstrapKubeconfigData, err = bootstrap.CreateBootstrapKubeConfigWithEndpoints(requiredCtxClusterName,
+			requiredEndpoints, tokenData)
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -231,6 +291,23 @@ func buildBootstrapKubeconfigData(ctx context.Context, clientHolder *helpers.Cli
 	return bootstrapKubeconfigData, tokenCreation, tokenExpiration, nil
 }
 
+// tokenLifetime returns the lifetime of the bootstrap token, it returns 0 if the token does not expire.
+func tokenLifetime(creation, expiration []byte) time.Duration {
+	if len(creation) == 0 || len(expiration) == 0 {
+		return 0
+	}
+
+	creationTime, err := time.Parse(time.RFC3339, string(creation))
+	if err != nil {
+		return 0
+	}
+	expirationTime, err := time.Parse(time.RFC3339, string(expiration))
+	if err != nil {
+		return 0
+	}
+	return expirationTime.Sub(creationTime)
+}
+
 func isSelfManaged(managedCluster *clusterv1.ManagedCluster) bool {
 	if managedCluster == nil {
 		return false
@@ -241,12 +318,83 @@ func isSelfManaged(managedCluster *clusterv1.ManagedCluster) bool {
 	return false
 }
 
+// klusterletConfigSettings is the klusterlet settings that are specified with the annotations of the klusterletconfig,
+// since they are not supported by the KlusterletConfig spec.
+type klusterletConfigSettings struct {
+	resources                 *corev1.ResourceRequirements
+	replicaCount              *int
+	affinity                  *corev1.Affinity
+	topologySpreadConstraints []corev1.TopologySpreadConstraint
+	syncLabelPrefixes         []string
+}
+
+// getKlusterletConfigSettings returns the klusterlet settings that are specified with the annotations of the
+// klusterletconfig or the global klusterletconfig.
+func getKlusterletConfigSettings(klusterletconfigName string,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister) (klusterletConfigSettings, error) {
+	settings := klusterletConfigSettings{}
+	vals := map[string]string{}
+	for _, key := range []string{
+		constants.KlusterletResourcesAnnotation,
+		constants.KlusterletReplicasAnnotation,
+		constants.KlusterletAffinityAnnotation,
+		constants.KlusterletTopologySpreadConstraintsAnnotation,
+		constants.KlusterletSyncLabelsAnnotation,
+	} {
+		val, err := helpers.GetKlusterletConfigAnnotation(klusterletconfigName, key, kcLister)
+		if err != nil {
+			return settings, err
+		}
+		vals[key] = val
+	}
+
+	var err error
+	if settings.resources, err = helpers.ParseResourceRequirements(
+		vals[constants.KlusterletResourcesAnnotation]); err != nil {
+		return settings, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid klusterlet resources of the klusterletconfig: %v", err),
+		}
+	}
+	if settings.replicaCount, err = helpers.ParseReplicaCount(
+		vals[constants.KlusterletReplicasAnnotation]); err != nil {
+		return settings, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid klusterlet replicas of the klusterletconfig: %v", err),
+		}
+	}
+	if settings.affinity, err = helpers.ParseAffinity(
+		vals[constants.KlusterletAffinityAnnotation]); err != nil {
+		return settings, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid klusterlet affinity of the klusterletconfig: %v", err),
+		}
+	}
+	if settings.topologySpreadConstraints, err = helpers.ParseTopologySpreadConstraints(
+		vals[constants.KlusterletTopologySpreadConstraintsAnnotation]); err != nil {
+		return settings, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid klusterlet topologySpreadConstraints of the klusterletconfig: %v", err),
+		}
+	}
+	if settings.syncLabelPrefixes, err = bootstrap.ParseSyncLabelPrefixes(
+		vals[constants.KlusterletSyncLabelsAnnotation]); err != nil {
+		return settings, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid klusterlet sync labels of the klusterletconfig: %v", err),
+		}
+	}
+	return settings, nil
+}
+
+// buildImportSecret builds the import secret and the cluster import config secret of the managed cluster. If the
+// render hash of the klusterlet manifests is not changed since the previous import secret is built, the manifests
+// of the previous import secret are reused and the cluster import config secret is not built.
 func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder, managedCluster *clusterv1.ManagedCluster,
 	mode operatorv1.InstallMode, klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig,
-	bootstrapKubeconfigData, tokenCreation, tokenExpiration []byte) (*corev1.Secret, *corev1.Secret, error) {
+	extraManifests []helpers.ManifestReference, manifestPatches []bootstrap.KlusterletManifestPatch,
+	settings klusterletConfigSettings, bootstrapKubeconfigData, tokenCreation, tokenExpiration []byte,
+	previousImportSecret *corev1.Secret) (*corev1.Secret, *corev1.Secret, error) {
 	var yamlcontent, crdsYAML, valuesYAML []byte
 	var secretAnnotations map[string]string
-	var err error
+	var config *bootstrap.KlusterletManifestsConfig
+	// the crds are not included in the import secret of the hosted mode
+	withCRDs := true
 	switch mode {
 	case operatorv1.InstallModeDefault, operatorv1.InstallModeSingleton:
 		supportPriorityClass, err := helpers.SupportPriorityClass(managedCluster)
@@ -257,20 +405,21 @@ func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder,
 		if supportPriorityClass {
 			priorityClassName = constants.DefaultKlusterletPriorityClassName
 		}
-		config := bootstrap.NewKlusterletManifestsConfig(
+		config = bootstrap.NewKlusterletManifestsConfig(
 			mode,
 			managedCluster.Name,
 			bootstrapKubeconfigData).
 			WithManagedCluster(managedCluster).
 			WithKlusterletConfig(klusterletConfig).
-			WithPriorityClassName(priorityClassName)
-		yamlcontent, crdsYAML, valuesYAML, err = config.Generate(ctx, clientHolder)
-		if err != nil {
-			return nil, nil, err
-		}
+			WithPriorityClassName(priorityClassName).
+			WithExtraManifests(extraManifests).
+			WithManifestPatches(manifestPatches).
+			WithKlusterletResources(settings.resources, settings.replicaCount).
+			WithKlusterletOperatorPlacement(settings.affinity, settings.topologySpreadConstraints).
+			WithSyncLabels(settings.syncLabelPrefixes)
 
 	case operatorv1.InstallModeHosted, operatorv1.InstallModeSingletonHosted:
-		yamlcontent, _, valuesYAML, err = bootstrap.NewKlusterletManifestsConfig(
+		config = bootstrap.NewKlusterletManifestsConfig(
 			mode,
 			managedCluster.Name,
 			bootstrapKubeconfigData).
@@ -280,18 +429,47 @@ func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder,
 			// already had the default PriorityClass
 			WithPriorityClassName(constants.DefaultKlusterletPriorityClassName).
 			WithKlusterletConfig(klusterletConfig).
-			Generate(ctx, clientHolder)
-		if err != nil {
-			return nil, nil, err
-		}
+			WithManifestPatches(manifestPatches)
 
 		secretAnnotations = map[string]string{
 			constants.KlusterletDeployModeAnnotation: string(operatorv1.InstallModeHosted),
 		}
+		withCRDs = false
 	default:
 		return nil, nil, fmt.Errorf("klusterlet deploy mode %s not supported", mode)
 	}
 
+	renderHash, err := config.RenderHash(ctx, clientHolder)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	rendered := true
+	if len(renderHash) != 0 && previousImportSecret != nil &&
+		previousImportSecret.Annotations[constants.ImportSecretRenderHashAnnotation] == renderHash &&
+		len(previousImportSecret.Data[constants.ImportSecretImportYamlKey]) != 0 {
+		klog.V(4).Infof("the render inputs of the managed cluster %s are not changed, reuse the import secret",
+			managedCluster.Name)
+		yamlcontent = previousImportSecret.Data[constants.ImportSecretImportYamlKey]
+		crdsYAML = previousImportSecret.Data[constants.ImportSecretCRDSYamlKey]
+		rendered = false
+	} else {
+		yamlcontent, crdsYAML, valuesYAML, err = config.Generate(ctx, clientHolder)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !withCRDs {
+			crdsYAML = nil
+		}
+	}
+
+	if len(renderHash) != 0 {
+		if secretAnnotations == nil {
+			secretAnnotations = map[string]string{}
+		}
+		secretAnnotations[constants.ImportSecretRenderHashAnnotation] = renderHash
+	}
+
 	// generate import secret
 	importSecret := &corev1.Secret{
 		TypeMeta: metav1.TypeMeta{},
@@ -329,6 +507,15 @@ func buildImportSecret(ctx context.Context, clientHolder *helpers.ClientHolder,
 	if len(tokenExpiration) != 0 {
 		importSecret.Data[constants.ImportSecretTokenExpiration] = tokenExpiration
 	}
+	if lifetime := tokenLifetime(tokenCreation, tokenExpiration); lifetime > 0 {
+		if importSecret.Annotations == nil {
+			importSecret.Annotations = map[string]string{}
+		}
+		importSecret.Annotations[constants.BootstrapTokenLifetimeAnnotation] = lifetime.String()
+	}
 
+	if !rendered {
+		return importSecret, nil, nil
+	}
 	return importSecret, valuesSecret, nil
 }
@@ -5,6 +5,7 @@ package importconfig
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
@@ -14,6 +15,7 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	authv1 "k8s.io/api/authentication/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -21,9 +23,11 @@ import (
 	"k8s.io/apimachinery/pkg/runtime"
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	clienttesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
 	clientcmdlatest "k8s.io/client-go/tools/clientcmd/api/latest"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
 	"open-cluster-management.io/ocm/pkg/operator/helpers/chart"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -134,6 +138,7 @@ func TestBuildBootstrapKubeconfigData(t *testing.T) {
 		runtimeObjs      []runtime.Object
 		selfManaged      bool
 		klusterletConfig *klusterletconfigv1alpha1.KlusterletConfig
+		tokenPolicy      *helpers.BootstrapTokenPolicy
 		want             *wantData
 		wantErr          bool
 	}{
@@ -305,6 +310,30 @@ func TestBuildBootstrapKubeconfigData(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name:        "legacy token is not used with a specified lifetime",
+			clientObjs:  []client.Object{testInfraConfigDNS, apiserverConfig},
+			runtimeObjs: []runtime.Object{cm, sa, saSecret},
+			tokenPolicy: &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour, LifetimeSpecified: true},
+			want: &wantData{
+				serverURL: "https://my-dns-name.com:6443",
+				certData:  certData1,
+				token:     "fake-token",
+			},
+		},
+		{
+			name:       "legacy token is replaced with a specified lifetime",
+			clientObjs: []client.Object{testInfraConfigDNS, apiserverConfigWithCustomCA},
+			runtimeObjs: []runtime.Object{cm, secretCorrect, sa, saSecret,
+				mockLegacyImportSecret(t, "https://my-dns-name.com:6443", certData2, "sa-token"),
+			},
+			tokenPolicy: &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour, LifetimeSpecified: true},
+			want: &wantData{
+				serverURL: "https://my-dns-name.com:6443",
+				certData:  certData2,
+				token:     "fake-token",
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -344,7 +373,13 @@ func TestBuildBootstrapKubeconfigData(t *testing.T) {
 				}
 			}
 
-			kubeconfigData, _, _, err := buildBootstrapKubeconfigData(context.Background(), clientHolder, cluster, tt.klusterletConfig) // cluster.Name = testcluster
+			tokenPolicy := helpers.DefaultBootstrapTokenPolicy
+			if tt.tokenPolicy != nil {
+				tokenPolicy = *tt.tokenPolicy
+			}
+
+			kubeconfigData, _, _, err := buildBootstrapKubeconfigData(context.Background(), clientHolder, nil, cluster, tt.klusterletConfig,
+				nil, tokenPolicy, false, false) // cluster.Name = testcluster
 			if err != nil {
 				t.Errorf("buildBootstrapKubeconfigData() error = %v", err)
 				return
@@ -408,6 +443,110 @@ func TestBuildBootstrapKubeconfigData(t *testing.T) {
 	}
 }
 
+func TestBuildBootstrapKubeconfigDataWithEndpoints(t *testing.T) {
+	certData1, _, _ := testinghelpers.NewRootCA("test ca1")
+	certData2, _, _ := testinghelpers.NewRootCA("test ca2")
+
+	endpoint1 := bootstrap.HubKubeAPIServerEndpoint{URL: "https://api1.example.com:6443", CABundle: certData1}
+	endpoint2 := bootstrap.HubKubeAPIServerEndpoint{
+		URL:      "https://api2.example.com:6443",
+		ProxyURL: "http://proxy.example.com:3128",
+		CABundle: certData2,
+	}
+	endpoint3 := bootstrap.HubKubeAPIServerEndpoint{URL: "https://api3.example.com:6443", CABundle: certData1}
+
+	kubeClient := kubefake.NewSimpleClientset()
+	kubeClient.PrependReactor(
+		"create",
+		"serviceaccounts/token",
+		func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+			return true, &authv1.TokenRequest{
+				ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.Now()},
+				Status: authv1.TokenRequestStatus{
+					Token:               "fake-token",
+					ExpirationTimestamp: metav1.NewTime(time.Now().Add(24 * time.Hour)),
+				},
+			}, nil
+		},
+	)
+	clientHolder := &helpers.ClientHolder{
+		RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		KubeClient:    kubeClient,
+	}
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "testcluster"}}
+
+	build := func(endpoints ...bootstrap.HubKubeAPIServerEndpoint) []byte {
+		kubeconfigData, creation, expiration, err := buildBootstrapKubeconfigData(context.Background(), clientHolder,
+			nil, cluster, nil, endpoints, helpers.DefaultBootstrapTokenPolicy, false, false)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+
+		// save the bootstrap kubeconfig into the import secret, it is split into one secret for each endpoint
+		kubeconfigs, err := bootstrap.SplitBootstrapKubeConfig(kubeconfigData)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		bootstrapKubeConfigs := []chart.BootStrapKubeConfig{}
+		for i, kubeconfig := range kubeconfigs {
+			bootstrapKubeConfigs = append(bootstrapKubeConfigs, chart.BootStrapKubeConfig{
+				Name:       fmt.Sprintf("%s%d", bootstrap.EndpointBootstrapKubeConfigSecretPrefix, i),
+				KubeConfig: string(kubeconfig),
+			})
+		}
+		_, objects, err := chart.RenderKlusterletChart(context.TODO(), &chart.KlusterletChartConfig{
+			Klusterlet:                      chart.KlusterletConfig{Namespace: "test", Name: "klusterlet"},
+			BootstrapHubKubeConfig:          string(kubeconfigData),
+			MultiHubBootstrapHubKubeConfigs: bootstrapKubeConfigs,
+		}, "test")
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		importSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "testcluster-import", Namespace: "testcluster"},
+			Data: map[string][]byte{
+				constants.ImportSecretImportYamlKey:   bootstrap.AggregateObjects(objects),
+				constants.ImportSecretTokenCreation:   creation,
+				constants.ImportSecretTokenExpiration: expiration,
+			},
+		}
+		if err := kubeClient.Tracker().Add(importSecret); err != nil {
+			if err := kubeClient.Tracker().Update(corev1.SchemeGroupVersion.WithResource("secrets"),
+				importSecret, importSecret.Namespace); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+		}
+		return kubeconfigData
+	}
+
+	kubeconfigData := build(endpoint1, endpoint2)
+	config, _, err := clientcmdlatest.Codec.Decode(kubeconfigData, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	kubeconfig := config.(*clientcmdapi.Config)
+	if len(kubeconfig.Contexts) != 2 {
+		t.Fatalf("expected 2 contexts, but got %d", len(kubeconfig.Contexts))
+	}
+	currentCluster := kubeconfig.Clusters[kubeconfig.Contexts[kubeconfig.CurrentContext].Cluster]
+	if currentCluster.Server != endpoint1.URL {
+		t.Errorf("expected the current context server %s, but got %s", endpoint1.URL, currentCluster.Server)
+	}
+	secondCluster := kubeconfig.Clusters[kubeconfig.Contexts["default-context-1"].Cluster]
+	if secondCluster.Server != endpoint2.URL || secondCluster.ProxyURL != endpoint2.ProxyURL {
+		t.Errorf("expected the second context server %s with proxy %s, but got %s with proxy %s",
+			endpoint2.URL, endpoint2.ProxyURL, secondCluster.Server, secondCluster.ProxyURL)
+	}
+
+	if data := build(endpoint2, endpoint1); !reflect.DeepEqual(data, kubeconfigData) {
+		t.Errorf("expected the bootstrap kubeconfig is not regenerated when the endpoints are reordered")
+	}
+
+	if data := build(endpoint1, endpoint2, endpoint3); reflect.DeepEqual(data, kubeconfigData) {
+		t.Errorf("expected the bootstrap kubeconfig is regenerated when an endpoint is added")
+	}
+}
+
 func mockImportSecret(t *testing.T, expirationTime time.Time, server string, caData []byte, token string) *corev1.Secret {
 	bootstrapConfig := clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{"default-cluster": {
@@ -538,6 +677,7 @@ func TestValidateTokenExpiration(t *testing.T) {
 		name                 string
 		token                string
 		creation, expiration []byte
+		tokenPolicy          *helpers.BootstrapTokenPolicy
 		expectedResult       bool
 	}{
 		{
@@ -550,6 +690,12 @@ func TestValidateTokenExpiration(t *testing.T) {
 			token:          "abc",
 			expectedResult: true,
 		},
+		{
+			name:           "expiration is empty with a specified lifetime",
+			token:          "abc",
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour, LifetimeSpecified: true},
+			expectedResult: false,
+		},
 		{
 			name:           "creation is empty",
 			token:          "abc",
@@ -602,12 +748,40 @@ func TestValidateTokenExpiration(t *testing.T) {
 			expiration:     timeToString(time.Now().Add(71 * time.Hour * 24)),
 			expectedResult: false,
 		},
+		{
+			name:           "lifetime is shortened",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(300 * time.Hour * 24)),
+			creation:       timeToString(time.Now().Add(-60 * time.Hour * 24)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour},
+			expectedResult: false,
+		},
+		{
+			name:           "not reach the refresh threshold",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(3 * time.Hour * 24)),
+			creation:       timeToString(time.Now().Add(-4 * time.Hour * 24)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour, RefreshThreshold: 48 * time.Hour},
+			expectedResult: true,
+		},
+		{
+			name:           "reach the refresh threshold",
+			token:          "abc",
+			expiration:     timeToString(time.Now().Add(1 * time.Hour * 24)),
+			creation:       timeToString(time.Now().Add(-6 * time.Hour * 24)),
+			tokenPolicy:    &helpers.BootstrapTokenPolicy{Lifetime: 7 * 24 * time.Hour, RefreshThreshold: 48 * time.Hour},
+			expectedResult: false,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.expectedResult != validateTokenExpiration(tt.token, tt.creation, tt.expiration) {
-				t.Errorf("validateTokenExpiration() expected %v, got %v", tt.expectedResult, tt.expectedResult)
+			tokenPolicy := helpers.DefaultBootstrapTokenPolicy
+			if tt.tokenPolicy != nil {
+				tokenPolicy = *tt.tokenPolicy
+			}
+			if result := validateTokenExpiration(tt.token, tt.creation, tt.expiration, tokenPolicy); result != tt.expectedResult {
+				t.Errorf("validateTokenExpiration() expected %v, got %v", tt.expectedResult, result)
 			}
 		})
 	}
@@ -777,7 +951,7 @@ func TestValidateLegacyServiceAccountToken(t *testing.T) {
 			ctx := context.Background()
 			fakeKubeClient := kubefake.NewSimpleClientset(tt.secrets...)
 
-			result := validateLegacyServiceAccountToken(ctx, fakeKubeClient, tt.saName, tt.secretNamespace, tt.expectedToken)
+			result := validateLegacyServiceAccountToken(ctx, fakeKubeClient, nil, tt.saName, tt.secretNamespace, tt.expectedToken)
 
 			if result != tt.expectedResult {
 				t.Errorf("validateLegacyServiceAccountToken() = %v, expected %v", result, tt.expectedResult)
@@ -785,3 +959,143 @@ func TestValidateLegacyServiceAccountToken(t *testing.T) {
 		})
 	}
 }
+
+// newServiceAccountTokenSecrets returns the clientset and the indexer of the secrets in the managed cluster
+// namespaces, each namespace has a legacy token secret of the bootstrap serviceaccount and a few other secrets.
+func newServiceAccountTokenSecrets(b *testing.B, clusters, secretsPerCluster int) (*kubefake.Clientset, cache.Indexer) {
+	indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+		bootstrap.ServiceAccountTokenSecretsIndexKey: bootstrap.IndexServiceAccountTokenSecretsByServiceAccount,
+	})
+
+	objs := []runtime.Object{}
+	for i := 0; i < clusters; i++ {
+		clusterName := fmt.Sprintf("cluster%d", i)
+		saName := helpers.GetBootstrapSAName(clusterName)
+		tokenSecret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        fmt.Sprintf("%s-token-abcde", saName),
+				Namespace:   clusterName,
+				Annotations: map[string]string{corev1.ServiceAccountNameKey: saName},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+			Data: map[string][]byte{"token": []byte(fmt.Sprintf("token-%d", i))},
+		}
+		objs = append(objs, tokenSecret)
+		if err := indexer.Add(tokenSecret); err != nil {
+			b.Fatal(err)
+		}
+
+		for j := 0; j < secretsPerCluster; j++ {
+			objs = append(objs, &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("secret%d", j), Namespace: clusterName},
+				Type:       corev1.SecretTypeOpaque,
+			})
+		}
+	}
+
+	return kubefake.NewSimpleClientset(objs...), indexer
+}
+
+func BenchmarkValidateLegacyServiceAccountToken(b *testing.B) {
+	ctx := context.Background()
+	kubeClient, indexer := newServiceAccountTokenSecrets(b, 500, 20)
+	saName := helpers.GetBootstrapSAName("cluster0")
+
+	b.Run("list", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if !validateLegacyServiceAccountToken(ctx, kubeClient, nil, saName, "cluster0", "token-0") {
+				b.Fatal("expected the token is valid")
+			}
+		}
+	})
+
+	b.Run("indexer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if !validateLegacyServiceAccountToken(ctx, kubeClient, indexer, saName, "cluster0", "token-0") {
+				b.Fatal("expected the token is valid")
+			}
+		}
+	})
+}
+
+func BenchmarkGetBootstrapToken(b *testing.B) {
+	ctx := context.Background()
+	kubeClient, indexer := newServiceAccountTokenSecrets(b, 500, 20)
+	saName := helpers.GetBootstrapSAName("cluster0")
+
+	b.Run("list", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			token, _, _, err := bootstrap.GetBootstrapToken(ctx, kubeClient, nil, saName, "cluster0", 3600)
+			if err != nil || string(token) != "token-0" {
+				b.Fatalf("unexpected token %s, %v", token, err)
+			}
+		}
+	})
+
+	b.Run("indexer", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			token, _, _, err := bootstrap.GetBootstrapToken(ctx, kubeClient, indexer, saName, "cluster0", 3600)
+			if err != nil || string(token) != "token-0" {
+				b.Fatalf("unexpected token %s, %v", token, err)
+			}
+		}
+	})
+}
+
+func TestBuildImportSecretWithRenderHash(t *testing.T) {
+	kubeClient := kubefake.NewSimpleClientset(&corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "test-image-pull-secret-secret",
+			Namespace: "cluster-secret",
+		},
+		Type: corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{corev1.DockerConfigJsonKey: []byte("{}")},
+	})
+	clientHolder := &helpers.ClientHolder{
+		KubeClient:          kubeClient,
+		RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).Build(),
+		ImageRegistryClient: imageregistry.NewClient(kubeClient),
+	}
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "test"}}
+
+	importSecret, configSecret, err := buildImportSecret(context.TODO(), clientHolder, cluster,
+		operatorv1.InstallModeDefault, nil, nil, nil, klusterletConfigSettings{}, []byte("kubeconfig"), nil, nil, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	renderHash := importSecret.Annotations[constants.ImportSecretRenderHashAnnotation]
+	if len(renderHash) == 0 {
+		t.Fatalf("expected the render hash annotation, but got %v", importSecret.Annotations)
+	}
+	if configSecret == nil {
+		t.Errorf("expected the cluster import config secret is built")
+	}
+
+	// the manifests of the previous import secret are reused if the render inputs are not changed
+	previousImportSecret := importSecret.DeepCopy()
+	previousImportSecret.Data[constants.ImportSecretImportYamlKey] = []byte("reused")
+	importSecret, configSecret, err = buildImportSecret(context.TODO(), clientHolder, cluster,
+		operatorv1.InstallModeDefault, nil, nil, nil, klusterletConfigSettings{}, []byte("kubeconfig"), nil, nil, previousImportSecret)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(importSecret.Data[constants.ImportSecretImportYamlKey]) != "reused" {
+		t.Errorf("expected the import.yaml is reused")
+	}
+	if configSecret != nil {
+		t.Errorf("expected the cluster import config secret is not built")
+	}
+
+	// the manifests are rendered again once the render inputs are changed
+	importSecret, _, err = buildImportSecret(context.TODO(), clientHolder, cluster,
+		operatorv1.InstallModeDefault, nil, nil, nil, klusterletConfigSettings{}, []byte("kubeconfig2"), nil, nil, previousImportSecret)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(importSecret.Data[constants.ImportSecretImportYamlKey]) == "reused" {
+		t.Errorf("expected the import.yaml is rendered again")
+	}
+	if importSecret.Annotations[constants.ImportSecretRenderHashAnnotation] == renderHash {
+		t.Errorf("expected the render hash is changed")
+	}
+}
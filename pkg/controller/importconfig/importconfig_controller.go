@@ -5,17 +5,23 @@ package importconfig
 
 import (
 	"context"
+	goerrors "errors"
+	"fmt"
 
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
 	"github.com/openshift/library-go/pkg/operator/events"
+	"k8s.io/client-go/tools/cache"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 
 	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 
 	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
@@ -31,6 +37,9 @@ type ReconcileImportConfig struct {
 	klusterletconfigLister listerklusterletconfigv1alpha1.KlusterletConfigLister
 	scheme                 *runtime.Scheme
 	recorder               events.Recorder
+	mcRecorder             kevents.EventRecorder
+	// index the service account token secrets by their service accounts
+	secretIndexer          cache.Indexer
 	importControllerConfig *helpers.ImportControllerConfig
 }
 
@@ -47,6 +56,7 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 	managedCluster := &clusterv1.ManagedCluster{}
 	err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: request.Name}, managedCluster)
 	if errors.IsNotFound(err) {
+		deleteBootstrapTokenMetrics(request.Name)
 		return reconcile.Result{}, nil
 	}
 	if err != nil {
@@ -79,33 +89,197 @@ func (r *ReconcileImportConfig) Reconcile(ctx context.Context, request reconcile
 		return reconcile.Result{}, err
 	}
 
+	tokenPolicy, err := r.importControllerConfig.GetBootstrapTokenPolicy(managedCluster, r.klusterletconfigLister)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the import secret is not updated with the invalid hub kube apiserver endpoints, the failure is reported with
+	// the klusterlet settings condition of the managed cluster.
+	endpointsVal, err := helpers.GetKlusterletConfigAnnotation(klusterletconfigName,
+		constants.HubKubeAPIServerEndpointsAnnotation, r.klusterletconfigLister)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	hubEndpoints, err := bootstrap.ParseHubKubeAPIServerEndpoints(endpointsVal)
+	if err != nil {
+		reqLogger.Info("Invalid hub kube apiserver endpoints of the klusterletconfig", "error", err.Error())
+		return reconcile.Result{}, r.updateSettingsCondition(managedCluster, &bootstrap.KlusterletSettingsError{
+			Err: fmt.Errorf("invalid hub kube apiserver endpoints of the klusterletconfig: %v", err),
+		})
+	}
+
+	extraManifests, err := r.importControllerConfig.GetExtraManifests(managedCluster, r.klusterletconfigLister)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// the import secret is not updated with the invalid klusterlet manifest patches, the failure is reported with
+	// the condition of the managed cluster instead of retrying, since it cannot be resolved until the patches are
+	// changed.
+	patchesVal, err := helpers.GetKlusterletConfigAnnotation(klusterletconfigName,
+		constants.KlusterletManifestPatchesAnnotation, r.klusterletconfigLister)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	manifestPatches, err := bootstrap.ParseKlusterletManifestPatches(patchesVal)
+	if err != nil {
+		reqLogger.Info("Invalid klusterlet manifest patches of the klusterletconfig", "error", err.Error())
+		return reconcile.Result{}, r.updateManifestPatchCondition(managedCluster, manifestPatches, err)
+	}
+
+	// the import secret is not updated with the invalid klusterlet settings either, the failure is reported with
+	// the condition of the managed cluster as well.
+	klusterletSettings, err := getKlusterletConfigSettings(klusterletconfigName, r.klusterletconfigLister)
+	var settingsErr *bootstrap.KlusterletSettingsError
+	if goerrors.As(err, &settingsErr) {
+		reqLogger.Info("Invalid klusterlet settings of the klusterletconfig", "error", err.Error())
+		return reconcile.Result{}, r.updateSettingsCondition(managedCluster, err)
+	}
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	migrateLegacyToken, err := r.importControllerConfig.MigrateLegacyBootstrapTokens()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	generateConfigSecret, err := r.importControllerConfig.GenerateImportConfig()
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// keep the token expiration of the current import secret to find out if the token is refreshed
+	var previousTokenExpiration []byte
+	previousImportSecret, err := getImportSecret(ctx, r.clientHolder, managedCluster.Name)
+	switch {
+	case err == nil:
+		previousTokenExpiration = previousImportSecret.Data[constants.ImportSecretTokenExpiration]
+	case errors.IsNotFound(err):
+		previousImportSecret = nil
+	default:
+		return reconcile.Result{}, err
+	}
+
+	// revoke the bootstrap tokens once the inputs of the import secret are validated, the bootstrap sa is recreated
+	// right after it is deleted. The revocation time is recorded on the managed cluster, so the tokens are revoked
+	// only once for each rotation request even if the import secret cannot be built yet.
+	rotateToken := isBootstrapTokenRotationRequested(managedCluster)
+	if rotateToken && !isBootstrapTokenRevoked(managedCluster) {
+		if err := r.revokeBootstrapTokens(ctx, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
+		if _, err := helpers.ApplyResources(
+			r.clientHolder, r.recorder, r.scheme, managedCluster, objects...); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
 	// build the bootstrap kubeconfig
 	bootstrapKubeconfigData, tokenCreation, tokenExpiration, err := buildBootstrapKubeconfigData(ctx, r.clientHolder,
-		managedCluster, mergedKlusterletConfig)
+		r.secretIndexer, managedCluster, mergedKlusterletConfig, hubEndpoints, tokenPolicy, rotateToken,
+		migrateLegacyToken)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
 
-	// rebuild the import secret and save it if it is modified
+	// rebuild the import secret and save it if it is modified, the klusterlet manifests of the current import
+	// secret are reused if their render inputs are not changed, unless the values.yaml of the cluster import
+	// config secret is required.
+	renderedImportSecret := previousImportSecret
+	if generateConfigSecret {
+		renderedImportSecret = nil
+	}
 	importSecret, configSecret, err := buildImportSecret(ctx, r.clientHolder, managedCluster, mode, mergedKlusterletConfig,
-		bootstrapKubeconfigData, tokenCreation, tokenExpiration)
+		extraManifests, manifestPatches, klusterletSettings, bootstrapKubeconfigData, tokenCreation, tokenExpiration,
+		renderedImportSecret)
+	var patchErr *bootstrap.KlusterletManifestPatchError
+	if goerrors.As(err, &patchErr) {
+		reqLogger.Info("Failed to apply the klusterlet manifest patches", "error", err.Error())
+		return reconcile.Result{}, r.updateManifestPatchCondition(managedCluster, manifestPatches, err)
+	}
+	if goerrors.As(err, &settingsErr) {
+		reqLogger.Info("Invalid klusterlet settings", "error", err.Error())
+		return reconcile.Result{}, r.updateSettingsCondition(managedCluster, err)
+	}
 	if err != nil {
 		return reconcile.Result{}, err
 	}
+	if err := r.updateManifestPatchCondition(managedCluster, manifestPatches, nil); err != nil {
+		return reconcile.Result{}, err
+	}
+	if err := r.updateSettingsCondition(managedCluster, nil); err != nil {
+		return reconcile.Result{}, err
+	}
 
 	if _, err := helpers.ApplyResources(
 		r.clientHolder, r.recorder, r.scheme, managedCluster, importSecret); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	generateConfigSecret, err := r.importControllerConfig.GenerateImportConfig()
-	if err != nil || !generateConfigSecret {
-		return reconcile.Result{}, err
+	recordBootstrapTokenMetrics(managedCluster.Name, tokenCreation, tokenExpiration)
+
+	if rotateToken {
+		if err := r.completeBootstrapTokenRotation(ctx, managedCluster); err != nil {
+			return reconcile.Result{}, err
+		}
+	} else {
+		warnBootstrapTokenExpiring(r.mcRecorder, managedCluster, previousTokenExpiration, tokenExpiration)
+	}
+
+	result := reconcile.Result{}
+	if migrateLegacyToken {
+		waiting, err := r.migrateLegacyBootstrapToken(ctx, managedCluster, bootstrapKubeconfigData, tokenExpiration)
+		if err != nil {
+			return reconcile.Result{}, err
+		}
+		if waiting {
+			result.RequeueAfter = legacyTokenMigrationRequeuePeriod
+		}
+	}
+
+	if !generateConfigSecret {
+		return result, nil
 	}
 	if _, err := helpers.ApplyResources(
 		r.clientHolder, r.recorder, r.scheme, managedCluster, configSecret); err != nil {
 		return reconcile.Result{}, err
 	}
 
-	return reconcile.Result{}, nil
+	return result, nil
+}
+
+// updateManifestPatchCondition reports the result of the klusterlet manifest patches with the
+// KlusterletManifestsPatched condition of the managed cluster, the condition is removed once there is no patch.
+func (r *ReconcileImportConfig) updateManifestPatchCondition(managedCluster *clusterv1.ManagedCluster,
+	patches []bootstrap.KlusterletManifestPatch, patchErr error) error {
+	if len(patches) == 0 && patchErr == nil {
+		if meta.FindStatusCondition(managedCluster.Status.Conditions,
+			constants.ConditionKlusterletManifestsPatched) == nil {
+			return nil
+		}
+		return helpers.RemoveManagedClusterCondition(r.clientHolder.RuntimeClient, managedCluster.Name,
+			constants.ConditionKlusterletManifestsPatched)
+	}
+
+	return helpers.UpdateManagedClusterPatchCondition(r.clientHolder.RuntimeClient, managedCluster,
+		helpers.NewKlusterletManifestsPatchedCondition(patchErr), r.mcRecorder)
+}
+
+// updateSettingsCondition reports the invalid klusterlet settings with the KlusterletSettingsValid condition of the
+// managed cluster, the condition is removed once the settings are valid.
+func (r *ReconcileImportConfig) updateSettingsCondition(managedCluster *clusterv1.ManagedCluster,
+	settingsErr error) error {
+	if settingsErr == nil {
+		if meta.FindStatusCondition(managedCluster.Status.Conditions,
+			constants.ConditionKlusterletSettingsValid) == nil {
+			return nil
+		}
+		return helpers.RemoveManagedClusterCondition(r.clientHolder.RuntimeClient, managedCluster.Name,
+			constants.ConditionKlusterletSettingsValid)
+	}
+
+	return helpers.UpdateManagedClusterSettingsCondition(r.clientHolder.RuntimeClient, managedCluster,
+		helpers.NewKlusterletSettingsInvalidCondition(settingsErr), r.mcRecorder)
 }
@@ -6,6 +6,7 @@ package importconfig
 import (
 	"context"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -22,6 +23,8 @@ import (
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers/imageregistry"
 	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
@@ -29,6 +32,7 @@ import (
 	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/tools/cache"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
 	runtimeclient "sigs.k8s.io/controller-runtime/pkg/client"
@@ -1006,6 +1010,787 @@ func TestReconcile(t *testing.T) {
 				}
 			},
 		},
+		{
+			name: "klusterletconfig with klusterlet manifest patches",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletManifestPatchesAnnotation: `[{"target":{"kind":"Deployment","name":"klusterlet"},"patch":{"metadata":{"annotations":{"example.com/patched":"true"}}}}]`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !strings.Contains(string(importSecret.Data[constants.ImportSecretImportYamlKey]),
+					"example.com/patched") {
+					t.Errorf("expected the klusterlet deployment is patched")
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if !meta.IsStatusConditionTrue(cluster.Status.Conditions, constants.ConditionKlusterletManifestsPatched) {
+					t.Errorf("expected the patched condition is true, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "klusterletconfig with klusterlet resources, placement and sync labels",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Labels: map[string]string{
+							"cloud": "Amazon",
+							"env":   "prod",
+						},
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletResourcesAnnotation: `{"limits":{"memory":"4Gi"}}`,
+						constants.KlusterletReplicasAnnotation:  "2",
+						constants.KlusterletTopologySpreadConstraintsAnnotation: `[{"maxSkew":1,` +
+							`"topologyKey":"topology.kubernetes.io/zone","whenUnsatisfiable":"ScheduleAnyway"}]`,
+						constants.KlusterletSyncLabelsAnnotation: "env",
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				importSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				importYaml := string(importSecret.Data[constants.ImportSecretImportYamlKey])
+				if !strings.Contains(importYaml, "replicas: 2") || !strings.Contains(importYaml, "memory: 4Gi") {
+					t.Errorf("expected the klusterlet resources are set, but got %s", importYaml)
+				}
+				if !strings.Contains(importYaml, "topology.kubernetes.io/zone") {
+					t.Errorf("expected the klusterlet topology spread constraints are set, but got %s", importYaml)
+				}
+				if !strings.Contains(importYaml, "--enable-sync-labels") || !strings.Contains(importYaml, "env: prod") {
+					t.Errorf("expected the klusterlet labels are synced, but got %s", importYaml)
+				}
+				if strings.Contains(importYaml, "cloud: Amazon") {
+					t.Errorf("expected the cloud label is not synced, but got %s", importYaml)
+				}
+			},
+		},
+		{
+			name: "klusterletconfig with failing klusterlet manifest patches",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletManifestPatchesAnnotation: `[{"target":{"kind":"Deployment","name":"nonexistent"},"patch":{"metadata":{"annotations":{"a":"b"}}}}]`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not created, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				cond := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletManifestsPatched)
+				if cond == nil || cond.Status != metav1.ConditionFalse ||
+					cond.Reason != constants.ConditionReasonKlusterletManifestsPatchFailed {
+					t.Errorf("expected the patch failed condition, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "rotate bootstrap token with failing klusterlet manifest patches",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig:  "test-klusterletconfig",
+							constants.RotateBootstrapTokenAnnotation: "",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletManifestPatchesAnnotation: `[{"target":{"kind":"Deployment","name":"nonexistent"},"patch":{"metadata":{"annotations":{"a":"b"}}}}]`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				if deleted := countServiceAccountDeletions(kubeClient); deleted != 1 {
+					t.Errorf("expected the bootstrap sa is deleted once, but got %d", deleted)
+				}
+				if _, err := kubeClient.CoreV1().ServiceAccounts("test").Get(
+					context.TODO(), "test-bootstrap-sa", metav1.GetOptions{}); err != nil {
+					t.Errorf("expected the bootstrap sa is recreated, but got %v", err)
+				}
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not created, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if _, ok := cluster.Annotations[constants.RotateBootstrapTokenAnnotation]; !ok {
+					t.Errorf("expected the rotation annotation is kept, but got %v", cluster.Annotations)
+				}
+				if _, ok := cluster.Annotations[constants.BootstrapTokenRevokedAnnotation]; !ok {
+					t.Errorf("expected the revocation time is recorded, but got %v", cluster.Annotations)
+				}
+			},
+		},
+		{
+			name: "rotate revoked bootstrap token with failing klusterlet manifest patches",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig:   "test-klusterletconfig",
+							constants.RotateBootstrapTokenAnnotation:  "",
+							constants.BootstrapTokenRevokedAnnotation: "2026-10-17T00:00:00Z",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletManifestPatchesAnnotation: `[{"target":{"kind":"Deployment","name":"nonexistent"},"patch":{"metadata":{"annotations":{"a":"b"}}}}]`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				if deleted := countServiceAccountDeletions(kubeClient); deleted != 0 {
+					t.Errorf("expected the bootstrap sa is not deleted again, but got %d deletions", deleted)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				if revokedAt := cluster.Annotations[constants.BootstrapTokenRevokedAnnotation]; revokedAt != "2026-10-17T00:00:00Z" {
+					t.Errorf("expected the revocation time is not changed, but got %s", revokedAt)
+				}
+			},
+		},
+		{
+			name: "klusterletconfig with invalid klusterlet resources",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletResourcesAnnotation: `{"limits":{"memory":"invalid"}}`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not created, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				cond := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletSettingsValid)
+				if cond == nil || cond.Status != metav1.ConditionFalse ||
+					cond.Reason != constants.ConditionReasonKlusterletSettingsInvalid {
+					t.Errorf("expected the settings invalid condition, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "klusterletconfig with invalid klusterlet replicas",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.KlusterletReplicasAnnotation: "0",
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not created, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				cond := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletSettingsValid)
+				if cond == nil || cond.Status != metav1.ConditionFalse ||
+					cond.Reason != constants.ConditionReasonKlusterletSettingsInvalid {
+					t.Errorf("expected the settings invalid condition, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
+		{
+			name: "klusterletconfig with invalid hub kube apiserver endpoints",
+			clientObjs: []runtimeclient.Object{
+				&corev1.Namespace{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+					},
+				},
+				&clusterv1.ManagedCluster{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "test",
+						Annotations: map[string]string{
+							apiconstants.AnnotationKlusterletConfig: "test-klusterletconfig",
+						},
+					},
+				},
+				&configv1.Infrastructure{
+					ObjectMeta: metav1.ObjectMeta{
+						Name: "cluster",
+					},
+				},
+			},
+			runtimeObjs: []runtime.Object{
+				&corev1.ServiceAccount{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa",
+						Namespace: "test",
+					},
+					Secrets: []corev1.ObjectReference{
+						{
+							Name:      "test-bootstrap-sa-token-5pw5c",
+							Namespace: "test",
+						},
+					},
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "test-bootstrap-sa-token-5pw5c",
+						Namespace: "test",
+					},
+					Data: map[string][]byte{
+						"token": []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      os.Getenv("DEFAULT_IMAGE_PULL_SECRET"),
+						Namespace: os.Getenv("POD_NAMESPACE"),
+					},
+					Data: map[string][]byte{
+						corev1.DockerConfigJsonKey: []byte("fake-token"),
+					},
+					Type: corev1.SecretTypeDockerConfigJson,
+				},
+				&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "kube-root-ca.crt",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"ca.crt": string(rootCACertData),
+					},
+				},
+			},
+			klusterletconfig: &klusterletconfigv1alpha1.KlusterletConfig{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "test-klusterletconfig",
+					Annotations: map[string]string{
+						constants.HubKubeAPIServerEndpointsAnnotation: `[{"url":"http://api.example.com:6443"}]`,
+					},
+				},
+			},
+			request: reconcile.Request{
+				NamespacedName: types.NamespacedName{
+					Name: "test",
+				},
+			},
+			validateFunc: func(t *testing.T, client runtimeclient.Client, kubeClient kubernetes.Interface) {
+				_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "test-import", metav1.GetOptions{})
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected the import secret is not created, but got %v", err)
+				}
+
+				cluster := &clusterv1.ManagedCluster{}
+				if err := client.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				cond := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletSettingsValid)
+				if cond == nil || cond.Status != metav1.ConditionFalse ||
+					cond.Reason != constants.ConditionReasonKlusterletSettingsInvalid {
+					t.Errorf("expected the settings invalid condition, but got %v", cluster.Status.Conditions)
+				}
+			},
+		},
 		{
 			name: "disable-auto-import annotation set - import secret still created",
 			clientObjs: []runtimeclient.Object{
@@ -1111,8 +1896,9 @@ func TestReconcile(t *testing.T) {
 			klusterletconfigLister := listerklusterletconfigv1alpha1.NewKlusterletConfigLister(klusterletconfigInformer.GetIndexer())
 
 			clientHolder := &helpers.ClientHolder{
-				KubeClient:          kubeClient,
-				RuntimeClient:       fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).Build(),
+				KubeClient: kubeClient,
+				RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.clientObjs...).
+					WithStatusSubresource(&clusterv1.ManagedCluster{}).Build(),
 				ImageRegistryClient: imageregistry.NewClient(kubeClient),
 			}
 
@@ -1123,6 +1909,7 @@ func TestReconcile(t *testing.T) {
 				scheme:                 testscheme,
 				klusterletconfigLister: klusterletconfigLister,
 				recorder:               eventstesting.NewTestingEventRecorder(t),
+				mcRecorder:             kevents.NewFakeRecorder(10),
 				importControllerConfig: helpers.NewImportControllerConfig("test", importConfigLister,
 					logf.Log.WithName("fake-import-controller-config")),
 			}
@@ -1136,3 +1923,13 @@ func TestReconcile(t *testing.T) {
 		})
 	}
 }
+
+func countServiceAccountDeletions(kubeClient kubernetes.Interface) int {
+	deleted := 0
+	for _, action := range kubeClient.(*kubefake.Clientset).Actions() {
+		if action.GetVerb() == "delete" && action.GetResource().Resource == "serviceaccounts" {
+			deleted++
+		}
+	}
+	return deleted
+}
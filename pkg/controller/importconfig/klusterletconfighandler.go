@@ -10,74 +10,16 @@ import (
 	"k8s.io/klog/v2"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	operatorv1 "open-cluster-management.io/api/operator/v1"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
 )
 
-var _ handler.EventHandler = &enqueueManagedClusterInKlusterletConfigAnnotation{}
-
-type enqueueManagedClusterInKlusterletConfigAnnotation struct {
-	managedclusterIndexer cache.Indexer
-}
-
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Create(ctx context.Context,
-	evt event.TypedCreateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
-}
-
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Update(ctx context.Context,
-	evt event.TypedUpdateEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.ObjectNew.GetName(), q)
-}
-
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Delete(ctx context.Context,
-	evt event.TypedDeleteEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
-}
-
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) Generic(ctx context.Context,
-	evt event.TypedGenericEvent[client.Object], q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	e.enqueue(evt.Object.GetName(), q)
-}
-
-func (e *enqueueManagedClusterInKlusterletConfigAnnotation) enqueue(klusterletconfigName string,
-	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-	objs, err := e.managedclusterIndexer.ByIndex(ManagedClusterKlusterletConfigAnnotationIndexKey, klusterletconfigName)
-	if err != nil {
-		klog.Error(err, "Failed to get managed clusters by klusterletconfig annotation by indexer", "klusterletconfig", klusterletconfigName)
-		return
-	}
-	for _, obj := range objs {
-		mc := obj.(*clusterv1.ManagedCluster)
-		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
-			Name: mc.GetName(),
-		}})
-	}
-}
-
-const (
-	ManagedClusterKlusterletConfigAnnotationIndexKey = "annotation-klusterletconfig"
-)
-
-func IndexManagedClusterByKlusterletconfigAnnotation(obj interface{}) ([]string, error) {
-	managedCluster, ok := obj.(*clusterv1.ManagedCluster)
-	if !ok {
-		return nil, fmt.Errorf("not a managedcluster object")
-	}
-	klusterletconfigs := []string{constants.GlobalKlusterletConfigName}
-	klusterletconfig, ok := managedCluster.GetAnnotations()[apiconstants.AnnotationKlusterletConfig]
-	if ok && klusterletconfig != "" {
-		klusterletconfigs = append(klusterletconfigs, klusterletconfig)
-	}
-	return klusterletconfigs, nil
-}
-
 const (
 	KlusterletConfigBootstrapKubeConfigSecretsIndexKey = "klusterletconfig-bootstrapkubeconfig-secrets"
 )
@@ -123,7 +65,7 @@ func (e *enqueueManagedClusterByBootstrapKubeConfigSecrets) enqueue(secretName s
 	for _, kcObj := range klusterletconfigObjs {
 		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
 		managedclusterObjs, err := e.managedclusterIndexer.ByIndex(
-			ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
 		if err != nil {
 			klog.Error(err, "Failed to get managedclusters by klusterletconfig annotation by indexer",
 				"klusterletconfig", kc.GetName())
@@ -206,7 +148,7 @@ func (e *enqueueManagedClusterByCustomizedCAConfigmaps) enqueue(
 	for _, kcObj := range klusterletconfigObjs {
 		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
 		managedclusterObjs, err := e.managedclusterIndexer.ByIndex(
-			ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
 		if err != nil {
 			klog.Error(err, "Failed to get managedclusters by klusterletconfig annotation by indexer",
 				"klusterletconfig", kc.GetName())
@@ -242,3 +184,146 @@ func IndexKlusterletConfigByCustomizedCAConfigmaps() func(obj interface{}) ([]st
 func configmapKey(namespace, name string) string {
 	return namespace + "/" + name
 }
+
+const (
+	KlusterletConfigExtraManifestsIndexKey = "klusterletconfig-extra-manifests"
+)
+
+var _ handler.EventHandler = &enqueueManagedClusterByExtraManifests{}
+
+// enqueueManagedClusterByExtraManifests enqueues all of the managedclusters if the configmap or secret is one of
+// the extra manifests of the import-controller-config, otherwise it first finds the klusterletconfigs that using
+// the configmap or secret as extra manifests, then finds the managedclusters that using the klusterletconfigs.
+type enqueueManagedClusterByExtraManifests struct {
+	// kind is the manifest reference kind of the watched objects, configmap or secret
+	kind string
+
+	importControllerConfig *helpers.ImportControllerConfig
+
+	// index klusterletconfig by the extra manifests annotation
+	klusterletconfigIndexer cache.Indexer
+
+	// index managedcluster by the annotation
+	managedclusterIndexer cache.Indexer
+}
+
+func (e *enqueueManagedClusterByExtraManifests) Create(ctx context.Context,
+	evt event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueManagedClusterByExtraManifests) Update(ctx context.Context,
+	evt event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.ObjectNew.GetName(), q)
+}
+
+func (e *enqueueManagedClusterByExtraManifests) Delete(ctx context.Context,
+	evt event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueManagedClusterByExtraManifests) Generic(ctx context.Context,
+	evt event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *enqueueManagedClusterByExtraManifests) enqueue(name string,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	ref := helpers.ManifestReference{Kind: e.kind, Name: name}
+
+	refs, err := e.importControllerConfig.GetControllerExtraManifests()
+	if err != nil {
+		klog.Error(err, "Failed to get the extra manifests of the import controller config")
+		return
+	}
+	for _, r := range refs {
+		if r == ref {
+			enqueueAllManagedClusters(e.managedclusterIndexer, q)
+			return
+		}
+	}
+
+	klusterletconfigObjs, err := e.klusterletconfigIndexer.ByIndex(KlusterletConfigExtraManifestsIndexKey, ref.String())
+	if err != nil {
+		klog.Error(err, "Failed to get klusterletconfigs by extra manifests by indexer", "extraManifests", ref.String())
+		return
+	}
+	for _, kcObj := range klusterletconfigObjs {
+		kc := kcObj.(*klusterletconfigv1alpha1.KlusterletConfig)
+		managedclusterObjs, err := e.managedclusterIndexer.ByIndex(
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey, kc.GetName())
+		if err != nil {
+			klog.Error(err, "Failed to get managedclusters by klusterletconfig annotation by indexer",
+				"klusterletconfig", kc.GetName())
+			return
+		}
+		for _, mcObj := range managedclusterObjs {
+			mc := mcObj.(*clusterv1.ManagedCluster)
+			q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+				Name: mc.GetName(),
+			}})
+		}
+	}
+}
+
+func IndexKlusterletConfigByExtraManifests() func(obj interface{}) ([]string, error) {
+	return func(obj interface{}) ([]string, error) {
+		kc, ok := obj.(*klusterletconfigv1alpha1.KlusterletConfig)
+		if !ok {
+			return nil, fmt.Errorf("not a klustereltconfig object")
+		}
+
+		// the invalid annotation is ignored
+		refs, err := helpers.ParseManifestReferences(kc.GetAnnotations()[constants.ExtraManifestsAnnotation])
+		if err != nil {
+			return nil, nil
+		}
+
+		var keys []string
+		for _, ref := range refs {
+			keys = append(keys, ref.String())
+		}
+		return keys, nil
+	}
+}
+
+var _ handler.EventHandler = &enqueueManagedClustersByControllerConfig{}
+
+// enqueueManagedClustersByControllerConfig enqueues all of the managedclusters once the import-controller-config
+// is changed.
+type enqueueManagedClustersByControllerConfig struct {
+	managedclusterIndexer cache.Indexer
+}
+
+func (e *enqueueManagedClustersByControllerConfig) Create(ctx context.Context,
+	evt event.CreateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func (e *enqueueManagedClustersByControllerConfig) Update(ctx context.Context,
+	evt event.UpdateEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func (e *enqueueManagedClustersByControllerConfig) Delete(ctx context.Context,
+	evt event.DeleteEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func (e *enqueueManagedClustersByControllerConfig) Generic(ctx context.Context,
+	evt event.GenericEvent, q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	enqueueAllManagedClusters(e.managedclusterIndexer, q)
+}
+
+func enqueueAllManagedClusters(managedclusterIndexer cache.Indexer,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	for _, mcObj := range managedclusterIndexer.List() {
+		mc, ok := mcObj.(*clusterv1.ManagedCluster)
+		if !ok {
+			continue
+		}
+		q.Add(reconcile.Request{NamespacedName: types.NamespacedName{
+			Name: mc.GetName(),
+		}})
+	}
+}
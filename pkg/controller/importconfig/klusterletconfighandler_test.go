@@ -3,11 +3,15 @@ package importconfig
 import (
 	"context"
 	"testing"
+	"time"
 
 	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
 	"k8s.io/client-go/tools/cache"
 	"k8s.io/client-go/util/workqueue"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
@@ -15,161 +19,10 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
-)
-
-func TestEnqueueManagedClusterInKlusterletConfigAnnotation(t *testing.T) {
-	mcs := []*clusterv1.ManagedCluster{
-		{
-			ObjectMeta: v1.ObjectMeta{
-				Name:        "test1",
-				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig1"},
-			},
-		},
-		{
-			ObjectMeta: v1.ObjectMeta{
-				Name:        "test2",
-				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig2"},
-			},
-		},
-		{
-			ObjectMeta: v1.ObjectMeta{
-				Name:        "test3",
-				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig2"},
-			},
-		},
-	}
 
-	testcases := []struct {
-		addEvent func(handler handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request])
-		verify   func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request])
-	}{
-		{
-			addEvent: func(h handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				// Create a new create event for the managed cluster
-				evt := event.CreateEvent{
-					Object: &klusterletconfigv1alpha1.KlusterletConfig{
-						ObjectMeta: v1.ObjectMeta{
-							Name: "test-klusterletconfig1",
-						},
-					},
-				}
-				h.Create(context.Background(), evt, queue)
-			},
-			verify: func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				if queue.Len() != 1 {
-					t.Errorf("Expected queue length to be 1, but got %d", queue.Len())
-				}
-				expectedRequest := reconcile.Request{
-					NamespacedName: types.NamespacedName{
-						Name: "test1",
-					},
-				}
-				// Get the item from the queue
-				item, _ := queue.Get()
-				// Check that the item is the expected reconcile request
-				if item != expectedRequest {
-					t.Errorf("Expected item to be %v, but got %v", expectedRequest, item)
-				}
-			},
-		},
-		{
-			addEvent: func(h handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				// Create a new create event for the managed cluster
-				evt := event.DeleteEvent{
-					Object: &klusterletconfigv1alpha1.KlusterletConfig{
-						ObjectMeta: v1.ObjectMeta{
-							Name: "test-klusterletconfig2",
-						},
-					},
-				}
-				h.Delete(context.Background(), evt, queue)
-			},
-			verify: func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				if queue.Len() != 2 {
-					t.Errorf("Expected queue length to be 2, but got %d", queue.Len())
-				}
-			},
-		},
-		{
-			addEvent: func(h handler.EventHandler, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				// Create a new create event for the managed cluster
-				evt := event.CreateEvent{
-					Object: &klusterletconfigv1alpha1.KlusterletConfig{
-						ObjectMeta: v1.ObjectMeta{
-							Name: "global",
-						},
-					},
-				}
-				h.Create(context.Background(), evt, queue)
-			},
-			verify: func(t *testing.T, queue workqueue.TypedRateLimitingInterface[reconcile.Request]) {
-				if queue.Len() != 3 {
-					t.Errorf("Expected queue length to be 3, but got %d", queue.Len())
-				}
-			},
-		},
-	}
-
-	for _, tc := range testcases {
-		// Create a fake clientset and indexer
-		indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
-			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
-		})
-
-		// Create a new kcHandler
-		kcHandler := &enqueueManagedClusterInKlusterletConfigAnnotation{
-			managedclusterIndexer: indexer,
-		}
-
-		// Create a new queue
-		queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
-		// Add the managed clusters to the indexer
-		for _, mc := range mcs {
-			err := indexer.Add(mc)
-			if err != nil {
-				t.Fatalf("Failed to add managed cluster to indexer: %v", err)
-			}
-		}
-
-		tc.addEvent(kcHandler, queue)
-		tc.verify(t, queue)
-	}
-}
-
-func TestIndexManagedClusterByKlusterletconfigAnnotation(t *testing.T) {
-	// Create a new managed cluster with a klusterletconfig annotation
-	mcWithAnnotation := &clusterv1.ManagedCluster{
-		ObjectMeta: v1.ObjectMeta{
-			Name:        "test1",
-			Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig"},
-		},
-	}
-
-	// Create a new managed cluster without a klusterletconfig annotation
-	mcWithoutAnnotation := &clusterv1.ManagedCluster{
-		ObjectMeta: v1.ObjectMeta{
-			Name: "test2",
-		},
-	}
-
-	// Test the function with a managed cluster that has a klusterletconfig annotation
-	result, err := IndexManagedClusterByKlusterletconfigAnnotation(mcWithAnnotation)
-	if err != nil {
-		t.Fatalf("Unexpected error: %v", err)
-	}
-	if len(result) != 2 || result[0] != "global" || result[1] != "test-klusterletconfig" {
-		t.Errorf("Expected result to be [\"global, test-klusterletconfig\"], but got %v", result)
-	}
-
-	// Test the function with a managed cluster that does not have a klusterletconfig annotation
-	result, err = IndexManagedClusterByKlusterletconfigAnnotation(mcWithoutAnnotation)
-	if err != nil {
-		t.Fatalf("Unexpected error: %v", err)
-	}
-	if len(result) != 1 || result[0] != "global" {
-		t.Errorf("Expected result to be [\"global\"], but got %v", result)
-	}
-}
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+	"github.com/stolostron/managedcluster-import-controller/pkg/source"
+)
 
 func TestEnqueueManagedClusterByBootstrapKubeconfigSecret(t *testing.T) {
 	mcs := []*clusterv1.ManagedCluster{
@@ -306,7 +159,7 @@ func TestEnqueueManagedClusterByBootstrapKubeconfigSecret(t *testing.T) {
 	for _, tc := range testcases {
 		// Create fake clientet and indexer
 		managedClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
-			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey: source.IndexManagedClusterByKlusterletconfigAnnotation,
 		})
 
 		klusterletconfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
@@ -537,7 +390,7 @@ func TestEnqueueManagedClusterByCustomizedCAConfigmaps(t *testing.T) {
 	for _, tc := range testcases {
 		// Create fake clientet and indexer
 		managedClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
-			ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			source.ManagedClusterKlusterletConfigAnnotationIndexKey: source.IndexManagedClusterByKlusterletconfigAnnotation,
 		})
 
 		klusterletconfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
@@ -573,3 +426,164 @@ func TestEnqueueManagedClusterByCustomizedCAConfigmaps(t *testing.T) {
 		tc.verify(t, queue)
 	}
 }
+
+func TestEnqueueManagedClusterByExtraManifests(t *testing.T) {
+	mcs := []*clusterv1.ManagedCluster{
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        "test1",
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-kc1"},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name:        "test2",
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-kc2"},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "test3",
+			},
+		},
+	}
+
+	klusterletconfigs := []*klusterletconfigv1alpha1.KlusterletConfig{
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "test-kc1",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/extra-manifests": "configmap/cm1,secret/secret1",
+				},
+			},
+		},
+		{
+			ObjectMeta: v1.ObjectMeta{
+				Name: "test-kc2",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/extra-manifests": "secret/secret1",
+				},
+			},
+		},
+	}
+
+	controllerConfig := &corev1.ConfigMap{
+		ObjectMeta: v1.ObjectMeta{
+			Name:      "import-controller-config",
+			Namespace: "test",
+		},
+		Data: map[string]string{
+			"extraManifests": "configmap/global",
+		},
+	}
+
+	cases := []struct {
+		name          string
+		kind          string
+		objName       string
+		expectedNames []string
+	}{
+		{
+			name:          "configmap of a klusterletconfig",
+			kind:          helpers.ManifestReferenceKindConfigMap,
+			objName:       "cm1",
+			expectedNames: []string{"test1"},
+		},
+		{
+			name:          "secret of klusterletconfigs",
+			kind:          helpers.ManifestReferenceKindSecret,
+			objName:       "secret1",
+			expectedNames: []string{"test1", "test2"},
+		},
+		{
+			name:          "configmap of the import controller config",
+			kind:          helpers.ManifestReferenceKindConfigMap,
+			objName:       "global",
+			expectedNames: []string{"test1", "test2", "test3"},
+		},
+		{
+			name:    "secret with the same name of the configmap",
+			kind:    helpers.ManifestReferenceKindSecret,
+			objName: "cm1",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			managedClusterIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+				source.ManagedClusterKlusterletConfigAnnotationIndexKey: source.IndexManagedClusterByKlusterletconfigAnnotation,
+			})
+			klusterletconfigIndexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+				KlusterletConfigExtraManifestsIndexKey: IndexKlusterletConfigByExtraManifests(),
+			})
+			for _, mc := range mcs {
+				if err := managedClusterIndexer.Add(mc); err != nil {
+					t.Fatalf("Failed to add managed cluster to indexer: %v", err)
+				}
+			}
+			for _, kc := range klusterletconfigs {
+				if err := klusterletconfigIndexer.Add(kc); err != nil {
+					t.Fatalf("Failed to add klusterletconfig to indexer: %v", err)
+				}
+			}
+
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if err := kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(controllerConfig); err != nil {
+				t.Fatalf("Failed to add configmap to store: %v", err)
+			}
+
+			handler := &enqueueManagedClusterByExtraManifests{
+				kind: c.kind,
+				importControllerConfig: helpers.NewImportControllerConfig("test",
+					kubeInformerFactory.Core().V1().ConfigMaps().Lister(), log),
+				managedclusterIndexer:   managedClusterIndexer,
+				klusterletconfigIndexer: klusterletconfigIndexer,
+			}
+
+			queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+			handler.Update(context.Background(), event.UpdateEvent{
+				ObjectOld: &v1.PartialObjectMetadata{ObjectMeta: v1.ObjectMeta{Name: c.objName, Namespace: "test"}},
+				ObjectNew: &v1.PartialObjectMetadata{ObjectMeta: v1.ObjectMeta{Name: c.objName, Namespace: "test"}},
+			}, queue)
+
+			if queue.Len() != len(c.expectedNames) {
+				t.Fatalf("Expected queue length to be %d, but got %d", len(c.expectedNames), queue.Len())
+			}
+			names := sets.New[string]()
+			for i := 0; i < len(c.expectedNames); i++ {
+				item, _ := queue.Get()
+				names.Insert(item.Name)
+			}
+			if !names.Equal(sets.New(c.expectedNames...)) {
+				t.Errorf("Expected %v to be enqueued, but got %v", c.expectedNames, names.UnsortedList())
+			}
+		})
+	}
+}
+
+func TestIndexKlusterletConfigByExtraManifests(t *testing.T) {
+	kc := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: v1.ObjectMeta{
+			Annotations: map[string]string{
+				"import.open-cluster-management.io/extra-manifests": "configmap/cm1,secret/secret1",
+			},
+		},
+	}
+	result, err := IndexKlusterletConfigByExtraManifests()(kc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 || result[0] != "configmap/cm1" || result[1] != "secret/secret1" {
+		t.Errorf("Expected result to be [\"configmap/cm1\", \"secret/secret1\"], but got %v", result)
+	}
+
+	kc.Annotations["import.open-cluster-management.io/extra-manifests"] = "invalid"
+	result, err = IndexKlusterletConfigByExtraManifests()(kc)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 0 {
+		t.Errorf("Expected result to be [], but got %v", result)
+	}
+}
@@ -11,9 +11,11 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	rbacv1 "k8s.io/api/rbac/v1"
 	"k8s.io/apimachinery/pkg/api/equality"
+	kevents "k8s.io/client-go/tools/events"
 	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
 	"sigs.k8s.io/controller-runtime/pkg/event"
 	"sigs.k8s.io/controller-runtime/pkg/handler"
@@ -36,11 +38,30 @@ func Add(ctx context.Context,
 	mgr manager.Manager,
 	clientHolder *helpers.ClientHolder,
 	informerHolder *source.InformerHolder,
+	mcRecorder kevents.EventRecorder,
 	componentNamespace string) error {
 
 	// All bootstrap kubeconfigs should created in the same pod namespace
 	podNS := os.Getenv(constants.PodNamespaceEnvVarName)
 
+	importControllerConfig := helpers.NewImportControllerConfig(componentNamespace,
+		informerHolder.ControllerConfigLister, log)
+
+	inPodNamespace := predicate.Funcs{
+		GenericFunc: func(e event.GenericEvent) bool {
+			return e.Object.GetNamespace() == podNS
+		},
+		CreateFunc: func(e event.CreateEvent) bool {
+			return e.Object.GetNamespace() == podNS
+		},
+		DeleteFunc: func(e event.DeleteEvent) bool {
+			return e.Object.GetNamespace() == podNS
+		},
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectNew.GetNamespace() == podNS
+		},
+	}
+
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
@@ -53,7 +74,7 @@ func Add(ctx context.Context,
 				DeleteFunc:  func(e event.DeleteEvent) bool { return false },
 				CreateFunc:  func(e event.CreateEvent) bool { return true },
 				UpdateFunc: func(e event.UpdateEvent) bool {
-					// handle the labels changes for image registry
+					// handle the labels changes for image registry and the labels that are synced to the agents
 					// handle the annotations changes for node placement and klusterletconfig
 					// handle the claim changes for priority class
 					return !equality.Semantic.DeepEqual(e.ObjectOld.GetLabels(), e.ObjectNew.GetLabels()) ||
@@ -109,8 +130,8 @@ func Add(ctx context.Context,
 		).
 		Watches(
 			&klusterletconfigv1alpha1.KlusterletConfig{},
-			&enqueueManagedClusterInKlusterletConfigAnnotation{
-				managedclusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+			&source.KlusterletConfigEventHandler{
+				ManagedClusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
 			},
 			builder.WithPredicates(predicate.Funcs{
 				GenericFunc: func(e event.GenericEvent) bool { return true },
@@ -179,12 +200,84 @@ func Add(ctx context.Context,
 				},
 			}),
 		).
+		// the configmaps and secrets of the extra manifests
+		WatchesMetadata(
+			&corev1.ConfigMap{},
+			&enqueueManagedClusterByExtraManifests{
+				kind:                    helpers.ManifestReferenceKindConfigMap,
+				importControllerConfig:  importControllerConfig,
+				managedclusterIndexer:   informerHolder.ManagedClusterInformer.GetIndexer(),
+				klusterletconfigIndexer: informerHolder.KlusterletConfigInformer.GetIndexer(),
+			},
+			builder.WithPredicates(inPodNamespace),
+		).
+		WatchesMetadata(
+			&corev1.Secret{},
+			&enqueueManagedClusterByExtraManifests{
+				kind:                    helpers.ManifestReferenceKindSecret,
+				importControllerConfig:  importControllerConfig,
+				managedclusterIndexer:   informerHolder.ManagedClusterInformer.GetIndexer(),
+				klusterletconfigIndexer: informerHolder.KlusterletConfigInformer.GetIndexer(),
+			},
+			builder.WithPredicates(inPodNamespace),
+		).
+		WatchesRawSource(
+			source.NewControllerConfigSource(informerHolder.ControllerConfigInformer,
+				&enqueueManagedClustersByControllerConfig{
+					managedclusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+				},
+				predicate.Predicate(predicate.Funcs{
+					GenericFunc: func(e event.GenericEvent) bool { return false },
+					CreateFunc: func(e event.CreateEvent) bool {
+						return hasImportConfigKeys(e.Object)
+					},
+					DeleteFunc: func(e event.DeleteEvent) bool {
+						return hasImportConfigKeys(e.Object)
+					},
+					UpdateFunc: func(e event.UpdateEvent) bool {
+						// only handle the changes of the keys that affect the import config
+						new, okNew := e.ObjectNew.(*corev1.ConfigMap)
+						old, okOld := e.ObjectOld.(*corev1.ConfigMap)
+						if okNew && okOld {
+							for _, key := range importConfigKeys {
+								if new.Data[key] != old.Data[key] {
+									return true
+								}
+							}
+						}
+						return false
+					},
+				})),
+		).
 		Complete(&ReconcileImportConfig{
 			clientHolder:           clientHolder,
 			klusterletconfigLister: informerHolder.KlusterletConfigLister,
 			scheme:                 mgr.GetScheme(),
 			recorder:               helpers.NewEventRecorder(clientHolder.KubeClient, ControllerName),
-			importControllerConfig: helpers.NewImportControllerConfig(componentNamespace, informerHolder.ControllerConfigLister, log),
+			mcRecorder:             mcRecorder,
+			secretIndexer:          informerHolder.ServiceAccountTokenSecretInformer.GetIndexer(),
+			importControllerConfig: importControllerConfig,
 		})
 	return err
 }
+
+// importConfigKeys are the keys of the controller config that affect the import config of the managed clusters
+var importConfigKeys = []string{
+	constants.ExtraManifestsKey,
+	constants.LegacyBootstrapTokenMigrationKey,
+	constants.BootstrapTokenLifetimeKey,
+	constants.BootstrapTokenRefreshThresholdKey,
+}
+
+func hasImportConfigKeys(obj client.Object) bool {
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok {
+		return false
+	}
+	for _, key := range importConfigKeys {
+		if len(cm.Data[key]) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+var legacyBootstrapTokenMigratedCounter = prometheus.NewCounter(prometheus.CounterOpts{
+	Name: "managedcluster_import_legacy_bootstrap_token_migrated_clusters_total",
+	Help: "The number of the managed clusters whose legacy service account token secrets are replaced with " +
+		"the TokenRequest tokens and deleted.",
+})
+
+func init() {
+	metrics.Registry.MustRegister(legacyBootstrapTokenMigratedCounter)
+}
+
+// legacyTokenMigrationRequeuePeriod is the period to check again if the klusterlet manifestwork with the new
+// bootstrap kubeconfig is applied
+const legacyTokenMigrationRequeuePeriod = time.Minute
+
+// migrateLegacyBootstrapToken deletes the legacy token secrets of the bootstrap serviceaccount once the import
+// secret of the managed cluster uses a TokenRequest token. The secrets are kept until the cluster has joined,
+// so the import.yaml that was obtained before can still be used to import the cluster, and until the klusterlet
+// manifestwork with the new bootstrap kubeconfig is applied, so the klusterlet has a valid bootstrap token. The
+// secrets of the cluster with auto-import disabled are kept, since its klusterlet manifestworks are ReadOnly and
+// the klusterlet is never updated with the new bootstrap kubeconfig. It returns true if the migration is waiting
+// for the klusterlet manifestwork to be applied.
+func (r *ReconcileImportConfig) migrateLegacyBootstrapToken(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, bootstrapKubeconfigData, tokenExpiration []byte) (bool, error) {
+	if len(tokenExpiration) == 0 {
+		// the import secret still uses a legacy token
+		return false, nil
+	}
+	if !meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		return false, nil
+	}
+
+	secrets, err := r.getLegacyTokenSecrets(ctx, managedCluster.Name)
+	if err != nil {
+		return false, err
+	}
+	if len(secrets) == 0 {
+		return false, nil
+	}
+
+	if _, autoImportDisabled := managedCluster.Annotations[apiconstants.DisableAutoImportAnnotation]; autoImportDisabled {
+		log.Info("The legacy token secrets are kept since the auto import is disabled",
+			"managedCluster", managedCluster.Name)
+		return false, nil
+	}
+
+	applied, err := r.isBootstrapKubeconfigApplied(ctx, managedCluster, bootstrapKubeconfigData)
+	if err != nil {
+		return false, err
+	}
+	if !applied {
+		log.V(5).Info("Wait for the klusterlet manifestwork with the new bootstrap kubeconfig to be applied",
+			"managedCluster", managedCluster.Name)
+		return true, nil
+	}
+
+	deleted, err := r.deleteLegacyTokenSecrets(ctx, managedCluster.Name, secrets)
+	if err != nil {
+		return false, err
+	}
+	if deleted == 0 {
+		return false, nil
+	}
+
+	legacyBootstrapTokenMigratedCounter.Inc()
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	r.mcRecorder.Eventf(mc, nil, corev1.EventTypeNormal,
+		constants.EventReasonLegacyTokenMigrated, constants.EventReasonLegacyTokenMigrated,
+		"The bootstrap token of %s is migrated to the TokenRequest token, %d legacy token secrets are deleted",
+		mc.Name, deleted)
+	return false, nil
+}
+
+// isBootstrapKubeconfigApplied returns true if the latest generation of the klusterlet manifestwork of the managed
+// cluster is applied and its bootstrap kubeconfig has the token of the given bootstrap kubeconfig. The klusterlet
+// manifestwork of a hosted cluster is in the namespace of its hosting cluster.
+func (r *ReconcileImportConfig) isBootstrapKubeconfigApplied(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster, bootstrapKubeconfigData []byte) (bool, error) {
+	_, _, _, _, requiredToken, _, err := helpers.ParseKubeConfigData(bootstrapKubeconfigData)
+	if err != nil {
+		return false, err
+	}
+
+	workNamespace := managedCluster.Name
+	workName := fmt.Sprintf("%s-%s", managedCluster.Name, constants.KlusterletSuffix)
+	if helpers.DetermineKlusterletMode(managedCluster) == operatorv1.InstallModeHosted {
+		if workNamespace, err = helpers.GetHostingCluster(managedCluster); err != nil {
+			return false, err
+		}
+		workName = helpers.HostedKlusterletManifestWorkName(managedCluster.Name)
+	}
+
+	work, err := r.clientHolder.WorkClient.WorkV1().ManifestWorks(workNamespace).Get(ctx, workName,
+		metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	cond := meta.FindStatusCondition(work.Status.Conditions, workv1.WorkApplied)
+	if cond == nil || cond.Status != metav1.ConditionTrue || cond.ObservedGeneration != work.Generation {
+		return false, nil
+	}
+
+	for _, manifest := range work.Spec.Workload.Manifests {
+		secret, ok := helpers.MustCreateObject(manifest.Raw).(*corev1.Secret)
+		if !ok {
+			continue
+		}
+		if secret.Name != constants.DefaultBootstrapHubKubeConfigSecretName &&
+			!strings.HasPrefix(secret.Name, bootstrap.EndpointBootstrapKubeConfigSecretPrefix) {
+			continue
+		}
+		_, _, _, _, token, _, err := helpers.ParseKubeConfigData(secret.Data["kubeconfig"])
+		if err != nil || token != requiredToken {
+			return false, nil
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// getLegacyTokenSecrets returns the legacy token secrets of the bootstrap serviceaccount of the managed cluster
+func (r *ReconcileImportConfig) getLegacyTokenSecrets(ctx context.Context, clusterName string) ([]*corev1.Secret, error) {
+	saName := helpers.GetBootstrapSAName(clusterName)
+
+	candidates, err := bootstrap.GetServiceAccountTokenSecretCandidates(ctx, r.clientHolder.KubeClient,
+		r.secretIndexer, saName, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	secrets := []*corev1.Secret{}
+	for _, secret := range candidates {
+		if secret.Type != corev1.SecretTypeServiceAccountToken ||
+			secret.Annotations[corev1.ServiceAccountNameKey] != saName {
+			continue
+		}
+		secrets = append(secrets, secret)
+	}
+	return secrets, nil
+}
+
+// deleteLegacyTokenSecrets deletes the given legacy token secrets of the managed cluster and returns the number
+// of the deleted secrets.
+func (r *ReconcileImportConfig) deleteLegacyTokenSecrets(ctx context.Context, clusterName string,
+	secrets []*corev1.Secret) (int, error) {
+	deleted := 0
+	for _, secret := range secrets {
+		err := r.clientHolder.KubeClient.CoreV1().Secrets(clusterName).Delete(ctx, secret.Name,
+			metav1.DeleteOptions{})
+		if errors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return deleted, err
+		}
+
+		log.Info("The legacy token secret is deleted", "managedCluster", clusterName, "secret", secret.Name)
+		deleted++
+	}
+
+	return deleted, nil
+}
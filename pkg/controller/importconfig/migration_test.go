@@ -0,0 +1,202 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kevents "k8s.io/client-go/tools/events"
+	workfake "open-cluster-management.io/api/client/work/clientset/versioned/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	workv1 "open-cluster-management.io/api/work/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/bootstrap"
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+func TestMigrateLegacyBootstrapToken(t *testing.T) {
+	joined := metav1.Condition{Type: clusterv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue}
+
+	newKubeconfig := func(token string) []byte {
+		kubeconfig, err := bootstrap.CreateBootstrapKubeConfigWithEndpoints("default-cluster",
+			[]bootstrap.KubeAPIServerEndpointConfig{{KubeAPIServer: "https://api.example.com:6443"}}, []byte(token))
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return kubeconfig
+	}
+	klusterletWork := func(token string, applied bool) *workv1.ManifestWork {
+		bootstrapSecret := &corev1.Secret{
+			TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "bootstrap-hub-kubeconfig",
+				Namespace: "open-cluster-management-agent",
+			},
+			Data: map[string][]byte{"kubeconfig": newKubeconfig(token)},
+		}
+		raw, err := json.Marshal(bootstrapSecret)
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		status := metav1.ConditionFalse
+		if applied {
+			status = metav1.ConditionTrue
+		}
+		return &workv1.ManifestWork{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-klusterlet", Namespace: "cluster1", Generation: 2},
+			Spec: workv1.ManifestWorkSpec{
+				Workload: workv1.ManifestsTemplate{
+					Manifests: []workv1.Manifest{{RawExtension: runtime.RawExtension{Raw: raw}}},
+				},
+			},
+			Status: workv1.ManifestWorkStatus{
+				Conditions: []metav1.Condition{
+					{Type: workv1.WorkApplied, Status: status, ObservedGeneration: 2},
+				},
+			},
+		}
+	}
+
+	hostedKlusterletWork := func() *workv1.ManifestWork {
+		work := klusterletWork("new-token", true)
+		work.Name = "cluster1-hosted-klusterlet"
+		work.Namespace = "hosting"
+		return work
+	}
+
+	cases := []struct {
+		name            string
+		annotations     map[string]string
+		conditions      []metav1.Condition
+		tokenExpiration string
+		works           []runtime.Object
+		expectedWaiting bool
+		expectedDeleted bool
+	}{
+		{
+			name:       "legacy token is in use",
+			conditions: []metav1.Condition{joined},
+		},
+		{
+			name:            "cluster has not joined",
+			tokenExpiration: "2027-10-18T00:00:00Z",
+		},
+		{
+			name:            "klusterlet manifestwork is not applied",
+			conditions:      []metav1.Condition{joined},
+			tokenExpiration: "2027-10-18T00:00:00Z",
+			works:           []runtime.Object{klusterletWork("new-token", false)},
+			expectedWaiting: true,
+		},
+		{
+			name:            "klusterlet manifestwork has the legacy token",
+			conditions:      []metav1.Condition{joined},
+			tokenExpiration: "2027-10-18T00:00:00Z",
+			works:           []runtime.Object{klusterletWork("legacy-token", true)},
+			expectedWaiting: true,
+		},
+		{
+			name:            "auto import is disabled",
+			annotations:     map[string]string{apiconstants.DisableAutoImportAnnotation: ""},
+			conditions:      []metav1.Condition{joined},
+			tokenExpiration: "2027-10-18T00:00:00Z",
+			works:           []runtime.Object{klusterletWork("new-token", true)},
+		},
+		{
+			name: "hosted klusterlet manifestwork is applied",
+			annotations: map[string]string{
+				constants.KlusterletDeployModeAnnotation: "Hosted",
+				constants.HostingClusterNameAnnotation:   "hosting",
+			},
+			conditions:      []metav1.Condition{joined},
+			tokenExpiration: "2027-10-18T00:00:00Z",
+			works:           []runtime.Object{hostedKlusterletWork()},
+			expectedDeleted: true,
+		},
+		{
+			name:            "legacy token is migrated",
+			conditions:      []metav1.Condition{joined},
+			tokenExpiration: "2027-10-18T00:00:00Z",
+			works:           []runtime.Object{klusterletWork("new-token", true)},
+			expectedDeleted: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.TODO()
+			kubeClient := kubefake.NewSimpleClientset(
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "cluster1-bootstrap-sa-token-abcde",
+						Namespace:   "cluster1",
+						Annotations: map[string]string{corev1.ServiceAccountNameKey: "cluster1-bootstrap-sa"},
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+				&corev1.Secret{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:        "other-token-abcde",
+						Namespace:   "cluster1",
+						Annotations: map[string]string{corev1.ServiceAccountNameKey: "other"},
+					},
+					Type: corev1.SecretTypeServiceAccountToken,
+				},
+			)
+			recorder := kevents.NewFakeRecorder(1)
+			r := &ReconcileImportConfig{
+				clientHolder: &helpers.ClientHolder{
+					KubeClient: kubeClient,
+					WorkClient: workfake.NewSimpleClientset(c.works...),
+				},
+				mcRecorder: recorder,
+			}
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1", Annotations: c.annotations},
+				Status:     clusterv1.ManagedClusterStatus{Conditions: c.conditions},
+			}
+
+			waiting, err := r.migrateLegacyBootstrapToken(ctx, cluster, newKubeconfig("new-token"),
+				[]byte(c.tokenExpiration))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if waiting != c.expectedWaiting {
+				t.Errorf("expected waiting %v, but got %v", c.expectedWaiting, waiting)
+			}
+
+			secrets, err := kubeClient.CoreV1().Secrets("cluster1").List(ctx, metav1.ListOptions{})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			expectedSecrets := 2
+			if c.expectedDeleted {
+				expectedSecrets = 1
+			}
+			if len(secrets.Items) != expectedSecrets {
+				t.Errorf("expected %d secrets, but got %d", expectedSecrets, len(secrets.Items))
+			}
+
+			select {
+			case event := <-recorder.Events:
+				if !c.expectedDeleted || !strings.Contains(event, "LegacyBootstrapTokenMigrated") {
+					t.Errorf("unexpected event %s", event)
+				}
+			default:
+				if c.expectedDeleted {
+					t.Errorf("expected an event, but got none")
+				}
+			}
+		})
+	}
+}
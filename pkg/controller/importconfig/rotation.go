@@ -0,0 +1,83 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+func isBootstrapTokenRotationRequested(managedCluster *clusterv1.ManagedCluster) bool {
+	_, ok := managedCluster.Annotations[constants.RotateBootstrapTokenAnnotation]
+	return ok
+}
+
+func isBootstrapTokenRevoked(managedCluster *clusterv1.ManagedCluster) bool {
+	_, ok := managedCluster.Annotations[constants.BootstrapTokenRevokedAnnotation]
+	return ok
+}
+
+// revokeBootstrapTokens deletes the bootstrap serviceaccount of the managed cluster to invalidate all of the
+// tokens that are bound to it, and deletes its legacy token secrets. Then it records the revocation time with
+// an annotation of the managed cluster, the serviceaccount is recreated by the caller.
+func (r *ReconcileImportConfig) revokeBootstrapTokens(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster) error {
+	clusterName := managedCluster.Name
+	saName := helpers.GetBootstrapSAName(clusterName)
+
+	err := r.clientHolder.KubeClient.CoreV1().ServiceAccounts(clusterName).Delete(ctx, saName,
+		metav1.DeleteOptions{})
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	secrets, err := r.getLegacyTokenSecrets(ctx, clusterName)
+	if err != nil {
+		return err
+	}
+	if _, err := r.deleteLegacyTokenSecrets(ctx, clusterName, secrets); err != nil {
+		return err
+	}
+
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	if managedCluster.Annotations == nil {
+		managedCluster.Annotations = map[string]string{}
+	}
+	managedCluster.Annotations[constants.BootstrapTokenRevokedAnnotation] = time.Now().UTC().Format(time.RFC3339)
+	if err := r.clientHolder.RuntimeClient.Patch(ctx, managedCluster, patch); err != nil {
+		return err
+	}
+
+	log.Info("The bootstrap tokens are revoked", "managedCluster", clusterName, "serviceAccount", saName)
+	return nil
+}
+
+// completeBootstrapTokenRotation removes the rotation and revocation annotations from the managed cluster and
+// records the completion time with an event.
+func (r *ReconcileImportConfig) completeBootstrapTokenRotation(ctx context.Context,
+	managedCluster *clusterv1.ManagedCluster) error {
+	patch := client.MergeFrom(managedCluster.DeepCopy())
+	cluster := managedCluster.DeepCopy()
+	delete(cluster.Annotations, constants.RotateBootstrapTokenAnnotation)
+	delete(cluster.Annotations, constants.BootstrapTokenRevokedAnnotation)
+	if err := r.clientHolder.RuntimeClient.Patch(ctx, cluster, patch); err != nil {
+		return err
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	r.mcRecorder.Eventf(mc, nil, corev1.EventTypeNormal,
+		constants.EventReasonBootstrapTokenRotated, constants.EventReasonBootstrapTokenRotated,
+		"The bootstrap token of %s is rotated at %s", mc.Name, time.Now().UTC().Format(time.RFC3339))
+	return nil
+}
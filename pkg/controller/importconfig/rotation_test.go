@@ -0,0 +1,102 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
+)
+
+func TestBootstrapTokenRotation(t *testing.T) {
+	ctx := context.TODO()
+	cluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "cluster1",
+			Annotations: map[string]string{
+				"import.open-cluster-management.io/rotate-bootstrap-token": "",
+				"test": "test",
+			},
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster1-bootstrap-sa", Namespace: "cluster1"},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster1-bootstrap-sa-token-abcde",
+				Namespace:   "cluster1",
+				Annotations: map[string]string{corev1.ServiceAccountNameKey: "cluster1-bootstrap-sa"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "other-token-abcde",
+				Namespace:   "cluster1",
+				Annotations: map[string]string{corev1.ServiceAccountNameKey: "other"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		},
+	)
+	recorder := kevents.NewFakeRecorder(1)
+	r := &ReconcileImportConfig{
+		clientHolder: &helpers.ClientHolder{
+			KubeClient:    kubeClient,
+			RuntimeClient: fake.NewClientBuilder().WithScheme(testscheme).WithObjects(cluster).Build(),
+		},
+		mcRecorder: recorder,
+	}
+
+	if !isBootstrapTokenRotationRequested(cluster) {
+		t.Errorf("expected the rotation is requested")
+	}
+
+	if err := r.revokeBootstrapTokens(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !isBootstrapTokenRevoked(cluster) {
+		t.Errorf("expected the revocation time is recorded")
+	}
+	_, err := kubeClient.CoreV1().ServiceAccounts("cluster1").Get(ctx, "cluster1-bootstrap-sa", metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the bootstrap sa is deleted, but got %v", err)
+	}
+	_, err = kubeClient.CoreV1().Secrets("cluster1").Get(ctx, "cluster1-bootstrap-sa-token-abcde", metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the legacy token secret is deleted, but got %v", err)
+	}
+	if _, err := kubeClient.CoreV1().Secrets("cluster1").Get(ctx, "other-token-abcde", metav1.GetOptions{}); err != nil {
+		t.Errorf("expected the other token secret is kept, but got %v", err)
+	}
+
+	if err := r.completeBootstrapTokenRotation(ctx, cluster); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	updated := &clusterv1.ManagedCluster{}
+	if err := r.clientHolder.RuntimeClient.Get(ctx, types.NamespacedName{Name: "cluster1"}, updated); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if isBootstrapTokenRotationRequested(updated) || isBootstrapTokenRevoked(updated) {
+		t.Errorf("expected the rotation annotations are removed, but got %v", updated.Annotations)
+	}
+	if updated.Annotations["test"] != "test" {
+		t.Errorf("expected the other annotations are kept, but got %v", updated.Annotations)
+	}
+	if event := <-recorder.Events; !strings.Contains(event, "BootstrapTokenRotated") {
+		t.Errorf("unexpected event %s", event)
+	}
+}
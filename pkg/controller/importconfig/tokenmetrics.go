@@ -0,0 +1,109 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/util/sets"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+var (
+	bootstrapTokenCreationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managedcluster_import_bootstrap_token_creation_timestamp_seconds",
+		Help: "The creation time of the bootstrap token in the import secret of the managed cluster.",
+	}, []string{"managed_cluster"})
+
+	bootstrapTokenExpirationGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "managedcluster_import_bootstrap_token_expiration_timestamp_seconds",
+		Help: "The expiration time of the bootstrap token in the import secret of the managed cluster.",
+	}, []string{"managed_cluster"})
+
+	legacyBootstrapTokenClustersGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "managedcluster_import_legacy_bootstrap_token_clusters",
+		Help: "The number of the managed clusters whose bootstrap token is from a legacy service account " +
+			"token secret.",
+	})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		bootstrapTokenCreationGauge,
+		bootstrapTokenExpirationGauge,
+		legacyBootstrapTokenClustersGauge,
+	)
+}
+
+// legacyTokenClusters is the set of the managed clusters that use the legacy service account tokens
+var legacyTokenClusters = struct {
+	sync.Mutex
+	clusters sets.Set[string]
+}{clusters: sets.New[string]()}
+
+// recordBootstrapTokenMetrics records the creation and expiration time of the bootstrap token of the managed
+// cluster, the token without expiration is from a legacy service account token secret.
+func recordBootstrapTokenMetrics(clusterName string, tokenCreation, tokenExpiration []byte) {
+	legacy := len(tokenExpiration) == 0
+	setLegacyTokenCluster(clusterName, legacy)
+	if legacy {
+		bootstrapTokenCreationGauge.DeleteLabelValues(clusterName)
+		bootstrapTokenExpirationGauge.DeleteLabelValues(clusterName)
+		return
+	}
+
+	if creation, err := time.Parse(time.RFC3339, string(tokenCreation)); err == nil {
+		bootstrapTokenCreationGauge.WithLabelValues(clusterName).Set(float64(creation.Unix()))
+	}
+	if expiration, err := time.Parse(time.RFC3339, string(tokenExpiration)); err == nil {
+		bootstrapTokenExpirationGauge.WithLabelValues(clusterName).Set(float64(expiration.Unix()))
+	}
+}
+
+// deleteBootstrapTokenMetrics deletes the bootstrap token metrics of the managed cluster
+func deleteBootstrapTokenMetrics(clusterName string) {
+	setLegacyTokenCluster(clusterName, false)
+	bootstrapTokenCreationGauge.DeleteLabelValues(clusterName)
+	bootstrapTokenExpirationGauge.DeleteLabelValues(clusterName)
+}
+
+func setLegacyTokenCluster(clusterName string, legacy bool) {
+	legacyTokenClusters.Lock()
+	defer legacyTokenClusters.Unlock()
+
+	if legacy {
+		legacyTokenClusters.clusters.Insert(clusterName)
+	} else {
+		legacyTokenClusters.clusters.Delete(clusterName)
+	}
+	legacyBootstrapTokenClustersGauge.Set(float64(legacyTokenClusters.clusters.Len()))
+}
+
+// warnBootstrapTokenExpiring records a warning event if the managed cluster has not joined and the bootstrap
+// token in its import secret is refreshed, which means the previous token reaches the refresh threshold. The
+// import.yaml that was obtained before is going to expire, so it should be obtained again to import the cluster.
+func warnBootstrapTokenExpiring(recorder kevents.EventRecorder, managedCluster *clusterv1.ManagedCluster,
+	previousExpiration, tokenExpiration []byte) {
+	if len(previousExpiration) == 0 || string(previousExpiration) == string(tokenExpiration) {
+		return
+	}
+	if meta.IsStatusConditionTrue(managedCluster.Status.Conditions, clusterv1.ManagedClusterConditionJoined) {
+		return
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+		constants.EventReasonBootstrapTokenExpiring, constants.EventReasonBootstrapTokenExpiring,
+		"The bootstrap token in the import.yaml obtained before expires at %s, obtain the import.yaml again "+
+			"to import the cluster %s", previousExpiration, mc.Name)
+}
@@ -0,0 +1,120 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package importconfig
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+)
+
+func TestBootstrapTokenMetrics(t *testing.T) {
+	creation := time.Now().UTC().Truncate(time.Second)
+	expiration := creation.Add(24 * time.Hour)
+	// the metrics may be recorded by the other tests
+	legacyClusters := gaugeValue(t, legacyBootstrapTokenClustersGauge)
+
+	recordBootstrapTokenMetrics("cluster1", []byte(creation.Format(time.RFC3339)),
+		[]byte(expiration.Format(time.RFC3339)))
+	recordBootstrapTokenMetrics("cluster2", nil, nil)
+	recordBootstrapTokenMetrics("cluster3", nil, nil)
+
+	if v := gaugeValue(t, bootstrapTokenCreationGauge.WithLabelValues("cluster1")); v != float64(creation.Unix()) {
+		t.Errorf("expected creation %d, but got %v", creation.Unix(), v)
+	}
+	if v := gaugeValue(t, bootstrapTokenExpirationGauge.WithLabelValues("cluster1")); v != float64(expiration.Unix()) {
+		t.Errorf("expected expiration %d, but got %v", expiration.Unix(), v)
+	}
+	if v := gaugeValue(t, legacyBootstrapTokenClustersGauge); v != legacyClusters+2 {
+		t.Errorf("expected %v legacy clusters, but got %v", legacyClusters+2, v)
+	}
+
+	// cluster2 moves to the expiring token and cluster3 is deleted
+	recordBootstrapTokenMetrics("cluster2", []byte(creation.Format(time.RFC3339)),
+		[]byte(expiration.Format(time.RFC3339)))
+	deleteBootstrapTokenMetrics("cluster3")
+	deleteBootstrapTokenMetrics("cluster1")
+
+	if v := gaugeValue(t, legacyBootstrapTokenClustersGauge); v != legacyClusters {
+		t.Errorf("expected %v legacy clusters, but got %v", legacyClusters, v)
+	}
+	if bootstrapTokenExpirationGauge.DeleteLabelValues("cluster1") {
+		t.Errorf("expected the expiration metric of cluster1 is deleted")
+	}
+	deleteBootstrapTokenMetrics("cluster2")
+}
+
+func gaugeValue(t *testing.T, gauge prometheus.Gauge) float64 {
+	metric := &dto.Metric{}
+	if err := gauge.Write(metric); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	return metric.GetGauge().GetValue()
+}
+
+func TestWarnBootstrapTokenExpiring(t *testing.T) {
+	joined := metav1.Condition{Type: clusterv1.ManagedClusterConditionJoined, Status: metav1.ConditionTrue}
+
+	cases := []struct {
+		name               string
+		conditions         []metav1.Condition
+		previousExpiration string
+		tokenExpiration    string
+		expectedEvent      bool
+	}{
+		{
+			name:            "new import secret",
+			tokenExpiration: "2026-10-18T00:00:00Z",
+		},
+		{
+			name:               "token is not refreshed",
+			previousExpiration: "2026-10-18T00:00:00Z",
+			tokenExpiration:    "2026-10-18T00:00:00Z",
+		},
+		{
+			name:               "token is refreshed",
+			previousExpiration: "2026-10-18T00:00:00Z",
+			tokenExpiration:    "2027-10-18T00:00:00Z",
+			expectedEvent:      true,
+		},
+		{
+			name:               "token of joined cluster is refreshed",
+			conditions:         []metav1.Condition{joined},
+			previousExpiration: "2026-10-18T00:00:00Z",
+			tokenExpiration:    "2027-10-18T00:00:00Z",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			recorder := kevents.NewFakeRecorder(1)
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{Name: "cluster1"},
+				Status:     clusterv1.ManagedClusterStatus{Conditions: c.conditions},
+			}
+
+			warnBootstrapTokenExpiring(recorder, cluster, []byte(c.previousExpiration), []byte(c.tokenExpiration))
+
+			select {
+			case event := <-recorder.Events:
+				if !c.expectedEvent {
+					t.Errorf("unexpected event %s", event)
+				}
+				if !strings.Contains(event, "BootstrapTokenExpiring") || !strings.Contains(event, c.previousExpiration) {
+					t.Errorf("unexpected event %s", event)
+				}
+			default:
+				if c.expectedEvent {
+					t.Errorf("expected an event, but got none")
+				}
+			}
+		})
+	}
+}
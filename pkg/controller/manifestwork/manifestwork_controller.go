@@ -255,6 +255,8 @@ func createManifestWorks(
 //   - Deployment         -> deployments
 //   - Klusterlet         -> klusterlets
 //   - CustomResourceDefinition -> customresourcedefinitions
+//   - ConfigMap          -> configmaps (extra manifests)
+//   - NetworkPolicy      -> networkpolicies
 //
 // If a new type is added to the klusterlet ManifestWorks, verify that
 // meta.UnsafeGuessKindToResource produces the correct plural resource name for it.
@@ -8,6 +8,7 @@ import (
 	"strings"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/helpers"
 	"github.com/stolostron/managedcluster-import-controller/pkg/source"
@@ -41,6 +42,8 @@ func Add(ctx context.Context,
 	err := ctrl.NewControllerManagedBy(mgr).Named(ControllerName).
 		WithOptions(controller.Options{
 			MaxConcurrentReconciles: helpers.GetMaxConcurrentReconciles(),
+			// process the clusters with higher import priority first
+			NewQueue: helpers.NewImportPriorityQueueFunc(clientHolder.RuntimeClient),
 		}).
 		WatchesRawSource( // watch the import-secret
 			source.NewImportSecretSource(informerHolder.ImportSecretInformer,
@@ -83,6 +86,12 @@ func Add(ctx context.Context,
 							return true
 						}
 
+						// handle the change of the auto-import-strategy annotation
+						if e.ObjectOld.GetAnnotations()[constants.AutoImportStrategyAnnotation] !=
+							e.ObjectNew.GetAnnotations()[constants.AutoImportStrategyAnnotation] {
+							return true
+						}
+
 						// case 3: handle the removal of the disable-auto-import annotation
 						_, oldAutoImportDisabled := e.ObjectOld.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
 						_, newAutoImportDisabled := e.ObjectNew.GetAnnotations()[apiconstants.DisableAutoImportAnnotation]
@@ -91,6 +100,13 @@ func Add(ctx context.Context,
 				},
 			),
 		).
+		Watches( // watch the klusterletconfigs for the auto-import-strategy annotation
+			&klusterletconfigv1alpha1.KlusterletConfig{},
+			&source.KlusterletConfigEventHandler{
+				ManagedClusterIndexer: informerHolder.ManagedClusterInformer.GetIndexer(),
+			},
+			builder.WithPredicates(source.NewAutoImportStrategyPredicate()),
+		).
 		WatchesRawSource( // watch the klusterlet manifest works
 			source.NewKlusterletWorkSource(informerHolder.KlusterletWorkInformer,
 				&source.ManagedClusterResourceEventHandler{},
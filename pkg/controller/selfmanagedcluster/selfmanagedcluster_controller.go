@@ -109,7 +109,8 @@ func (r *ReconcileLocalCluster) Reconcile(ctx context.Context, request reconcile
 	}
 
 	immediateImport := helpers.IsImmediateImport(managedCluster.Annotations)
-	autoImportStrategy, err := r.importControllerConfig.GetAutoImportStrategy()
+	autoImportStrategy, err := r.importControllerConfig.GetClusterAutoImportStrategy(managedCluster,
+		r.informerHolder.KlusterletConfigLister)
 	if err != nil {
 		return reconcile.Result{}, err
 	}
@@ -0,0 +1,96 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// importVerbs are the verbs that ApplyResources requires to apply an object
+var importVerbs = []string{"get", "create", "update"}
+
+// CheckImportPermissions runs SelfSubjectAccessReviews on the managed cluster with the given client to find
+// the permissions that are required to apply the objects but are not granted to the client. It returns the
+// missing permissions in the format of `<verb> <resource>[.<group>] [<namespace>/]<name>`.
+func CheckImportPermissions(ctx context.Context, kubeClient kubernetes.Interface,
+	objs []runtime.Object) ([]string, error) {
+	reviewed := map[authorizationv1.ResourceAttributes]bool{}
+	missing := []string{}
+
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		gvk := obj.GetObjectKind().GroupVersionKind()
+		if gvk.Empty() {
+			if gvk, err = apiutil.GVKForObject(obj, genericScheme); err != nil {
+				return nil, err
+			}
+		}
+		gvr, _ := meta.UnsafeGuessKindToResource(gvk)
+
+		for _, verb := range importVerbs {
+			attributes := authorizationv1.ResourceAttributes{
+				Verb:      verb,
+				Group:     gvr.Group,
+				Version:   gvr.Version,
+				Resource:  gvr.Resource,
+				Namespace: accessor.GetNamespace(),
+				Name:      accessor.GetName(),
+			}
+			if verb == "create" {
+				// the name is unknown when a create request is authorized
+				attributes.Name = ""
+			}
+
+			if reviewed[attributes] {
+				continue
+			}
+			reviewed[attributes] = true
+
+			review, err := kubeClient.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx,
+				&authorizationv1.SelfSubjectAccessReview{
+					Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+						ResourceAttributes: &attributes,
+					},
+				}, metav1.CreateOptions{})
+			if err != nil {
+				return nil, err
+			}
+
+			if !review.Status.Allowed {
+				missing = append(missing, formatResourceAttributes(attributes))
+			}
+		}
+	}
+
+	return missing, nil
+}
+
+func formatResourceAttributes(attributes authorizationv1.ResourceAttributes) string {
+	resource := attributes.Resource
+	if len(attributes.Group) != 0 {
+		resource = fmt.Sprintf("%s.%s", attributes.Resource, attributes.Group)
+	}
+
+	name := attributes.Name
+	if len(attributes.Namespace) != 0 {
+		name = fmt.Sprintf("%s/%s", attributes.Namespace, attributes.Name)
+	}
+
+	if len(name) == 0 {
+		return fmt.Sprintf("%s %s", attributes.Verb, resource)
+	}
+	return fmt.Sprintf("%s %s %s", attributes.Verb, resource, name)
+}
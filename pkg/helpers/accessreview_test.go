@@ -0,0 +1,115 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestCheckImportPermissions(t *testing.T) {
+	importSecret := testinghelpers.GetImportSecret("test")
+
+	objs, err := importObjects(false, importSecret)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	bootstrapObjs, err := importObjects(true, importSecret)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cases := []struct {
+		name            string
+		objs            []runtime.Object
+		allowed         func(attributes *authorizationv1.ResourceAttributes) bool
+		reviewErr       error
+		expectedMissing []string
+		expectedErr     bool
+	}{
+		{
+			name: "all permissions are granted",
+			objs: objs,
+			allowed: func(attributes *authorizationv1.ResourceAttributes) bool {
+				return true
+			},
+			expectedMissing: []string{},
+		},
+		{
+			name: "crds cannot be created or updated",
+			objs: objs,
+			allowed: func(attributes *authorizationv1.ResourceAttributes) bool {
+				return attributes.Resource != "customresourcedefinitions" || attributes.Verb == "get"
+			},
+			expectedMissing: []string{
+				"create customresourcedefinitions.apiextensions.k8s.io",
+				"update customresourcedefinitions.apiextensions.k8s.io klusterlets.operator.open-cluster-management.io",
+			},
+		},
+		{
+			name: "secrets cannot be updated",
+			objs: bootstrapObjs,
+			allowed: func(attributes *authorizationv1.ResourceAttributes) bool {
+				return attributes.Resource != "secrets" || attributes.Verb != "update"
+			},
+			expectedMissing: []string{
+				"update secrets open-cluster-management-agent/bootstrap-hub-kubeconfig",
+			},
+		},
+		{
+			name:        "review failed",
+			objs:        objs,
+			reviewErr:   fmt.Errorf("connection refused"),
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reviews := map[authorizationv1.ResourceAttributes]int{}
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "selfsubjectaccessreviews",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if c.reviewErr != nil {
+						return true, nil, c.reviewErr
+					}
+					review := action.(clienttesting.CreateAction).GetObject().(*authorizationv1.SelfSubjectAccessReview)
+					reviews[*review.Spec.ResourceAttributes]++
+					review.Status.Allowed = c.allowed(review.Spec.ResourceAttributes)
+					return true, review, nil
+				})
+
+			missing, err := CheckImportPermissions(context.TODO(), kubeClient, c.objs)
+			if c.expectedErr {
+				if err == nil {
+					t.Errorf("expected error, but failed")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(missing, c.expectedMissing) {
+				t.Errorf("expected missing permissions %v, but got %v", c.expectedMissing, missing)
+			}
+			for attributes, count := range reviews {
+				if count > 1 {
+					t.Errorf("expected %v is reviewed once, but got %d", attributes, count)
+				}
+				if attributes.Verb == "create" && len(attributes.Name) != 0 {
+					t.Errorf("expected no name for the create review, but got %s", attributes.Name)
+				}
+			}
+		})
+	}
+}
@@ -6,6 +6,8 @@ package helpers
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-logr/logr"
@@ -14,6 +16,7 @@ import (
 	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
 	utilerrors "k8s.io/apimachinery/pkg/util/errors"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	"k8s.io/client-go/kubernetes"
@@ -96,6 +99,13 @@ type ImportHelper struct {
 	log            logr.Logger
 
 	generateClientHolderFunc GenerateClientHolderFunc
+	permissionPreflightCheck bool
+	importAdmission          *ImportAdmission
+	importSource             string
+
+	// the rollbacks of the failed attempts that are not done yet, they are continued by the following imports
+	rollbackLock     sync.Mutex
+	pendingRollbacks map[string]*importRollback
 }
 
 func (i *ImportHelper) WithGenerateClientHolderFunc(f GenerateClientHolderFunc) *ImportHelper {
@@ -103,6 +113,22 @@ func (i *ImportHelper) WithGenerateClientHolderFunc(f GenerateClientHolderFunc)
 	return i
 }
 
+// WithPermissionPreflightCheck enables to review the permissions of the managed cluster client before the
+// importing resources are applied, so the import fails without partially applied resources if the client
+// does not have enough permissions.
+func (i *ImportHelper) WithPermissionPreflightCheck(enabled bool) *ImportHelper {
+	i.permissionPreflightCheck = enabled
+	return i
+}
+
+// WithImportAdmission enables to ask the import admission for an import slot of the given source before the
+// managed cluster client is generated, the managed cluster is not imported until it gets a slot.
+func (i *ImportHelper) WithImportAdmission(admission *ImportAdmission, source string) *ImportHelper {
+	i.importAdmission = admission
+	i.importSource = source
+	return i
+}
+
 func NewImportHelper(informerHolder *source.InformerHolder,
 	recorder events.Recorder,
 	log logr.Logger) *ImportHelper {
@@ -122,6 +148,18 @@ func applyResourcesFunc(backupRestore bool, client *ClientHolder,
 	return ImportManagedClusterFromSecret(client, restMapper, recorder, importSecret)
 }
 
+// importObjects returns the objects that will be applied to the managed cluster by applyResourcesFunc
+func importObjects(backupRestore bool, importSecret *corev1.Secret) ([]runtime.Object, error) {
+	if backupRestore {
+		obj, err := bootstrapSecretObjectFromSecret(importSecret)
+		if err != nil {
+			return nil, err
+		}
+		return []runtime.Object{obj}, nil
+	}
+	return importObjectsFromSecret(importSecret)
+}
+
 // Import uses the managedClusterKubeClientSecret to generate a managed cluster client,
 // then use this client to import the managed cluster, return managed cluster import condition
 // when finished apply.
@@ -136,6 +174,11 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 	clusterName := cluster.Name
 	reqLogger := i.log.WithValues("Request.Name", clusterName)
 
+	// finish the rollback of the failed attempt before the next attempt
+	if rollback := i.getPendingRollback(clusterName); rollback != nil {
+		return i.continueRollback(clusterName, rollback)
+	}
+
 	// move to importing state for the condition
 	ic := meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionManagedClusterImportSucceeded)
 	if ic == nil || ic.Reason == constants.ConditionReasonManagedClusterWaitForImporting {
@@ -174,9 +217,37 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 			), false, nil
 	}
 
+	admitted, err := i.importAdmission.TryAcquire(clusterName, i.importSourceOf(managedClusterKubeClientSecret))
+	if err != nil {
+		return reconcile.Result{},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterImporting,
+				fmt.Sprintf("Get import slot failed: %v. Will retry", err),
+			), false, err
+	}
+	if !admitted {
+		reqLogger.Info(fmt.Sprintf("Waiting for an import slot for managed cluster %s", clusterName))
+		return reconcile.Result{RequeueAfter: constants.ImportSlotRetryInterval},
+			NewManagedClusterImportSucceededCondition(
+				metav1.ConditionFalse,
+				constants.ConditionReasonManagedClusterWaitingForImportSlot,
+				"The number of the imports in flight reaches the limit, wait for an import slot",
+			), false, nil
+	}
+	releaseSlot := true
+	defer func() {
+		if releaseSlot {
+			i.importAdmission.Release(clusterName)
+		}
+	}()
+
 	// build import client with managed cluster kube client secret
 	result, clientHolder, restMapper, err := i.generateClientHolderFunc(managedClusterKubeClientSecret)
 	if err != nil {
+		// the client generator asks to requeue, e.g. the rosa kubeconfig is not ready, the attempt is still in
+		// flight, so the slot is kept
+		releaseSlot = result.IsZero()
 		return result,
 			NewManagedClusterImportSucceededCondition(
 				metav1.ConditionFalse,
@@ -204,6 +275,31 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 			), false, err
 	}
 
+	if i.permissionPreflightCheck {
+		missing, err := i.checkImportPermissions(backupRestore, clientHolder, importSecret)
+		if err != nil {
+			// the permissions cannot be reviewed, leave the permission check to the import
+			reqLogger.Info("Failed to review the permissions of the managed cluster client", "error", err)
+		}
+		if len(missing) > 0 {
+			return reconcile.Result{},
+				NewManagedClusterImportSucceededCondition(
+					metav1.ConditionFalse,
+					constants.ConditionReasonManagedClusterImportFailed,
+					failureMessageOfMissingPermissions(managedClusterKubeClientSecret, missing),
+				), false, fmt.Errorf("the managed cluster client is missing permissions: %v", missing)
+		}
+	}
+
+	var rollback *importRollback
+	if !backupRestore && IsImportRollbackEnabled(cluster) {
+		rollback, err = i.recordImportRollback(clientHolder, importSecret)
+		if err != nil {
+			// the import cannot be rolled back, but it is still able to be applied
+			reqLogger.Info("Failed to record the objects for the rollback", "error", err)
+		}
+	}
+
 	modified, err := applyResourcesFunc(backupRestore, clientHolder, restMapper, i.recorder, importSecret)
 	if err != nil {
 		condition := NewManagedClusterImportSucceededCondition(
@@ -226,6 +322,20 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 				"Try to import managed cluster, apply resources error: %v. Will Retry", err)
 		}
 
+		if rollback != nil {
+			rollback.failureMessage = condition.Message
+			done := rollback.Rollback(context.TODO())
+			if !done {
+				// the rollback is continued by the following imports without holding the import slot
+				i.setPendingRollback(clusterName, rollback)
+			}
+			deleted, rErr := rollback.Result()
+			condition.Message = fmt.Sprintf("%s. %s", condition.Message,
+				messageOfImportRollback(deleted, done, rErr))
+			// the objects are deleted, so the next attempt has to recreate them
+			modified = modified || len(deleted) > 0
+		}
+
 		return reconcile.Result{}, condition, modified, err
 	}
 
@@ -237,6 +347,97 @@ func (i *ImportHelper) Import(backupRestore bool, cluster *clusterv1.ManagedClus
 		), modified, nil
 }
 
+// importSourceOf returns the import source of the managed cluster kube client secret
+func (i *ImportHelper) importSourceOf(managedClusterKubeClientSecret *corev1.Secret) string {
+	if managedClusterKubeClientSecret != nil {
+		switch managedClusterKubeClientSecret.Type {
+		case constants.AutoImportSecretRosaConfig, constants.AutoImportSecretOCMConfig:
+			return constants.ImportSourceRosa
+		}
+	}
+	return i.importSource
+}
+
+func (i *ImportHelper) recordImportRollback(clientHolder *ClientHolder,
+	importSecret *corev1.Secret) (*importRollback, error) {
+	if clientHolder.RuntimeClient == nil {
+		return nil, fmt.Errorf("the runtime client of the managed cluster is not available")
+	}
+
+	objs, err := importObjectsFromSecret(importSecret)
+	if err != nil {
+		return nil, err
+	}
+	return newImportRollback(context.TODO(), clientHolder.RuntimeClient, objs)
+}
+
+// IsRollbackPending returns true if the rollback of the failed attempt of the managed cluster is not done yet
+func (i *ImportHelper) IsRollbackPending(clusterName string) bool {
+	return i.getPendingRollback(clusterName) != nil
+}
+
+func (i *ImportHelper) getPendingRollback(clusterName string) *importRollback {
+	i.rollbackLock.Lock()
+	defer i.rollbackLock.Unlock()
+	return i.pendingRollbacks[clusterName]
+}
+
+func (i *ImportHelper) setPendingRollback(clusterName string, rollback *importRollback) {
+	i.rollbackLock.Lock()
+	defer i.rollbackLock.Unlock()
+	if rollback == nil {
+		delete(i.pendingRollbacks, clusterName)
+		return
+	}
+	if i.pendingRollbacks == nil {
+		i.pendingRollbacks = map[string]*importRollback{}
+	}
+	i.pendingRollbacks[clusterName] = rollback
+}
+
+// continueRollback continues the pending rollback of the failed attempt. The failed attempt is already recorded,
+// so a non-zero result is always returned to requeue the managed cluster, and the next attempt is started after
+// the rollback is done.
+func (i *ImportHelper) continueRollback(clusterName string, rollback *importRollback) (
+	reconcile.Result, metav1.Condition, bool, error) {
+	deletedCount := len(rollback.deleted)
+	done := rollback.Rollback(context.TODO())
+	deleted, err := rollback.Result()
+	condition := NewManagedClusterImportSucceededCondition(
+		metav1.ConditionFalse,
+		constants.ConditionReasonManagedClusterImportFailed,
+		fmt.Sprintf("%s. %s", rollback.failureMessage, messageOfImportRollback(deleted, done, err)),
+	)
+	modified := len(deleted) > deletedCount
+	if !done {
+		return reconcile.Result{RequeueAfter: rollbackRequeueInterval}, condition, modified, nil
+	}
+
+	i.setPendingRollback(clusterName, nil)
+	return reconcile.Result{Requeue: true}, condition, modified, nil
+}
+
+func messageOfImportRollback(deleted []string, done bool, err error) string {
+	if err != nil {
+		return fmt.Sprintf("Failed to roll back the import, deleted objects: [%s], error: %v",
+			strings.Join(deleted, ", "), err)
+	}
+	if !done {
+		return fmt.Sprintf("The import is being rolled back, deleted objects: [%s]. "+
+			"Wait for the Klusterlet to be deleted", strings.Join(deleted, ", "))
+	}
+	return fmt.Sprintf("The import is rolled back, deleted objects: [%s]", strings.Join(deleted, ", "))
+}
+
+func (i *ImportHelper) checkImportPermissions(backupRestore bool, clientHolder *ClientHolder,
+	importSecret *corev1.Secret) ([]string, error) {
+	objs, err := importObjects(backupRestore, importSecret)
+	if err != nil {
+		return nil, err
+	}
+	return CheckImportPermissions(context.TODO(), clientHolder.KubeClient, objs)
+}
+
 func failureMessageOfKubeClientGerneration(managedClusterKubeClientSecret *corev1.Secret,
 	err error) string {
 	if managedClusterKubeClientSecret != nil {
@@ -266,6 +467,18 @@ func failureMessageOfInvalidAutoImportSecretPrivileges(managedClusterKubeClientS
 		"Apply resources error, please check kube client permission: %v", err)
 }
 
+func failureMessageOfMissingPermissions(managedClusterKubeClientSecret *corev1.Secret,
+	missing []string) string {
+	if managedClusterKubeClientSecret != nil {
+		return fmt.Sprintf(
+			"AutoImportSecretInvalid %s/%s; please check its permission, missing permissions: [%s]",
+			managedClusterKubeClientSecret.Namespace, managedClusterKubeClientSecret.Name,
+			strings.Join(missing, ", "))
+	}
+	return fmt.Sprintf(
+		"Please check kube client permission, missing permissions: [%s]", strings.Join(missing, ", "))
+}
+
 const (
 	conditionMessageImportingResourcesApplied = "Importing resources are applied, wait for resources be available"
 )
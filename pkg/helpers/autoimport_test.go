@@ -491,3 +491,41 @@ func TestFailureMessageOfInvalidAutoImportSecretPrivileges(t *testing.T) {
 		})
 	}
 }
+
+func TestFailureMessageOfMissingPermissions(t *testing.T) {
+	autoImportSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-import-secret",
+			Namespace: "cluster1",
+		},
+	}
+	missing := []string{"create namespaces", "update secrets ns/name"}
+	cases := []struct {
+		name             string
+		autoImportSecret *corev1.Secret
+		message          string
+	}{
+		{
+			name:    "self-management",
+			message: "Please check kube client permission, missing permissions: [create namespaces, update secrets ns/name]",
+		},
+		{
+			name:             "with auto-import-secret",
+			autoImportSecret: autoImportSecret,
+			message: fmt.Sprintf(
+				"AutoImportSecretInvalid %s; please check its permission, missing permissions: [%s]",
+				"cluster1/auto-import-secret",
+				"create namespaces, update secrets ns/name",
+			),
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := failureMessageOfMissingPermissions(c.autoImportSecret, missing)
+			if c.message != actual {
+				t.Errorf("expect %s, but got %s", c.message, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	sdk "github.com/openshift-online/ocm-sdk-go"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"k8s.io/klog/v2"
+)
+
+const (
+	breakGlassCredentialUser = "acm-import"
+
+	// the kubeconfig of the break-glass credential is only used to apply the importing resources
+	breakGlassCredentialExpiration = time.Hour
+
+	breakGlassCredentialStatusIssued = "issued"
+)
+
+// errBreakGlassCredentialsNotOffered is returned if the cluster does not offer break-glass credentials, e.g. the
+// external authentication is not enabled on the cluster.
+var errBreakGlassCredentialsNotOffered = errors.New("break-glass credentials are not offered")
+
+// breakGlassCredential is the break-glass credential of the clusters_mgmt API, the ocm-sdk-go in use does not
+// have its type, so the credential is requested with the raw requests.
+type breakGlassCredential struct {
+	ID                  string `json:"id,omitempty"`
+	Username            string `json:"username,omitempty"`
+	ExpirationTimestamp string `json:"expiration_timestamp,omitempty"`
+	Status              string `json:"status,omitempty"`
+	Kubeconfig          string `json:"kubeconfig,omitempty"`
+}
+
+// breakGlassKubeConfig creates a break-glass credential for the cluster and returns its kubeconfig once the
+// credential is issued, errBreakGlassCredentialsNotOffered is returned if the cluster does not offer break-glass
+// credentials.
+func (g *RosaKubeConfigGetter) breakGlassKubeConfig(connection *sdk.Connection) (bool, *clientcmdapi.Config, error) {
+	path := fmt.Sprintf("/api/clusters_mgmt/v1/clusters/%s/break_glass_credentials", g.clusterID)
+
+	if len(g.breakGlassCredentialID) == 0 {
+		credential, err := createBreakGlassCredential(connection, path)
+		if err != nil {
+			return false, nil, err
+		}
+		g.breakGlassCredentialID = credential.ID
+	}
+
+	credential, err := getBreakGlassCredential(connection, fmt.Sprintf("%s/%s", path, g.breakGlassCredentialID))
+	if err != nil {
+		return false, nil, err
+	}
+
+	switch credential.Status {
+	case breakGlassCredentialStatusIssued:
+		if len(credential.Kubeconfig) != 0 {
+			config, err := clientcmd.Load([]byte(credential.Kubeconfig))
+			return false, config, err
+		}
+	case "failed", "expired", "revoked", "awaiting_revocation":
+		// request a new credential in the next attempt
+		g.breakGlassCredentialID = ""
+		return false, nil, fmt.Errorf("the break-glass credential %s for %s cluster %s is %s",
+			credential.ID, g.productName(), g.clusterID, credential.Status)
+	}
+
+	if g.currentRetryTimes >= g.totalRetryTimes {
+		klog.Warningf("stop to retry getting break-glass credential for %s cluster %s, reach the retry times limit (%d)",
+			g.productName(), g.clusterID, g.totalRetryTimes)
+		return false, nil, fmt.Errorf("failed to get break-glass credential for %s cluster %s after %d seconds",
+			g.productName(), g.clusterID, (rosaImportRetryPeriod*time.Duration(g.totalRetryTimes))/time.Second)
+	}
+
+	g.currentRetryTimes++
+	return true, nil, fmt.Errorf("break-glass credential for %s cluster %s is not ready, retry after %d seconds",
+		g.productName(), g.clusterID, rosaImportRetryPeriod/time.Second)
+}
+
+func createBreakGlassCredential(connection *sdk.Connection, path string) (*breakGlassCredential, error) {
+	body, err := json.Marshal(&breakGlassCredential{
+		Username:            breakGlassCredentialUser,
+		ExpirationTimestamp: time.Now().Add(breakGlassCredentialExpiration).UTC().Format(time.RFC3339),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := connection.Post().Path(path).Bytes(body).Send()
+	if err != nil {
+		return nil, err
+	}
+
+	switch resp.Status() {
+	case http.StatusOK, http.StatusCreated:
+		return parseBreakGlassCredential(resp.Bytes())
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		// the cluster does not support the break-glass credentials
+		return nil, errBreakGlassCredentialsNotOffered
+	default:
+		return nil, fmt.Errorf("failed to create break-glass credential, status is %d, %s", resp.Status(), resp.String())
+	}
+}
+
+// revokeBreakGlassCredentials revokes the break-glass credentials of the cluster, the credentials that have been
+// revoked or do not exist are ignored.
+func revokeBreakGlassCredentials(connection *sdk.Connection, clusterID string) error {
+	resp, err := connection.Delete().
+		Path(fmt.Sprintf("/api/clusters_mgmt/v1/clusters/%s/break_glass_credentials", clusterID)).
+		Send()
+	if err != nil {
+		return err
+	}
+
+	switch resp.Status() {
+	case http.StatusOK, http.StatusAccepted, http.StatusNoContent, http.StatusNotFound:
+		return nil
+	default:
+		return fmt.Errorf("failed to revoke break-glass credentials, status is %d, %s", resp.Status(), resp.String())
+	}
+}
+
+func getBreakGlassCredential(connection *sdk.Connection, path string) (*breakGlassCredential, error) {
+	resp, err := connection.Get().Path(path).Send()
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Status() != http.StatusOK {
+		return nil, fmt.Errorf("failed to get break-glass credential, status is %d, %s", resp.Status(), resp.String())
+	}
+	return parseBreakGlassCredential(resp.Bytes())
+}
+
+func parseBreakGlassCredential(data []byte) (*breakGlassCredential, error) {
+	credential := &breakGlassCredential{}
+	if err := json.Unmarshal(data, credential); err != nil {
+		return nil, fmt.Errorf("failed to parse break-glass credential, %v", err)
+	}
+	if len(credential.ID) == 0 {
+		return nil, fmt.Errorf("the id of break-glass credential is missing")
+	}
+	return credential, nil
+}
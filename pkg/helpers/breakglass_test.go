@@ -0,0 +1,166 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	clustersmgmttesting "github.com/openshift-online/ocm-sdk-go/testing"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestBreakGlassKubeConfig(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	accessToken := clustersmgmttesting.MakeTokenString("Bearer", 5*time.Minute)
+	refreshToken := clustersmgmttesting.MakeTokenString("Refresh", 10*time.Hour)
+
+	oidServer := clustersmgmttesting.MakeTCPServer()
+	oidServer.RouteToHandler(http.MethodPost, "/",
+		clustersmgmttesting.RespondWithAccessAndRefreshTokens(accessToken, refreshToken))
+	apiServer := clustersmgmttesting.MakeTCPServer()
+	defer func() {
+		oidServer.Close()
+		apiServer.Close()
+	}()
+
+	kubeconfig := `
+apiVersion: v1
+kind: Config
+clusters:
+- cluster:
+    server: https://api.test.com:443
+  name: cluster
+contexts:
+- context:
+    cluster: cluster
+    user: break-glass
+  name: default
+current-context: default
+users:
+- name: break-glass
+  user:
+    client-certificate-data: dGVzdA==
+    client-key-data: dGVzdA==
+`
+
+	cases := []struct {
+		name                 string
+		credentialID         string
+		handlers             []http.HandlerFunc
+		expectedRequeue      bool
+		expectedConfig       bool
+		expectedErrMsg       string
+		expectedCredentialID string
+	}{
+		{
+			name: "not offered",
+			handlers: []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials"),
+					clustersmgmttesting.RespondWithJSON(http.StatusNotFound, "{}"),
+				),
+			},
+			expectedErrMsg: errBreakGlassCredentialsNotOffered.Error(),
+		},
+		{
+			name: "bad request",
+			handlers: []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials"),
+					clustersmgmttesting.RespondWithJSON(http.StatusBadRequest, "{}"),
+				),
+			},
+			expectedErrMsg: "failed to create break-glass credential, status is 400, {}",
+		},
+		{
+			name: "credential is not issued",
+			handlers: []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodPost, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials"),
+					clustersmgmttesting.RespondWithJSON(http.StatusCreated, `{"id":"b1","status":"created"}`),
+				),
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials/b1"),
+					clustersmgmttesting.RespondWithJSON(http.StatusOK, `{"id":"b1","status":"created"}`),
+				),
+			},
+			expectedRequeue:      true,
+			expectedErrMsg:       "break-glass credential for osd cluster c1 is not ready, retry after 30 seconds",
+			expectedCredentialID: "b1",
+		},
+		{
+			name:         "credential is issued",
+			credentialID: "b1",
+			handlers: []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials/b1"),
+					ghttp.RespondWithJSONEncoded(http.StatusOK, &breakGlassCredential{
+						ID:         "b1",
+						Status:     "issued",
+						Kubeconfig: kubeconfig,
+					}),
+				),
+			},
+			expectedConfig:       true,
+			expectedCredentialID: "b1",
+		},
+		{
+			name:         "credential is failed",
+			credentialID: "b1",
+			handlers: []http.HandlerFunc{
+				ghttp.CombineHandlers(
+					ghttp.VerifyRequest(http.MethodGet, "/api/clusters_mgmt/v1/clusters/c1/break_glass_credentials/b1"),
+					clustersmgmttesting.RespondWithJSON(http.StatusOK, `{"id":"b1","status":"failed"}`),
+				),
+			},
+			expectedErrMsg: "the break-glass credential b1 for osd cluster c1 is failed",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			getter := NewRosaKubeConfigGetter()
+			getter.SetAPIServerURL(apiServer.URL())
+			getter.SetTokenURL(oidServer.URL())
+			getter.SetAuthMethod(constants.AutoImportSecretRosaConfigAuthMethodServiceAccount)
+			getter.SetClientID("id")
+			getter.SetClientSecret("secret")
+			getter.SetClusterID("c1")
+			getter.SetProduct(constants.AutoImportSecretOCMProductOSD)
+			getter.SetBreakGlassEnabled(true)
+			getter.breakGlassCredentialID = c.credentialID
+
+			apiServer.AppendHandlers(c.handlers...)
+
+			connection, err := getter.newConnection()
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			defer connection.Close()
+
+			requeue, config, err := getter.breakGlassKubeConfig(connection)
+			switch {
+			case len(c.expectedErrMsg) == 0 && err != nil:
+				t.Errorf("unexpected error %v", err)
+			case len(c.expectedErrMsg) != 0 && (err == nil || err.Error() != c.expectedErrMsg):
+				t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+			}
+			if requeue != c.expectedRequeue {
+				t.Errorf("expected requeue %v, but got %v", c.expectedRequeue, requeue)
+			}
+			if (config != nil) != c.expectedConfig {
+				t.Errorf("expected config %v, but got %v", c.expectedConfig, config)
+			}
+			if getter.breakGlassCredentialID != c.expectedCredentialID {
+				t.Errorf("expected credential %q, but got %q", c.expectedCredentialID, getter.breakGlassCredentialID)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	certutil "k8s.io/client-go/util/cert"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const anonymousUser = "system:anonymous"
+
+// GenerateImportClientFromClientCertSecret generate a client from a given secret that contains kube apiserver,
+// client certificate/key and the CA of the kube apiserver. The client certificate is verified before it is used,
+// and the kube apiserver is verified only with the given CA.
+func GenerateImportClientFromClientCertSecret(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	required := []string{
+		constants.AutoImportSecretClientCertServerKey,
+		constants.AutoImportSecretClientCertCertKey,
+		constants.AutoImportSecretClientCertKeyKey,
+		constants.AutoImportSecretClientCertCAKey,
+	}
+	for _, key := range required {
+		if len(secret.Data[key]) == 0 {
+			return reconcile.Result{}, nil, nil, fmt.Errorf("%s is missing", key)
+		}
+	}
+
+	server := string(secret.Data[constants.AutoImportSecretClientCertServerKey])
+	certData := secret.Data[constants.AutoImportSecretClientCertCertKey]
+	keyData := secret.Data[constants.AutoImportSecretClientCertKeyKey]
+	caData := secret.Data[constants.AutoImportSecretClientCertCAKey]
+
+	if err := validateClientCertificate(certData, keyData, time.Now()); err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	if _, err := certutil.ParseCertsPEM(caData); err != nil {
+		return reconcile.Result{}, nil, nil, fmt.Errorf("the %s is invalid: %v",
+			constants.AutoImportSecretClientCertCAKey, err)
+	}
+
+	result, clientHolder, mapper, err := buildImportClient(
+		buildKubeConfigFileWithClientCertificate(server, certData, keyData, caData))
+	if err != nil {
+		return result, nil, nil, err
+	}
+
+	if err := checkClientCertificateTrusted(context.TODO(), clientHolder.KubeClient, server); err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	return result, clientHolder, mapper, nil
+}
+
+// validateClientCertificate ensures the certificate matches the key and is in its validity period
+func validateClientCertificate(certData, keyData []byte, now time.Time) error {
+	if _, err := tls.X509KeyPair(certData, keyData); err != nil {
+		return fmt.Errorf("the %s and %s are not a valid key pair: %v",
+			constants.AutoImportSecretClientCertCertKey, constants.AutoImportSecretClientCertKeyKey, err)
+	}
+
+	certs, err := certutil.ParseCertsPEM(certData)
+	if err != nil {
+		return fmt.Errorf("the %s is invalid: %v", constants.AutoImportSecretClientCertCertKey, err)
+	}
+
+	// the first certificate is the client certificate, the others are the intermediate certificates
+	leaf := certs[0]
+	if now.After(leaf.NotAfter) {
+		return fmt.Errorf("the client certificate %q expired at %s",
+			leaf.Subject.CommonName, leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	if now.Before(leaf.NotBefore) {
+		return fmt.Errorf("the client certificate %q is not valid until %s",
+			leaf.Subject.CommonName, leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// checkClientCertificateTrusted asks the kube apiserver who the client is, the kube apiserver treats the client
+// as anonymous or rejects it if the client certificate is not signed by its client CA.
+func checkClientCertificateTrusted(ctx context.Context, kubeClient kubernetes.Interface, server string) error {
+	review, err := kubeClient.AuthenticationV1().SelfSubjectReviews().Create(
+		ctx, &authenticationv1.SelfSubjectReview{}, metav1.CreateOptions{})
+
+	var verifyErr *tls.CertificateVerificationError
+	switch {
+	case err == nil:
+		if review.Status.UserInfo.Username == anonymousUser {
+			return fmt.Errorf("the client certificate is not trusted by the kube apiserver %s", server)
+		}
+		return nil
+	case errors.As(err, &verifyErr):
+		return fmt.Errorf("the kube apiserver %s is not trusted by the %s: %v",
+			server, constants.AutoImportSecretClientCertCAKey, verifyErr)
+	case apierrors.IsUnauthorized(err),
+		apierrors.IsForbidden(err) && strings.Contains(err.Error(), anonymousUser):
+		return fmt.Errorf("the client certificate is not trusted by the kube apiserver %s: %v", server, err)
+	case apierrors.IsNotFound(err), apierrors.IsForbidden(err):
+		// the SelfSubjectReview api is unavailable on the kube apiserver (before 1.28) or the client is
+		// authenticated but not allowed to review itself, leave the permission check to the import.
+		return nil
+	default:
+		return err
+	}
+}
+
+func buildKubeConfigFileWithClientCertificate(apiURL string, certData, keyData, caData []byte) *clientcmdapi.Config {
+	return &clientcmdapi.Config{
+		Clusters: map[string]*clientcmdapi.Cluster{kubeconfigDefaultCluster: {
+			Server:                   apiURL,
+			CertificateAuthorityData: caData,
+		}},
+		AuthInfos: map[string]*clientcmdapi.AuthInfo{kubeconfigDefaultAuth: {
+			ClientCertificateData: certData,
+			ClientKeyData:         keyData,
+		}},
+		Contexts: map[string]*clientcmdapi.Context{kubeconfigDefaultContext: {
+			Cluster:  kubeconfigDefaultCluster,
+			AuthInfo: kubeconfigDefaultAuth,
+		}},
+		CurrentContext: kubeconfigDefaultContext,
+	}
+}
@@ -0,0 +1,326 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestValidateClientCertificate(t *testing.T) {
+	caCert, caKey, err := testinghelpers.NewRootCA("test-ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	cert, key, err := testinghelpers.NewServerCertificate("import", caCert, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	_, otherKey, err := testinghelpers.NewServerCertificate("other", caCert, caKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	cases := []struct {
+		name           string
+		cert           []byte
+		key            []byte
+		now            time.Time
+		expectedErrMsg string
+	}{
+		{
+			name: "valid",
+			cert: cert,
+			key:  key,
+			now:  time.Now(),
+		},
+		{
+			name:           "mismatched key",
+			cert:           cert,
+			key:            otherKey,
+			now:            time.Now(),
+			expectedErrMsg: "the tls.crt and tls.key are not a valid key pair",
+		},
+		{
+			name:           "expired",
+			cert:           cert,
+			key:            key,
+			now:            time.Now().AddDate(1, 0, 0),
+			expectedErrMsg: "the client certificate \"import\" expired at",
+		},
+		{
+			name:           "not yet valid",
+			cert:           cert,
+			key:            key,
+			now:            time.Now().AddDate(0, 0, -1),
+			expectedErrMsg: "the client certificate \"import\" is not valid until",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := validateClientCertificate(c.cert, c.key, c.now)
+			if len(c.expectedErrMsg) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), c.expectedErrMsg) {
+				t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestCheckClientCertificateTrusted(t *testing.T) {
+	resource := schema.GroupResource{Group: "authentication.k8s.io", Resource: "selfsubjectreviews"}
+
+	cases := []struct {
+		name           string
+		username       string
+		err            error
+		expectedErrMsg string
+	}{
+		{
+			name:     "trusted",
+			username: "system:admin",
+		},
+		{
+			name:           "anonymous",
+			username:       "system:anonymous",
+			expectedErrMsg: "the client certificate is not trusted by the kube apiserver https://api.test:6443",
+		},
+		{
+			name:           "unauthorized",
+			err:            apierrors.NewUnauthorized("Unauthorized"),
+			expectedErrMsg: "the client certificate is not trusted by the kube apiserver https://api.test:6443",
+		},
+		{
+			name: "anonymous forbidden",
+			err: apierrors.NewForbidden(resource, "",
+				fmt.Errorf("User \"system:anonymous\" cannot create resource \"selfsubjectreviews\"")),
+			expectedErrMsg: "the client certificate is not trusted by the kube apiserver https://api.test:6443",
+		},
+		{
+			name: "authenticated forbidden",
+			err: apierrors.NewForbidden(resource, "",
+				fmt.Errorf("User \"import\" cannot create resource \"selfsubjectreviews\"")),
+		},
+		{
+			name: "api not found",
+			err:  apierrors.NewNotFound(resource, ""),
+		},
+		{
+			name: "unknown authority",
+			err: &url.Error{Op: "Post", URL: "https://api.test:6443", Err: &tls.CertificateVerificationError{
+				Err: x509.UnknownAuthorityError{},
+			}},
+			expectedErrMsg: "the kube apiserver https://api.test:6443 is not trusted by the ca.crt",
+		},
+		{
+			name:           "other error",
+			err:            fmt.Errorf("connection refused"),
+			expectedErrMsg: "connection refused",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeClient.PrependReactor("create", "selfsubjectreviews",
+				func(action clienttesting.Action) (handled bool, ret runtime.Object, err error) {
+					if c.err != nil {
+						return true, nil, c.err
+					}
+					return true, &authenticationv1.SelfSubjectReview{
+						Status: authenticationv1.SelfSubjectReviewStatus{
+							UserInfo: authenticationv1.UserInfo{Username: c.username},
+						},
+					}, nil
+				})
+
+			err := checkClientCertificateTrusted(context.TODO(), kubeClient, "https://api.test:6443")
+			if len(c.expectedErrMsg) == 0 {
+				if err != nil {
+					t.Errorf("unexpected error %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.HasPrefix(err.Error(), c.expectedErrMsg) {
+				t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
+func TestGenerateImportClientFromClientCertSecret(t *testing.T) {
+	serverCA, serverCAKey, err := testinghelpers.NewRootCA("server-ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	serverCert, serverKey, err := testinghelpers.NewServerCertificate("server", serverCA, serverCAKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	otherCA, _, err := testinghelpers.NewRootCA("other-ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	clientCA, clientCAKey, err := testinghelpers.NewRootCA("client-ca")
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	clientCert, clientKey, err := testinghelpers.NewServerCertificate("import", clientCA, clientCAKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	clientCAPool := x509.NewCertPool()
+	clientCAPool.AppendCertsFromPEM(clientCA)
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/apis/authentication.k8s.io/v1/selfsubjectreviews" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		// verify the client certificate like the kube apiserver, an untrusted certificate is unauthorized
+		username := "system:anonymous"
+		if req.TLS != nil && len(req.TLS.PeerCertificates) > 0 {
+			if _, err := req.TLS.PeerCertificates[0].Verify(x509.VerifyOptions{
+				Roots:     clientCAPool,
+				KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+			}); err != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusUnauthorized)
+				w.Write([]byte(`{"kind":"Status","apiVersion":"v1","status":"Failure","reason":"Unauthorized","code":401}`))
+				return
+			}
+			username = req.TLS.PeerCertificates[0].Subject.CommonName
+		}
+		output, err := json.Marshal(&authenticationv1.SelfSubjectReview{
+			TypeMeta: metav1.TypeMeta{APIVersion: "authentication.k8s.io/v1", Kind: "SelfSubjectReview"},
+			Status: authenticationv1.SelfSubjectReviewStatus{
+				UserInfo: authenticationv1.UserInfo{Username: username},
+			},
+		})
+		if err != nil {
+			t.Fatalf("unexpected encoding error: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(output)
+	}))
+	serverKeyPair, err := tls.X509KeyPair(serverCert, serverKey)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{serverKeyPair},
+		ClientAuth:   tls.RequestClientCert,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	cases := []struct {
+		name           string
+		data           map[string][]byte
+		expectedErrMsg string
+	}{
+		{
+			name: "missing ca",
+			data: map[string][]byte{
+				"server":  []byte(server.URL),
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+			},
+			expectedErrMsg: "ca.crt is missing",
+		},
+		{
+			name: "invalid ca",
+			data: map[string][]byte{
+				"server":  []byte(server.URL),
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  []byte("invalid"),
+			},
+			expectedErrMsg: "the ca.crt is invalid",
+		},
+		{
+			name: "server is not trusted",
+			data: map[string][]byte{
+				"server":  []byte(server.URL),
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  otherCA,
+			},
+			expectedErrMsg: fmt.Sprintf("the kube apiserver %s is not trusted by the ca.crt", server.URL),
+		},
+		{
+			name: "client certificate is not trusted",
+			data: map[string][]byte{
+				"server":  []byte(server.URL),
+				"tls.crt": serverCert,
+				"tls.key": serverKey,
+				"ca.crt":  serverCA,
+			},
+			expectedErrMsg: fmt.Sprintf("the client certificate is not trusted by the kube apiserver %s", server.URL),
+		},
+		{
+			name: "generate client",
+			data: map[string][]byte{
+				"server":  []byte(server.URL),
+				"tls.crt": clientCert,
+				"tls.key": clientKey,
+				"ca.crt":  serverCA,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, clientHolder, _, err := GenerateImportClientFromClientCertSecret(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "auto-import-secret",
+				},
+				Type: "auto-import/clientcert",
+				Data: c.data,
+			})
+			if len(c.expectedErrMsg) != 0 {
+				if err == nil || !strings.HasPrefix(err.Error(), c.expectedErrMsg) {
+					t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if clientHolder == nil {
+				t.Errorf("expected client holder, but got nil")
+			}
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// maxReportedDrifts is the max number of the drifts reported in the condition message
+	maxReportedDrifts = 20
+	// maxReportedValueLength is the max length of the values reported in a drift
+	maxReportedValueLength = 64
+)
+
+// DetectKlusterletDrift fetches the live objects of the import.yaml in the import secret from the managed cluster
+// and compares them with the rendered objects. Only the fields that are rendered are compared, so the fields
+// that are defaulted or maintained by the managed cluster are not reported. It returns the drifts in the format
+// of `<kind> [<namespace>/]<name>: <field path>: <detail>`, the values of the secrets are not reported.
+func DetectKlusterletDrift(ctx context.Context, runtimeClient client.Client,
+	importSecret *corev1.Secret) ([]string, error) {
+	if err := ValidateImportSecret(importSecret); err != nil {
+		return nil, err
+	}
+
+	drifts := []string{}
+	for _, raw := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+
+		jsonData, err := yaml.YAMLToJSON(raw)
+		if err != nil {
+			return nil, err
+		}
+		desired := &unstructured.Unstructured{}
+		if err := desired.UnmarshalJSON(jsonData); err != nil {
+			return nil, err
+		}
+
+		live := &unstructured.Unstructured{}
+		live.SetGroupVersionKind(desired.GroupVersionKind())
+		err = runtimeClient.Get(ctx, types.NamespacedName{
+			Namespace: desired.GetNamespace(),
+			Name:      desired.GetName(),
+		}, live)
+
+		objectRef := formatObjectRef(desired)
+		if errors.IsNotFound(err) {
+			drifts = append(drifts, fmt.Sprintf("%s: is missing", objectRef))
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for _, drift := range compareDesiredFields("", driftComparedFields(desired),
+			driftComparedFields(live), desired.GetKind() == "Secret") {
+			drifts = append(drifts, fmt.Sprintf("%s: %s", objectRef, drift))
+		}
+	}
+
+	return drifts, nil
+}
+
+// NewKlusterletDriftedCondition returns the KlusterletDrifted condition with the result of the drift detection
+func NewKlusterletDriftedCondition(drifts []string, err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    constants.ConditionKlusterletDrifted,
+			Status:  metav1.ConditionUnknown,
+			Reason:  constants.ConditionReasonKlusterletDriftUnknown,
+			Message: fmt.Sprintf("Failed to detect the klusterlet drift: %v", err),
+		}
+	}
+
+	if len(drifts) == 0 {
+		return metav1.Condition{
+			Type:    constants.ConditionKlusterletDrifted,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonKlusterletInSync,
+			Message: "The klusterlet is in sync with the import.yaml",
+		}
+	}
+
+	reported := drifts
+	if len(reported) > maxReportedDrifts {
+		reported = append(reported[:maxReportedDrifts:maxReportedDrifts],
+			fmt.Sprintf("and %d more", len(drifts)-maxReportedDrifts))
+	}
+	return metav1.Condition{
+		Type:    constants.ConditionKlusterletDrifted,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonKlusterletDriftDetected,
+		Message: fmt.Sprintf("The klusterlet drifts from the import.yaml: %s", strings.Join(reported, "; ")),
+	}
+}
+
+// UpdateManagedClusterDriftCondition updates the KlusterletDrifted condition of the managed cluster, and records
+// an event once the drift is detected or the detected drift is changed.
+func UpdateManagedClusterDriftCondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
+	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	if cond.Type != constants.ConditionKlusterletDrifted {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	changed, err := updateManagedClusterStatus(client, managedCluster.Name, cond)
+	if err != nil {
+		return err
+	}
+	if !changed || cond.Status != metav1.ConditionTrue {
+		return nil
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+		constants.EventReasonKlusterletDrifted, constants.EventReasonKlusterletDrifted,
+		"%s", cond.Message)
+	return nil
+}
+
+// driftComparedFields returns the fields of the object that are compared, the status and the metadata except
+// the labels and annotations are ignored.
+func driftComparedFields(obj *unstructured.Unstructured) map[string]interface{} {
+	fields := map[string]interface{}{}
+	for k, v := range obj.Object {
+		switch k {
+		case "apiVersion", "kind", "status":
+			continue
+		case "metadata":
+			metadata := map[string]interface{}{}
+			if labels, ok := obj.Object["metadata"].(map[string]interface{})["labels"]; ok {
+				metadata["labels"] = labels
+			}
+			if annotations, ok := obj.Object["metadata"].(map[string]interface{})["annotations"]; ok {
+				metadata["annotations"] = annotations
+			}
+			fields[k] = metadata
+		default:
+			fields[k] = v
+		}
+	}
+	return fields
+}
+
+// compareDesiredFields compares the desired fields with the live fields, the fields that are not desired are
+// ignored. The values are not reported if they are sensitive.
+func compareDesiredFields(path string, desired, live interface{}, sensitive bool) []string {
+	if desired == nil {
+		return nil
+	}
+
+	switch desiredValue := desired.(type) {
+	case map[string]interface{}:
+		liveValue, ok := live.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %s", fieldPath(path), describeMismatch(desired, live, sensitive))}
+		}
+
+		keys := make([]string, 0, len(desiredValue))
+		for k := range desiredValue {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		drifts := []string{}
+		for _, k := range keys {
+			childPath := k
+			if len(path) != 0 {
+				childPath = fmt.Sprintf("%s.%s", path, k)
+			}
+			if _, ok := liveValue[k]; !ok {
+				// the zero values are omitted by the api server, e.g. globalDefault: false of a PriorityClass
+				if !isZeroValue(desiredValue[k]) {
+					drifts = append(drifts, fmt.Sprintf("%s: is missing", childPath))
+				}
+				continue
+			}
+			drifts = append(drifts, compareDesiredFields(childPath, desiredValue[k], liveValue[k], sensitive)...)
+		}
+		return drifts
+	case []interface{}:
+		liveValue, ok := live.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: %s", fieldPath(path), describeMismatch(desired, live, sensitive))}
+		}
+		if len(desiredValue) != len(liveValue) {
+			return []string{fmt.Sprintf("%s: expected %d items, got %d", fieldPath(path),
+				len(desiredValue), len(liveValue))}
+		}
+
+		drifts := []string{}
+		for i := range desiredValue {
+			drifts = append(drifts, compareDesiredFields(fmt.Sprintf("%s[%d]", path, i),
+				desiredValue[i], liveValue[i], sensitive)...)
+		}
+		return drifts
+	default:
+		if fmt.Sprintf("%v", desired) == fmt.Sprintf("%v", live) {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: %s", fieldPath(path), describeMismatch(desired, live, sensitive))}
+	}
+}
+
+// isZeroValue returns true if the value is nil, false, 0, an empty string, or an empty map or list
+func isZeroValue(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case bool:
+		return !v
+	case string:
+		return len(v) == 0
+	case int64:
+		return v == 0
+	case float64:
+		return v == 0
+	case map[string]interface{}:
+		return len(v) == 0
+	case []interface{}:
+		return len(v) == 0
+	}
+	return false
+}
+
+func describeMismatch(desired, live interface{}, sensitive bool) string {
+	if sensitive {
+		return "differs"
+	}
+	return fmt.Sprintf("expected %s, got %s", truncateValue(desired), truncateValue(live))
+}
+
+func truncateValue(value interface{}) string {
+	s := fmt.Sprintf("%q", fmt.Sprintf("%v", value))
+	if len(s) > maxReportedValueLength {
+		return s[:maxReportedValueLength] + "..."
+	}
+	return s
+}
+
+func fieldPath(path string) string {
+	if len(path) == 0 {
+		return "<root>"
+	}
+	return path
+}
+
+func formatObjectRef(obj *unstructured.Unstructured) string {
+	if len(obj.GetNamespace()) == 0 {
+		return fmt.Sprintf("%s %s", obj.GetKind(), obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+}
@@ -0,0 +1,281 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestDetectKlusterletDrift(t *testing.T) {
+	importYaml := `
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: bootstrap-hub-kubeconfig
+  namespace: open-cluster-management-agent
+data:
+  kubeconfig: dGVzdA==
+---
+apiVersion: apps/v1
+kind: Deployment
+metadata:
+  name: klusterlet
+  namespace: open-cluster-management
+  labels:
+    app: klusterlet
+spec:
+  replicas: 1
+  selector:
+    matchLabels:
+      app: klusterlet
+  template:
+    metadata:
+      creationTimestamp: null
+      labels:
+        app: klusterlet
+    spec:
+      containers:
+      - name: klusterlet
+        image: registration-operator:latest
+---
+apiVersion: v1
+kind: ServiceAccount
+metadata:
+  name: klusterlet
+  namespace: open-cluster-management
+`
+
+	deployment := func(image string, replicas int32) *appsv1.Deployment {
+		return &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "klusterlet",
+				Namespace: "open-cluster-management",
+				Labels: map[string]string{
+					"app":   "klusterlet",
+					"extra": "label",
+				},
+			},
+			Spec: appsv1.DeploymentSpec{
+				Replicas: ptr.To[int32](replicas),
+				Selector: &metav1.LabelSelector{
+					MatchLabels: map[string]string{"app": "klusterlet"},
+				},
+				Template: corev1.PodTemplateSpec{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{"app": "klusterlet"},
+					},
+					Spec: corev1.PodSpec{
+						Containers: []corev1.Container{
+							{
+								Name:            "klusterlet",
+								Image:           image,
+								ImagePullPolicy: corev1.PullIfNotPresent,
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	bootstrapSecret := func(kubeconfig string) *corev1.Secret {
+		return &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "bootstrap-hub-kubeconfig",
+				Namespace: "open-cluster-management-agent",
+			},
+			Data: map[string][]byte{
+				"kubeconfig": []byte(kubeconfig),
+			},
+		}
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "klusterlet",
+			Namespace: "open-cluster-management",
+		},
+	}
+
+	cases := []struct {
+		name           string
+		objs           []runtime.Object
+		expectedDrifts []string
+	}{
+		{
+			name: "in sync",
+			objs: []runtime.Object{
+				bootstrapSecret("test"),
+				deployment("registration-operator:latest", 1),
+				serviceAccount,
+			},
+			expectedDrifts: []string{},
+		},
+		{
+			name: "drifted",
+			objs: []runtime.Object{bootstrapSecret("changed"), deployment("registration-operator:dev", 2)},
+			expectedDrifts: []string{
+				"Secret open-cluster-management-agent/bootstrap-hub-kubeconfig: data.kubeconfig: differs",
+				"Deployment open-cluster-management/klusterlet: spec.replicas: expected \"1\", got \"2\"",
+				"Deployment open-cluster-management/klusterlet: spec.template.spec.containers[0].image: " +
+					"expected \"registration-operator:latest\", got \"registration-operator:dev\"",
+				"ServiceAccount open-cluster-management/klusterlet: is missing",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runtimeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(c.objs...).Build()
+			importSecret := &corev1.Secret{
+				Data: map[string][]byte{
+					constants.ImportSecretImportYamlKey: []byte(importYaml),
+				},
+			}
+
+			drifts, err := DetectKlusterletDrift(context.TODO(), runtimeClient, importSecret)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(drifts, c.expectedDrifts) {
+				t.Errorf("expected drifts %v, but got %v", c.expectedDrifts, drifts)
+			}
+		})
+	}
+}
+
+func TestDetectKlusterletDriftWithOmittedZeroValues(t *testing.T) {
+	// the rendered priority class of the klusterlet chart
+	importYaml := `
+apiVersion: scheduling.k8s.io/v1
+kind: PriorityClass
+metadata:
+  name: klusterlet-critical
+value: 1000000
+globalDefault: false
+description: "This priority class should be used for klusterlet agents only."
+preemptionPolicy: PreemptLowerPriority
+`
+
+	preemptionPolicy := corev1.PreemptLowerPriority
+	priorityClass := func(value int32, globalDefault bool) *schedulingv1.PriorityClass {
+		return &schedulingv1.PriorityClass{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "klusterlet-critical",
+			},
+			Value:            value,
+			GlobalDefault:    globalDefault,
+			Description:      "This priority class should be used for klusterlet agents only.",
+			PreemptionPolicy: &preemptionPolicy,
+		}
+	}
+
+	cases := []struct {
+		name           string
+		priorityClass  *schedulingv1.PriorityClass
+		expectedDrifts []string
+	}{
+		{
+			name:           "globalDefault is omitted",
+			priorityClass:  priorityClass(1000000, false),
+			expectedDrifts: []string{},
+		},
+		{
+			name:          "drifted",
+			priorityClass: priorityClass(1000, true),
+			expectedDrifts: []string{
+				"PriorityClass klusterlet-critical: globalDefault: expected \"false\", got \"true\"",
+				"PriorityClass klusterlet-critical: value: expected \"1000000\", got \"1000\"",
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runtimeClient := fake.NewClientBuilder().WithScheme(scheme.Scheme).
+				WithRuntimeObjects(c.priorityClass).Build()
+			importSecret := &corev1.Secret{
+				Data: map[string][]byte{
+					constants.ImportSecretImportYamlKey: []byte(importYaml),
+				},
+			}
+
+			drifts, err := DetectKlusterletDrift(context.TODO(), runtimeClient, importSecret)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(drifts, c.expectedDrifts) {
+				t.Errorf("expected drifts %v, but got %v", c.expectedDrifts, drifts)
+			}
+		})
+	}
+}
+
+func TestNewKlusterletDriftedCondition(t *testing.T) {
+	manyDrifts := []string{}
+	for i := 0; i < 22; i++ {
+		manyDrifts = append(manyDrifts, fmt.Sprintf("d%d", i))
+	}
+
+	cases := []struct {
+		name            string
+		drifts          []string
+		err             error
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedMessage string
+	}{
+		{
+			name:            "detection failed",
+			err:             fmt.Errorf("unreachable"),
+			expectedStatus:  metav1.ConditionUnknown,
+			expectedReason:  constants.ConditionReasonKlusterletDriftUnknown,
+			expectedMessage: "Failed to detect the klusterlet drift: unreachable",
+		},
+		{
+			name:            "in sync",
+			drifts:          []string{},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  constants.ConditionReasonKlusterletInSync,
+			expectedMessage: "The klusterlet is in sync with the import.yaml",
+		},
+		{
+			name:           "too many drifts",
+			drifts:         manyDrifts,
+			expectedStatus: metav1.ConditionTrue,
+			expectedReason: constants.ConditionReasonKlusterletDriftDetected,
+			expectedMessage: "The klusterlet drifts from the import.yaml: d0; d1; d2; d3; d4; d5; d6; d7; d8; d9; " +
+				"d10; d11; d12; d13; d14; d15; d16; d17; d18; d19; and 2 more",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			condition := NewKlusterletDriftedCondition(c.drifts, c.err)
+			if condition.Type != constants.ConditionKlusterletDrifted {
+				t.Errorf("unexpected condition type %s", condition.Type)
+			}
+			if condition.Status != c.expectedStatus || condition.Reason != c.expectedReason {
+				t.Errorf("expected %s/%s, but got %s/%s", c.expectedStatus, c.expectedReason,
+					condition.Status, condition.Reason)
+			}
+			if condition.Message != c.expectedMessage {
+				t.Errorf("expected message %q, but got %q", c.expectedMessage, condition.Message)
+			}
+		})
+	}
+}
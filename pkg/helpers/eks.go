@@ -0,0 +1,188 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// The EKS token is an AWS STS GetCallerIdentity request presigned with SigV4, the same token that
+// aws-iam-authenticator and `aws eks get-token` mint, see
+// https://github.com/kubernetes-sigs/aws-iam-authenticator#api-authorization-from-outside-a-cluster
+const (
+	eksTokenPrefix       = "k8s-aws-v1."
+	eksClusterIDHeader   = "x-k8s-aws-id"
+	eksSTSService        = "sts"
+	eksSTSAction         = "GetCallerIdentity"
+	eksSTSVersion        = "2011-06-15"
+	eksPresignExpiration = 60 // seconds
+
+	awsV4SigningAlgorithm = "AWS4-HMAC-SHA256"
+	awsV4Request          = "aws4_request"
+	awsV4DateFormat       = "20060102"
+	awsV4TimeFormat       = "20060102T150405Z"
+
+	// the sha256 of an empty payload
+	emptyPayloadHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// eksCredentials is the aws credentials used to sign the eks token
+type eksCredentials struct {
+	accessKeyID     string
+	secretAccessKey string
+	sessionToken    string
+}
+
+// GenerateImportClientFromEKSSecret generate a client from a given secret that contains aws credentials and eks
+// cluster info, the bearer token is minted locally with the aws credentials.
+func GenerateImportClientFromEKSSecret(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	required := []string{
+		constants.AutoImportSecretEKSAccessKeyIDKey,
+		constants.AutoImportSecretEKSSecretAccessKeyKey,
+		constants.AutoImportSecretEKSRegionKey,
+		constants.AutoImportSecretEKSClusterNameKey,
+		constants.AutoImportSecretEKSServerKey,
+		// the eks cluster is served with the certificate of the cluster CA, which is not in the system trust store
+		constants.AutoImportSecretEKSCAKey,
+	}
+	for _, key := range required {
+		if len(secret.Data[key]) == 0 {
+			return reconcile.Result{}, nil, nil, fmt.Errorf("%s is missing", key)
+		}
+	}
+
+	creds := eksCredentials{
+		accessKeyID:     string(secret.Data[constants.AutoImportSecretEKSAccessKeyIDKey]),
+		secretAccessKey: string(secret.Data[constants.AutoImportSecretEKSSecretAccessKeyKey]),
+		sessionToken:    string(secret.Data[constants.AutoImportSecretEKSSessionTokenKey]),
+	}
+
+	token, err := generateEKSToken(creds,
+		string(secret.Data[constants.AutoImportSecretEKSRegionKey]),
+		string(secret.Data[constants.AutoImportSecretEKSClusterNameKey]),
+		time.Now())
+	if err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	return buildImportClient(buildKubeConfigFileWithTokenAndCA(
+		string(secret.Data[constants.AutoImportSecretEKSServerKey]),
+		token,
+		secret.Data[constants.AutoImportSecretEKSCAKey],
+	))
+}
+
+// generateEKSToken presigns a sts GetCallerIdentity request for the given eks cluster and encodes the
+// presigned url to an eks bearer token.
+func generateEKSToken(creds eksCredentials, region, clusterName string, now time.Time) (string, error) {
+	if len(region) == 0 {
+		return "", fmt.Errorf("region is missing")
+	}
+	if len(clusterName) == 0 {
+		return "", fmt.Errorf("cluster_name is missing")
+	}
+
+	now = now.UTC()
+	host := stsHost(region)
+	credentialScope := strings.Join([]string{now.Format(awsV4DateFormat), region, eksSTSService, awsV4Request}, "/")
+	signedHeaders := "host;" + eksClusterIDHeader
+
+	query := url.Values{}
+	query.Set("Action", eksSTSAction)
+	query.Set("Version", eksSTSVersion)
+	query.Set("X-Amz-Algorithm", awsV4SigningAlgorithm)
+	query.Set("X-Amz-Credential", creds.accessKeyID+"/"+credentialScope)
+	query.Set("X-Amz-Date", now.Format(awsV4TimeFormat))
+	query.Set("X-Amz-Expires", fmt.Sprintf("%d", eksPresignExpiration))
+	query.Set("X-Amz-SignedHeaders", signedHeaders)
+	if len(creds.sessionToken) != 0 {
+		query.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	canonicalQuery := awsV4CanonicalQuery(query)
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		"/",
+		canonicalQuery,
+		fmt.Sprintf("host:%s\n%s:%s\n", host, eksClusterIDHeader, clusterName),
+		signedHeaders,
+		emptyPayloadHash,
+	}, "\n")
+
+	stringToSign := strings.Join([]string{
+		awsV4SigningAlgorithm,
+		now.Format(awsV4TimeFormat),
+		credentialScope,
+		hashSHA256Hex([]byte(canonicalRequest)),
+	}, "\n")
+
+	signingKey := awsV4SigningKey(creds.secretAccessKey, now.Format(awsV4DateFormat), region, eksSTSService)
+	signature := hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+
+	presignedURL := fmt.Sprintf("https://%s/?%s&X-Amz-Signature=%s", host, canonicalQuery, signature)
+	return eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(presignedURL)), nil
+}
+
+// stsHost returns the regional sts endpoint host
+func stsHost(region string) string {
+	if strings.HasPrefix(region, "cn-") {
+		return fmt.Sprintf("sts.%s.amazonaws.com.cn", region)
+	}
+	return fmt.Sprintf("sts.%s.amazonaws.com", region)
+}
+
+// awsV4SigningKey derives the SigV4 signing key, see
+// https://docs.aws.amazon.com/IAM/latest/UserGuide/reference_sigv-create-signed-request.html
+func awsV4SigningKey(secretAccessKey, date, region, service string) []byte {
+	kDate := hmacSHA256([]byte("AWS4"+secretAccessKey), []byte(date))
+	kRegion := hmacSHA256(kDate, []byte(region))
+	kService := hmacSHA256(kRegion, []byte(service))
+	return hmacSHA256(kService, []byte(awsV4Request))
+}
+
+// awsV4CanonicalQuery sorts the query by key and encodes it with the RFC 3986 rules required by SigV4
+func awsV4CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := []string{}
+	for _, key := range keys {
+		for _, value := range query[key] {
+			pairs = append(pairs, awsV4Escape(key)+"="+awsV4Escape(value))
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+func awsV4Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashSHA256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,206 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestAWSV4SigningKey(t *testing.T) {
+	// the example in https://docs.aws.amazon.com/general/latest/gr/signature-v4-examples.html
+	key := awsV4SigningKey("wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY", "20120215", "us-east-1", "iam")
+	expected := "f4780e2d9f65fa895f9c67b32ce1baf0b0d8a43505a000a1a9e090d414db404d"
+	if hex.EncodeToString(key) != expected {
+		t.Errorf("expected signing key %s, but got %s", expected, hex.EncodeToString(key))
+	}
+}
+
+func TestGenerateEKSToken(t *testing.T) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	creds := eksCredentials{
+		accessKeyID:     "AKIDEXAMPLE",
+		secretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+	}
+
+	cases := []struct {
+		name           string
+		creds          eksCredentials
+		region         string
+		clusterName    string
+		expectedHost   string
+		expectedErrMsg string
+	}{
+		{
+			name:           "no region",
+			creds:          creds,
+			clusterName:    "test",
+			expectedErrMsg: "region is missing",
+		},
+		{
+			name:           "no cluster name",
+			creds:          creds,
+			region:         "us-east-1",
+			expectedErrMsg: "cluster_name is missing",
+		},
+		{
+			name:         "token",
+			creds:        creds,
+			region:       "us-east-1",
+			clusterName:  "test",
+			expectedHost: "sts.us-east-1.amazonaws.com",
+		},
+		{
+			name: "token with session token",
+			creds: eksCredentials{
+				accessKeyID:     creds.accessKeyID,
+				secretAccessKey: creds.secretAccessKey,
+				sessionToken:    "session/token+",
+			},
+			region:       "us-west-2",
+			clusterName:  "test",
+			expectedHost: "sts.us-west-2.amazonaws.com",
+		},
+		{
+			name:         "china region",
+			creds:        creds,
+			region:       "cn-north-1",
+			clusterName:  "test",
+			expectedHost: "sts.cn-north-1.amazonaws.com.cn",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			token, err := generateEKSToken(c.creds, c.region, c.clusterName, now)
+			if len(c.expectedErrMsg) != 0 {
+				if err == nil || err.Error() != c.expectedErrMsg {
+					t.Fatalf("expected error %q, but got %v", c.expectedErrMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if !strings.HasPrefix(token, eksTokenPrefix) {
+				t.Fatalf("expected token with prefix %s, but got %s", eksTokenPrefix, token)
+			}
+
+			rawURL, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, eksTokenPrefix))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			presignedURL, err := url.Parse(string(rawURL))
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			if presignedURL.Host != c.expectedHost {
+				t.Errorf("expected host %s, but got %s", c.expectedHost, presignedURL.Host)
+			}
+
+			query := presignedURL.Query()
+			expectedQuery := map[string]string{
+				"Action":               "GetCallerIdentity",
+				"Version":              "2011-06-15",
+				"X-Amz-Algorithm":      "AWS4-HMAC-SHA256",
+				"X-Amz-Credential":     "AKIDEXAMPLE/20240102/" + c.region + "/sts/aws4_request",
+				"X-Amz-Date":           "20240102T030405Z",
+				"X-Amz-Expires":        "60",
+				"X-Amz-SignedHeaders":  "host;x-k8s-aws-id",
+				"X-Amz-Security-Token": c.creds.sessionToken,
+			}
+			for key, value := range expectedQuery {
+				if query.Get(key) != value {
+					t.Errorf("expected query %s=%s, but got %s", key, value, query.Get(key))
+				}
+			}
+
+			if len(query.Get("X-Amz-Signature")) != 64 {
+				t.Errorf("unexpected signature %s", query.Get("X-Amz-Signature"))
+			}
+
+			// the token should be stable for the same input
+			again, err := generateEKSToken(c.creds, c.region, c.clusterName, now)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if again != token {
+				t.Errorf("expected the same token, but got %s and %s", token, again)
+			}
+
+			// the token should be bound to the cluster
+			other, err := generateEKSToken(c.creds, c.region, "other", now)
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if other == token {
+				t.Errorf("expected different tokens for different clusters")
+			}
+		})
+	}
+}
+
+func TestGenerateImportClientFromEKSSecret(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           map[string][]byte
+		expectedErrMsg string
+	}{
+		{
+			name: "missing access key",
+			data: map[string][]byte{
+				"aws_secret_access_key": []byte("secret"),
+				"region":                []byte("us-east-1"),
+				"cluster_name":          []byte("test"),
+				"server":                []byte("https://127.0.0.1:6443"),
+			},
+			expectedErrMsg: "aws_access_key_id is missing",
+		},
+		{
+			name: "missing server",
+			data: map[string][]byte{
+				"aws_access_key_id":     []byte("AKIDEXAMPLE"),
+				"aws_secret_access_key": []byte("secret"),
+				"region":                []byte("us-east-1"),
+				"cluster_name":          []byte("test"),
+			},
+			expectedErrMsg: "server is missing",
+		},
+		{
+			name: "missing ca",
+			data: map[string][]byte{
+				"aws_access_key_id":     []byte("AKIDEXAMPLE"),
+				"aws_secret_access_key": []byte("secret"),
+				"region":                []byte("us-east-1"),
+				"cluster_name":          []byte("test"),
+				"server":                []byte("https://127.0.0.1:6443"),
+			},
+			expectedErrMsg: "ca is missing",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, _, _, err := GenerateImportClientFromEKSSecret(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "auto-import-secret",
+				},
+				Data: c.data,
+			})
+			if err == nil || err.Error() != c.expectedErrMsg {
+				t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"strings"
+
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	schedulingv1 "k8s.io/api/scheduling/v1"
+	crdv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	ManifestReferenceKindConfigMap = "configmap"
+	ManifestReferenceKindSecret    = "secret"
+)
+
+// ManifestReference is a ConfigMap or Secret in the import controller namespace that contains the extra
+// manifests of the import.yaml in its data.
+type ManifestReference struct {
+	Kind string
+	Name string
+}
+
+func (r ManifestReference) String() string {
+	return r.Kind + "/" + r.Name
+}
+
+// ParseManifestReferences parses the comma separated manifest references in the format of configmap/<name> or
+// secret/<name>.
+func ParseManifestReferences(val string) ([]ManifestReference, error) {
+	refs := []ManifestReference{}
+	for _, item := range strings.Split(val, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) == 0 {
+			continue
+		}
+
+		kind, name, ok := strings.Cut(item, "/")
+		kind = strings.ToLower(kind)
+		if !ok || len(name) == 0 ||
+			(kind != ManifestReferenceKindConfigMap && kind != ManifestReferenceKindSecret) {
+			return nil, fmt.Errorf("invalid manifest reference %q, it should be configmap/<name> or secret/<name>",
+				item)
+		}
+		refs = append(refs, ManifestReference{Kind: kind, Name: name})
+	}
+	return refs, nil
+}
+
+// GetControllerExtraManifests returns the extra manifests of all of the managed clusters that are specified with
+// the extraManifests of the import-controller-config ConfigMap, the invalid value is ignored.
+func (c *ImportControllerConfig) GetControllerExtraManifests() ([]ManifestReference, error) {
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	refs, err := ParseManifestReferences(cm.Data[constants.ExtraManifestsKey])
+	if err != nil {
+		c.log.Info("Invalid config value found and ignore it.",
+			"configmap", constants.ControllerConfigConfigMapName,
+			constants.ExtraManifestsKey, cm.Data[constants.ExtraManifestsKey],
+			"error", err.Error())
+		return nil, nil
+	}
+	return refs, nil
+}
+
+// GetExtraManifests returns the extra manifests of the given managed cluster, they are the extra manifests of the
+// import-controller-config ConfigMap followed by the ones of the klusterletconfig (or the global klusterletconfig)
+// of the cluster. The invalid values are ignored.
+func (c *ImportControllerConfig) GetExtraManifests(cluster *clusterv1.ManagedCluster,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister) ([]ManifestReference, error) {
+	refs, err := c.GetControllerExtraManifests()
+	if err != nil {
+		return nil, err
+	}
+
+	val, err := GetKlusterletConfigAnnotation(cluster.Annotations[apiconstants.AnnotationKlusterletConfig],
+		constants.ExtraManifestsAnnotation, kcLister)
+	if err != nil {
+		return nil, err
+	}
+	kcRefs, err := ParseManifestReferences(val)
+	if err != nil {
+		c.log.Info("Invalid klusterletconfig annotation value found and ignore it.",
+			"managedCluster", cluster.Name,
+			constants.ExtraManifestsAnnotation, val,
+			"error", err.Error())
+		return refs, nil
+	}
+
+	for _, kcRef := range kcRefs {
+		found := false
+		for _, ref := range refs {
+			if ref == kcRef {
+				found = true
+				break
+			}
+		}
+		if !found {
+			refs = append(refs, kcRef)
+		}
+	}
+	return refs, nil
+}
+
+// ValidateImportObject returns an error if the object cannot be applied by the ApplyResources, so it cannot be
+// included in the import.yaml.
+func ValidateImportObject(raw []byte) error {
+	obj, gvk, err := genericCodec.Decode(raw, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	switch obj.(type) {
+	case *corev1.ServiceAccount, *corev1.Secret, *corev1.ConfigMap, *corev1.Namespace, *appsv1.Deployment,
+		*rbacv1.ClusterRole, *rbacv1.ClusterRoleBinding, *crdv1.CustomResourceDefinition,
+		*schedulingv1.PriorityClass, *networkingv1.NetworkPolicy:
+		return nil
+	default:
+		return fmt.Errorf("unsupported kind %s", gvk.Kind)
+	}
+}
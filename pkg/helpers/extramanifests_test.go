@@ -0,0 +1,218 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func TestParseManifestReferences(t *testing.T) {
+	cases := []struct {
+		name         string
+		val          string
+		expectedRefs []ManifestReference
+		expectedErr  bool
+	}{
+		{
+			name:         "empty",
+			expectedRefs: []ManifestReference{},
+		},
+		{
+			name: "configmaps and secrets",
+			val:  "configmap/cm1, Secret/secret1,,configmap/cm2",
+			expectedRefs: []ManifestReference{
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm1"},
+				{Kind: ManifestReferenceKindSecret, Name: "secret1"},
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm2"},
+			},
+		},
+		{
+			name:        "no kind",
+			val:         "cm1",
+			expectedErr: true,
+		},
+		{
+			name:        "no name",
+			val:         "configmap/",
+			expectedErr: true,
+		},
+		{
+			name:        "unsupported kind",
+			val:         "deployment/test",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			refs, err := ParseManifestReferences(c.val)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if !c.expectedErr && !reflect.DeepEqual(refs, c.expectedRefs) {
+				t.Errorf("expected %v, but got %v", c.expectedRefs, refs)
+			}
+		})
+	}
+}
+
+func TestGetExtraManifests(t *testing.T) {
+	klusterletConfigs := map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+		"extra": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "extra",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/extra-manifests": "configmap/cm1,secret/secret1",
+				},
+			},
+		},
+		"invalid": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "invalid",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/extra-manifests": "service/test",
+				},
+			},
+		},
+	}
+	lister := &mockKlusterletConfigLister{
+		GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			if kc, ok := klusterletConfigs[name]; ok {
+				return kc, nil
+			}
+			return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+		},
+	}
+
+	cases := []struct {
+		name         string
+		data         map[string]string
+		annotations  map[string]string
+		expectedRefs []ManifestReference
+	}{
+		{
+			name: "no extra manifests",
+		},
+		{
+			name: "controller extra manifests",
+			data: map[string]string{"extraManifests": "configmap/cm1"},
+			expectedRefs: []ManifestReference{
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm1"},
+			},
+		},
+		{
+			name: "invalid controller extra manifests are ignored",
+			data: map[string]string{"extraManifests": "cm1"},
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "extra",
+			},
+			expectedRefs: []ManifestReference{
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm1"},
+				{Kind: ManifestReferenceKindSecret, Name: "secret1"},
+			},
+		},
+		{
+			name: "klusterletconfig extra manifests are appended",
+			data: map[string]string{"extraManifests": "configmap/cm0,configmap/cm1"},
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "extra",
+			},
+			expectedRefs: []ManifestReference{
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm0"},
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm1"},
+				{Kind: ManifestReferenceKindSecret, Name: "secret1"},
+			},
+		},
+		{
+			name: "invalid klusterletconfig extra manifests are ignored",
+			data: map[string]string{"extraManifests": "configmap/cm0"},
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "invalid",
+			},
+			expectedRefs: []ManifestReference{
+				{Kind: ManifestReferenceKindConfigMap, Name: "cm0"},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if c.data != nil {
+				_ = kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "import-controller-config",
+						Namespace: "test",
+					},
+					Data: c.data,
+				})
+			}
+			controllerConfig := NewImportControllerConfig("test",
+				kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("import-controller-config"))
+
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: c.annotations,
+				},
+			}
+			refs, err := controllerConfig.GetExtraManifests(cluster, lister)
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if len(refs) != len(c.expectedRefs) || (len(refs) > 0 && !reflect.DeepEqual(refs, c.expectedRefs)) {
+				t.Errorf("expect %v, but got %v", c.expectedRefs, refs)
+			}
+		})
+	}
+}
+
+func TestValidateImportObject(t *testing.T) {
+	cases := []struct {
+		name        string
+		raw         string
+		expectedErr bool
+	}{
+		{
+			name: "configmap",
+			raw:  "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: test\n  namespace: default\n",
+		},
+		{
+			name: "networkpolicy",
+			raw: "apiVersion: networking.k8s.io/v1\nkind: NetworkPolicy\nmetadata:\n  name: test\n" +
+				"  namespace: default\n",
+		},
+		{
+			name:        "unsupported kind",
+			raw:         "apiVersion: v1\nkind: Service\nmetadata:\n  name: test\n  namespace: default\n",
+			expectedErr: true,
+		},
+		{
+			name:        "unknown kind",
+			raw:         "apiVersion: example.com/v1\nkind: Foo\nmetadata:\n  name: test\n",
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateImportObject([]byte(c.raw))
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
@@ -71,10 +71,27 @@ import (
 const maxConcurrentReconcilesEnvVarName = "MAX_CONCURRENT_RECONCILES"
 
 const (
-	nodeSelectorAnnotation = "open-cluster-management/nodeSelector"
-	tolerationsAnnotation  = "open-cluster-management/tolerations"
+	nodeSelectorAnnotation        = "open-cluster-management/nodeSelector"
+	tolerationsAnnotation         = "open-cluster-management/tolerations"
+	klusterletResourcesAnnotation = "open-cluster-management/klusterlet-resources"
+	klusterletReplicasAnnotation  = "open-cluster-management/klusterlet-replicas"
+
+	affinityAnnotation                  = "open-cluster-management/affinity"
+	topologySpreadConstraintsAnnotation = "open-cluster-management/topologySpreadConstraints"
 )
 
+// KlusterletSettingsAnnotations returns the keys of the managed cluster annotations that customize the klusterlet
+func KlusterletSettingsAnnotations() []string {
+	return []string{
+		nodeSelectorAnnotation,
+		tolerationsAnnotation,
+		klusterletResourcesAnnotation,
+		klusterletReplicasAnnotation,
+		affinityAnnotation,
+		topologySpreadConstraintsAnnotation,
+	}
+}
+
 const (
 	kubeconfigDefaultCluster = "default-cluster"
 	kubeconfigDefaultAuth    = "default-auth"
@@ -160,6 +177,50 @@ func GenerateImportClientFromKubeTokenSecret(secret *corev1.Secret) (reconcile.R
 
 // GenerateImportClientFromRosaCluster generate a client from a given secret that contains rosa cluster info
 func GenerateImportClientFromRosaCluster(getter *RosaKubeConfigGetter, secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	if err := setRosaKubeConfigGetter(getter, secret); err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+	getter.SetProduct("")
+	getter.SetBreakGlassEnabled(false)
+
+	return generateImportClientFromOCMGetter(getter)
+}
+
+// GenerateImportClientFromOCMCluster generate a client from a given secret that contains the info of a cluster
+// managed by the OpenShift Cluster Manager API, the break-glass credentials of the cluster are preferred over an
+// import user with the service-account auth method.
+func GenerateImportClientFromOCMCluster(getter *RosaKubeConfigGetter, secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	if err := setRosaKubeConfigGetter(getter, secret); err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	product := strings.ToLower(string(secret.Data[constants.AutoImportSecretOCMConfigProductKey]))
+	switch product {
+	case constants.AutoImportSecretOCMProductROSA,
+		constants.AutoImportSecretOCMProductOSD,
+		constants.AutoImportSecretOCMProductARO:
+		getter.SetProduct(product)
+	case "":
+		return reconcile.Result{}, nil, nil, fmt.Errorf("product is missing")
+	default:
+		return reconcile.Result{}, nil, nil, fmt.Errorf("unsupported product %s", product)
+	}
+	getter.SetBreakGlassEnabled(true)
+
+	return generateImportClientFromOCMGetter(getter)
+}
+
+func generateImportClientFromOCMGetter(getter *RosaKubeConfigGetter) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	requeue, config, err := getter.KubeConfig()
+	if err != nil {
+		return reconcile.Result{Requeue: requeue, RequeueAfter: rosaImportRetryPeriod}, nil, nil, err
+	}
+
+	return buildImportClient(config)
+}
+
+// setRosaKubeConfigGetter sets the getter with the rosa cluster info of the given secret
+func setRosaKubeConfigGetter(getter *RosaKubeConfigGetter, secret *corev1.Secret) error {
 	authMethod := secret.Data[constants.AutoImportSecretRosaConfigAuthMethodKey]
 	switch string(authMethod) {
 	case constants.AutoImportSecretRosaConfigAuthMethodServiceAccount:
@@ -167,11 +228,11 @@ func GenerateImportClientFromRosaCluster(getter *RosaKubeConfigGetter, secret *c
 
 		clientID, hasClientID := secret.Data[constants.AutoImportSecretRosaConfigClientIDKey]
 		if !hasClientID {
-			return reconcile.Result{}, nil, nil, fmt.Errorf("client_id is missing")
+			return fmt.Errorf("client_id is missing")
 		}
 		clientSecret, hasClientSecret := secret.Data[constants.AutoImportSecretRosaConfigClientSecretKey]
 		if !hasClientSecret {
-			return reconcile.Result{}, nil, nil, fmt.Errorf("client_secret is missing")
+			return fmt.Errorf("client_secret is missing")
 		}
 
 		getter.SetClientID(string(clientID))
@@ -181,17 +242,17 @@ func GenerateImportClientFromRosaCluster(getter *RosaKubeConfigGetter, secret *c
 
 		token, hasOCMAPIToken := secret.Data[constants.AutoImportSecretRosaConfigAPITokenKey]
 		if !hasOCMAPIToken {
-			return reconcile.Result{}, nil, nil, fmt.Errorf("api_token is missing")
+			return fmt.Errorf("api_token is missing")
 		}
 
 		getter.SetToken(string(token))
 	default:
-		return reconcile.Result{}, nil, nil, fmt.Errorf("unsupported auth method %s", authMethod)
+		return fmt.Errorf("unsupported auth method %s", authMethod)
 	}
 
 	clusterID, hasRosaClusterID := secret.Data[constants.AutoImportSecretRosaConfigClusterIDKey]
 	if !hasRosaClusterID {
-		return reconcile.Result{}, nil, nil, fmt.Errorf("cluster_id is missing")
+		return fmt.Errorf("cluster_id is missing")
 	}
 	getter.SetClusterID(string(clusterID))
 
@@ -207,12 +268,7 @@ func GenerateImportClientFromRosaCluster(getter *RosaKubeConfigGetter, secret *c
 		getter.SetRetryTimes(string(retryTimes))
 	}
 
-	requeue, config, err := getter.KubeConfig()
-	if err != nil {
-		return reconcile.Result{Requeue: requeue, RequeueAfter: rosaImportRetryPeriod}, nil, nil, err
-	}
-
-	return buildImportClient(config)
+	return nil
 }
 
 func buildImportClient(config *clientcmdapi.Config) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
@@ -265,10 +321,18 @@ func buildImportClient(config *clientcmdapi.Config) (reconcile.Result, *ClientHo
 }
 
 func buildKubeConfigFileWithToken(apiURL, token string) *clientcmdapi.Config {
+	config := buildKubeConfigFileWithTokenAndCA(apiURL, token, nil)
+	config.Clusters[kubeconfigDefaultCluster].InsecureSkipTLSVerify = true
+	return config
+}
+
+// buildKubeConfigFileWithTokenAndCA builds a kubeconfig with the token, the server certificate is verified
+// with the caData if it is provided, otherwise it is verified with the system trust store.
+func buildKubeConfigFileWithTokenAndCA(apiURL, token string, caData []byte) *clientcmdapi.Config {
 	return &clientcmdapi.Config{
 		Clusters: map[string]*clientcmdapi.Cluster{kubeconfigDefaultCluster: {
-			Server:                apiURL,
-			InsecureSkipTLSVerify: true,
+			Server:                   apiURL,
+			CertificateAuthorityData: caData,
 		}},
 		AuthInfos: map[string]*clientcmdapi.AuthInfo{kubeconfigDefaultAuth: {
 			Token: token,
@@ -394,6 +458,10 @@ func UpdateManagedClusterImportCondition(client client.Client, managedCluster *c
 		recorder.Eventf(mc, nil, corev1.EventTypeNormal,
 			constants.EventReasonManagedClusterWait, constants.EventReasonManagedClusterWait,
 			"The %s is waiting for importing", mc.Name)
+	case constants.ConditionReasonManagedClusterWaitingForImportSlot:
+		recorder.Eventf(mc, nil, corev1.EventTypeNormal,
+			constants.EventReasonManagedClusterWaitSlot, constants.EventReasonManagedClusterWaitSlot,
+			"The %s is waiting for an import slot", mc.Name)
 	case constants.ConditionReasonManagedClusterImporting:
 		recorder.Eventf(mc, nil, corev1.EventTypeNormal,
 			constants.EventReasonManagedClusterImporting, constants.EventReasonManagedClusterImporting,
@@ -432,9 +500,19 @@ func ValidateImportSecret(importSecret *corev1.Secret) error {
 // ImportManagedClusterFromSecret use managed cluster client to import managed cluster from import-secret
 func ImportManagedClusterFromSecret(client *ClientHolder, restMapper meta.RESTMapper, recorder events.Recorder,
 	importSecret *corev1.Secret) (bool, error) {
-	if err := ValidateImportSecret(importSecret); err != nil {
+	objs, err := importObjectsFromSecret(importSecret)
+	if err != nil {
 		return false, err
 	}
+	// using managed cluster client to apply resources in managed cluster, so the owner is not need
+	return ApplyResources(client, recorder, nil, nil, objs...)
+}
+
+// importObjectsFromSecret returns the objects in the crds.yaml and import.yaml of the import secret
+func importObjectsFromSecret(importSecret *corev1.Secret) ([]runtime.Object, error) {
+	if err := ValidateImportSecret(importSecret); err != nil {
+		return nil, err
+	}
 
 	objs := []runtime.Object{}
 	if val, ok := importSecret.Data[constants.ImportSecretCRDSYamlKey]; ok && len(val) > 0 {
@@ -443,14 +521,21 @@ func ImportManagedClusterFromSecret(client *ClientHolder, restMapper meta.RESTMa
 	for _, yaml := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
 		objs = append(objs, MustCreateObject(yaml))
 	}
-	// using managed cluster client to apply resources in managed cluster, so the owner is not need
-	return ApplyResources(client, recorder, nil, nil, objs...)
+	return objs, nil
 }
 
 // UpdateManagedClusterBootstrapSecret update the bootstrap secret on the managed cluster
 func UpdateManagedClusterBootstrapSecret(client *ClientHolder, importSecret *corev1.Secret,
 	recorder events.Recorder) (bool, error) {
+	obj, err := bootstrapSecretObjectFromSecret(importSecret)
+	if err != nil {
+		return false, err
+	}
+	return ApplyResources(client, recorder, nil, nil, obj)
+}
 
+// bootstrapSecretObjectFromSecret returns the bootstrap-hub-kubeconfig object in the import.yaml of the import secret
+func bootstrapSecretObjectFromSecret(importSecret *corev1.Secret) (runtime.Object, error) {
 	var obj runtime.Object
 	for _, yaml := range SplitYamls(importSecret.Data[constants.ImportSecretImportYamlKey]) {
 		obj = MustCreateObject(yaml)
@@ -464,10 +549,10 @@ func UpdateManagedClusterBootstrapSecret(client *ClientHolder, importSecret *cor
 		}
 	}
 	if obj == nil {
-		return false, fmt.Errorf("failed to find bootstrap-hub-kubeconfig in import secret %s/%s",
+		return nil, fmt.Errorf("failed to find bootstrap-hub-kubeconfig in import secret %s/%s",
 			importSecret.Namespace, importSecret.Name)
 	}
-	return ApplyResources(client, recorder, nil, nil, obj)
+	return obj, nil
 }
 
 // SplitYamls split yamls with sperator `---`
@@ -573,6 +658,11 @@ func ApplyResources(clientHolder *ClientHolder, recorder events.Recorder,
 				clientHolder.KubeClient.CoreV1(), recorder, required)
 			errs = append(errs, err)
 			changed = changed || modified
+		case *corev1.ConfigMap:
+			_, modified, err := resourceapply.ApplyConfigMap(context.TODO(),
+				clientHolder.KubeClient.CoreV1(), recorder, required)
+			errs = append(errs, err)
+			changed = changed || modified
 		case *corev1.Namespace:
 			_, modified, err := resourceapply.ApplyNamespace(context.TODO(),
 				clientHolder.KubeClient.CoreV1(), recorder, required)
@@ -1857,6 +1857,67 @@ func TestGenerateImportClientFromRosaCluster(t *testing.T) {
 	}
 }
 
+func TestGenerateImportClientFromOCMCluster(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	accessToken := clustersmgmttesting.MakeTokenString("Bearer", 5*time.Minute)
+
+	apiServer := clustersmgmttesting.MakeTCPServer()
+	apiServer.AppendHandlers(
+		ghttp.CombineHandlers(
+			ghttp.VerifyRequest(http.MethodGet, "/api/clusters_mgmt/v1/clusters/c0001"),
+			clustersmgmttesting.RespondWithJSON(http.StatusOK,
+				`{"kind":"Cluster","product":{"id":"osd"},"api":{"url":"https://api.test.com:443"}}`),
+		),
+	)
+	defer apiServer.Close()
+
+	cases := []struct {
+		name           string
+		product        string
+		expectedErrMsg string
+	}{
+		{
+			name:           "product is missing",
+			expectedErrMsg: "product is missing",
+		},
+		{
+			name:           "unsupported product",
+			product:        "eks",
+			expectedErrMsg: "unsupported product eks",
+		},
+		{
+			name:           "product mismatch",
+			product:        "ROSA",
+			expectedErrMsg: "the cluster c0001 is a osd cluster, but the product is rosa",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "auto-import-secret",
+				},
+				Type: constants.AutoImportSecretOCMConfig,
+				Data: map[string][]byte{
+					"api_token":  []byte(accessToken),
+					"cluster_id": []byte("c0001"),
+					"api_url":    []byte(apiServer.URL()),
+				},
+			}
+			if len(c.product) != 0 {
+				secret.Data["product"] = []byte(c.product)
+			}
+
+			_, _, _, err := GenerateImportClientFromOCMCluster(NewRosaKubeConfigGetter(), secret)
+			if err == nil || err.Error() != c.expectedErrMsg {
+				t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+			}
+		})
+	}
+}
+
 func TestIsKubeVersionChanged(t *testing.T) {
 	cases := []struct {
 		name       string
@@ -2543,3 +2604,42 @@ users:
 		})
 	}
 }
+
+func TestBuildKubeConfigFileWithTokenAndCA(t *testing.T) {
+	cases := []struct {
+		name             string
+		config           *clientcmdapi.Config
+		expectedInsecure bool
+		expectedCAData   string
+	}{
+		{
+			name:           "verified with the ca",
+			config:         buildKubeConfigFileWithTokenAndCA("https://127.0.0.1:6443", "token", []byte("ca")),
+			expectedCAData: "ca",
+		},
+		{
+			name:   "verified with the system trust store",
+			config: buildKubeConfigFileWithTokenAndCA("https://127.0.0.1:6443", "token", nil),
+		},
+		{
+			name:             "not verified",
+			config:           buildKubeConfigFileWithToken("https://127.0.0.1:6443", "token"),
+			expectedInsecure: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := c.config.Clusters[c.config.Contexts[c.config.CurrentContext].Cluster]
+			if cluster.InsecureSkipTLSVerify != c.expectedInsecure {
+				t.Errorf("expected insecure %v, but got %v", c.expectedInsecure, cluster.InsecureSkipTLSVerify)
+			}
+			if string(cluster.CertificateAuthorityData) != c.expectedCAData {
+				t.Errorf("expected ca %q, but got %q", c.expectedCAData, cluster.CertificateAuthorityData)
+			}
+			if c.config.AuthInfos[c.config.Contexts[c.config.CurrentContext].AuthInfo].Token != "token" {
+				t.Errorf("expected the token is set, but got %v", c.config.AuthInfos)
+			}
+		})
+	}
+}
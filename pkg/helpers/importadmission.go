@@ -0,0 +1,187 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"sync"
+	"time"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// importSlotTimeout is the max duration that a cluster holds an import slot, the slot is reclaimed after that,
+	// so a cluster that is deleted or stops being imported during an attempt does not take up the slot forever.
+	importSlotTimeout = 10 * time.Minute
+	// importWaiterTimeout is the max duration that a cluster stays in the admission queue without asking for a
+	// slot again.
+	importWaiterTimeout = 3 * constants.ImportSlotRetryInterval
+)
+
+// ImportAdmissionLimits is the caps of the imports that are in flight, 0 means unlimited.
+type ImportAdmissionLimits struct {
+	MaxInFlight          int
+	MaxInFlightPerSource map[string]int
+}
+
+type importSlot struct {
+	source string
+	since  time.Time
+}
+
+type importWaiter struct {
+	cluster  string
+	source   string
+	lastSeen time.Time
+}
+
+// ImportAdmission limits the number of the imports that are in flight across the fleet. It is shared by the
+// controllers that import the managed clusters. A cluster asks for a slot before it is imported and releases
+// the slot once the import attempt ends, the clusters that cannot get a slot are queued and admitted in the
+// order of their first request.
+//
+// A nil ImportAdmission admits all of the imports.
+type ImportAdmission struct {
+	lock sync.Mutex
+
+	getLimits func() (ImportAdmissionLimits, error)
+	now       func() time.Time
+
+	inFlight map[string]importSlot
+	waiters  []importWaiter
+}
+
+func NewImportAdmission(config *ImportControllerConfig) *ImportAdmission {
+	return &ImportAdmission{
+		getLimits: config.GetImportAdmissionLimits,
+		now:       time.Now,
+		inFlight:  map[string]importSlot{},
+	}
+}
+
+// TryAcquire returns true if the cluster holds an import slot of the given source, otherwise the cluster is
+// queued and has to ask again later.
+func (a *ImportAdmission) TryAcquire(clusterName, source string) (bool, error) {
+	if a == nil {
+		return true, nil
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	now := a.now()
+	a.expire(now)
+
+	if slot, ok := a.inFlight[clusterName]; ok && slot.source == source {
+		return true, nil
+	}
+	// the source of the cluster is changed, e.g. the auto-import secret is replaced
+	delete(a.inFlight, clusterName)
+
+	limits, err := a.getLimits()
+	if err != nil {
+		return false, err
+	}
+
+	total, perSource := len(a.inFlight), a.countBySource()
+	aheadTotal, aheadSource := a.waitersAhead(clusterName, source, limits, perSource)
+	if a.hasRoom(limits, total, perSource, source, aheadTotal, aheadSource) {
+		a.inFlight[clusterName] = importSlot{source: source, since: now}
+		a.removeWaiter(clusterName)
+		return true, nil
+	}
+
+	a.enqueue(clusterName, source, now)
+	return false, nil
+}
+
+// Release releases the import slot of the cluster.
+func (a *ImportAdmission) Release(clusterName string) {
+	if a == nil {
+		return
+	}
+
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	delete(a.inFlight, clusterName)
+}
+
+// hasRoom returns true if the source has a free slot after the slots are taken by the earlier waiters.
+func (a *ImportAdmission) hasRoom(limits ImportAdmissionLimits, total int, perSource map[string]int,
+	source string, aheadTotal, aheadSource int) bool {
+	if limits.MaxInFlight > 0 && total+aheadTotal >= limits.MaxInFlight {
+		return false
+	}
+	if limit := limits.MaxInFlightPerSource[source]; limit > 0 && perSource[source]+aheadSource >= limit {
+		return false
+	}
+	return true
+}
+
+// waitersAhead returns the number of the waiters that are queued before the cluster and are able to take a slot,
+// and the number of those waiters that have the given source. All of the waiters are ahead of the cluster if
+// the cluster is not queued yet.
+func (a *ImportAdmission) waitersAhead(clusterName, source string, limits ImportAdmissionLimits,
+	perSource map[string]int) (int, int) {
+	aheadTotal, aheadSource := 0, 0
+	for _, w := range a.waiters {
+		if w.cluster == clusterName {
+			break
+		}
+		// the waiter is blocked by the cap of its own source, it does not take a slot
+		if limit := limits.MaxInFlightPerSource[w.source]; limit > 0 && perSource[w.source] >= limit {
+			continue
+		}
+		aheadTotal++
+		if w.source == source {
+			aheadSource++
+		}
+	}
+	return aheadTotal, aheadSource
+}
+
+func (a *ImportAdmission) countBySource() map[string]int {
+	perSource := map[string]int{}
+	for _, slot := range a.inFlight {
+		perSource[slot.source]++
+	}
+	return perSource
+}
+
+func (a *ImportAdmission) enqueue(clusterName, source string, now time.Time) {
+	for i, w := range a.waiters {
+		if w.cluster == clusterName {
+			a.waiters[i].source = source
+			a.waiters[i].lastSeen = now
+			return
+		}
+	}
+	a.waiters = append(a.waiters, importWaiter{cluster: clusterName, source: source, lastSeen: now})
+}
+
+func (a *ImportAdmission) removeWaiter(clusterName string) {
+	for i, w := range a.waiters {
+		if w.cluster == clusterName {
+			a.waiters = append(a.waiters[:i], a.waiters[i+1:]...)
+			return
+		}
+	}
+}
+
+func (a *ImportAdmission) expire(now time.Time) {
+	for cluster, slot := range a.inFlight {
+		if now.Sub(slot.since) > importSlotTimeout {
+			delete(a.inFlight, cluster)
+		}
+	}
+
+	waiters := a.waiters[:0]
+	for _, w := range a.waiters {
+		if now.Sub(w.lastSeen) <= importWaiterTimeout {
+			waiters = append(waiters, w)
+		}
+	}
+	a.waiters = waiters
+}
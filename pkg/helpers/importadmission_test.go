@@ -0,0 +1,216 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/informers"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+type admissionStep struct {
+	cluster  string
+	source   string
+	release  bool
+	advance  time.Duration
+	admitted bool
+}
+
+func TestImportAdmission(t *testing.T) {
+	hive, rosa := constants.ImportSourceHive, constants.ImportSourceRosa
+
+	cases := []struct {
+		name   string
+		limits ImportAdmissionLimits
+		steps  []admissionStep
+	}{
+		{
+			name:   "unlimited",
+			limits: ImportAdmissionLimits{},
+			steps: []admissionStep{
+				{cluster: "c1", source: hive, admitted: true},
+				{cluster: "c2", source: hive, admitted: true},
+				{cluster: "c3", source: rosa, admitted: true},
+			},
+		},
+		{
+			name:   "global cap",
+			limits: ImportAdmissionLimits{MaxInFlight: 2},
+			steps: []admissionStep{
+				{cluster: "c1", source: hive, admitted: true},
+				{cluster: "c2", source: rosa, admitted: true},
+				{cluster: "c3", source: hive, admitted: false},
+				// the cluster that holds a slot is admitted again
+				{cluster: "c1", source: hive, admitted: true},
+				{cluster: "c1", release: true},
+				{cluster: "c3", source: hive, admitted: true},
+			},
+		},
+		{
+			name: "per-source cap",
+			limits: ImportAdmissionLimits{
+				MaxInFlightPerSource: map[string]int{rosa: 1},
+			},
+			steps: []admissionStep{
+				{cluster: "c1", source: rosa, admitted: true},
+				{cluster: "c2", source: rosa, admitted: false},
+				{cluster: "c3", source: hive, admitted: true},
+				{cluster: "c1", release: true},
+				{cluster: "c2", source: rosa, admitted: true},
+			},
+		},
+		{
+			name:   "admitted in order",
+			limits: ImportAdmissionLimits{MaxInFlight: 1},
+			steps: []admissionStep{
+				{cluster: "c1", source: hive, admitted: true},
+				{cluster: "c2", source: hive, admitted: false},
+				{cluster: "c3", source: hive, admitted: false},
+				{cluster: "c1", release: true},
+				// c2 is queued before c3
+				{cluster: "c3", source: hive, admitted: false},
+				{cluster: "c2", source: hive, admitted: true},
+			},
+		},
+		{
+			name: "a waiter blocked by its source cap does not block others",
+			limits: ImportAdmissionLimits{
+				MaxInFlight:          2,
+				MaxInFlightPerSource: map[string]int{rosa: 1},
+			},
+			steps: []admissionStep{
+				{cluster: "c1", source: rosa, admitted: true},
+				{cluster: "c2", source: rosa, admitted: false},
+				{cluster: "c3", source: hive, admitted: true},
+			},
+		},
+		{
+			name:   "the queued cluster that stops asking is removed",
+			limits: ImportAdmissionLimits{MaxInFlight: 1},
+			steps: []admissionStep{
+				{cluster: "c1", source: hive, admitted: true},
+				{cluster: "c2", source: hive, admitted: false},
+				{cluster: "c1", release: true},
+				{advance: importWaiterTimeout + time.Second},
+				{cluster: "c3", source: hive, admitted: true},
+			},
+		},
+		{
+			name:   "the slot is reclaimed after timeout",
+			limits: ImportAdmissionLimits{MaxInFlight: 1},
+			steps: []admissionStep{
+				{cluster: "c1", source: hive, admitted: true},
+				{advance: importSlotTimeout + time.Second},
+				{cluster: "c2", source: hive, admitted: true},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			now := time.Now()
+			admission := &ImportAdmission{
+				getLimits: func() (ImportAdmissionLimits, error) { return c.limits, nil },
+				now:       func() time.Time { return now },
+				inFlight:  map[string]importSlot{},
+			}
+
+			for i, step := range c.steps {
+				switch {
+				case step.advance > 0:
+					now = now.Add(step.advance)
+				case step.release:
+					admission.Release(step.cluster)
+				default:
+					admitted, err := admission.TryAcquire(step.cluster, step.source)
+					if err != nil {
+						t.Fatalf("step %d: unexpected error %v", i, err)
+					}
+					if admitted != step.admitted {
+						t.Errorf("step %d: expected %s admitted %v, but got %v", i, step.cluster,
+							step.admitted, admitted)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNilImportAdmission(t *testing.T) {
+	var admission *ImportAdmission
+	admitted, err := admission.TryAcquire("c1", constants.ImportSourceHive)
+	if err != nil || !admitted {
+		t.Errorf("expected admitted, but got %v, %v", admitted, err)
+	}
+	admission.Release("c1")
+}
+
+func TestGetImportAdmissionLimits(t *testing.T) {
+	cases := []struct {
+		name           string
+		data           map[string]string
+		expectedLimits ImportAdmissionLimits
+	}{
+		{
+			name: "no config",
+			expectedLimits: ImportAdmissionLimits{
+				MaxInFlightPerSource: map[string]int{},
+			},
+		},
+		{
+			name: "customized limits",
+			data: map[string]string{
+				"maxInFlightImports":                 "20",
+				"maxInFlightHiveImports":             "10",
+				"maxInFlightRosaImports":             "invalid",
+				"maxInFlightAutoImportSecretImports": "-1",
+			},
+			expectedLimits: ImportAdmissionLimits{
+				MaxInFlight: 20,
+				MaxInFlightPerSource: map[string]int{
+					constants.ImportSourceHive:             10,
+					constants.ImportSourceRosa:             0,
+					constants.ImportSourceAutoImportSecret: 0,
+				},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			configmapInformer := kubeInformerFactory.Core().V1().ConfigMaps().Informer()
+			if c.data != nil {
+				if err := configmapInformer.GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "import-controller-config",
+						Namespace: "test",
+					},
+					Data: c.data,
+				}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			controllerConfig := NewImportControllerConfig("test",
+				kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("import-controller-config"))
+
+			limits, err := controllerConfig.GetImportAdmissionLimits()
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if !reflect.DeepEqual(limits, c.expectedLimits) {
+				t.Errorf("expect %v, but got %v", c.expectedLimits, limits)
+			}
+		})
+	}
+}
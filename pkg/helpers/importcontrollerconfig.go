@@ -1,11 +1,16 @@
 package helpers
 
 import (
+	"strconv"
+	"time"
+
 	"github.com/go-logr/logr"
+	listerklusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/client/klusterletconfig/listers/klusterletconfig/v1alpha1"
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
 	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
 	"k8s.io/apimachinery/pkg/api/errors"
 	corev1listers "k8s.io/client-go/listers/core/v1"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 )
 
 type ImportControllerConfig struct {
@@ -47,6 +52,43 @@ func (c *ImportControllerConfig) GetAutoImportStrategy() (string, error) {
 	}
 }
 
+// GetClusterAutoImportStrategy returns the AutoImportStrategy of the given managed cluster. The strategy is
+// determined by the annotation of the managed cluster, then the annotation of its klusterletconfig (or the
+// global klusterletconfig), and finally the autoImportStrategy of the import-controller-config ConfigMap.
+// The invalid annotation values are ignored.
+func (c *ImportControllerConfig) GetClusterAutoImportStrategy(cluster *clusterv1.ManagedCluster,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister) (string, error) {
+	if strategy, ok := cluster.Annotations[constants.AutoImportStrategyAnnotation]; ok {
+		if isValidAutoImportStrategy(strategy) {
+			return strategy, nil
+		}
+		c.log.Info("Invalid annotation value found and ignore it.",
+			"managedCluster", cluster.Name,
+			constants.AutoImportStrategyAnnotation, strategy)
+	}
+
+	strategy, err := GetKlusterletConfigAnnotation(cluster.Annotations[apiconstants.AnnotationKlusterletConfig],
+		constants.AutoImportStrategyAnnotation, kcLister)
+	if err != nil {
+		return "", err
+	}
+	if isValidAutoImportStrategy(strategy) {
+		return strategy, nil
+	}
+	if len(strategy) != 0 {
+		c.log.Info("Invalid klusterletconfig annotation value found and ignore it.",
+			"managedCluster", cluster.Name,
+			constants.AutoImportStrategyAnnotation, strategy)
+	}
+
+	return c.GetAutoImportStrategy()
+}
+
+func isValidAutoImportStrategy(strategy string) bool {
+	return strategy == apiconstants.AutoImportStrategyImportAndSync ||
+		strategy == apiconstants.AutoImportStrategyImportOnly
+}
+
 // GenerateImportConfig to check whether to generate import config secret.
 func (c *ImportControllerConfig) GenerateImportConfig() (bool, error) {
 	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
@@ -62,3 +104,231 @@ func (c *ImportControllerConfig) GenerateImportConfig() (bool, error) {
 	}
 	return false, nil
 }
+
+// MigrateLegacyBootstrapTokens returns true if the managed clusters are migrated off the legacy service account
+// token secrets.
+func (c *ImportControllerConfig) MigrateLegacyBootstrapTokens() (bool, error) {
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if cm.Data[constants.LegacyBootstrapTokenMigrationKey] == constants.LabelValueTrue {
+		return true, nil
+	}
+	return false, nil
+}
+
+// GetAutoImportRetryPolicy returns the retry policy of the auto-import, the invalid config values are replaced
+// with the defaults.
+func (c *ImportControllerConfig) GetAutoImportRetryPolicy() (ImportRetryPolicy, error) {
+	policy := ImportRetryPolicy{
+		MaxAttempts: constants.DefaultAutoImportMaxAttempts,
+		BaseDelay:   constants.DefaultAutoImportRetryBaseDelay,
+		MaxDelay:    constants.DefaultAutoImportRetryMaxDelay,
+	}
+
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if errors.IsNotFound(err) {
+		return policy, nil
+	}
+	if err != nil {
+		return policy, err
+	}
+
+	if val, ok := cm.Data[constants.AutoImportMaxAttemptsKey]; ok {
+		maxAttempts, err := strconv.Atoi(val)
+		if err != nil || maxAttempts < 0 {
+			c.log.Info("Invalid config value found and use default instead.",
+				"configmap", constants.ControllerConfigConfigMapName,
+				constants.AutoImportMaxAttemptsKey, val,
+				"default", policy.MaxAttempts)
+		} else {
+			policy.MaxAttempts = maxAttempts
+		}
+	}
+
+	policy.BaseDelay = c.getDuration(cm.Data, constants.AutoImportRetryBaseDelayKey, policy.BaseDelay)
+	policy.MaxDelay = c.getDuration(cm.Data, constants.AutoImportRetryMaxDelayKey, policy.MaxDelay)
+	if policy.MaxDelay < policy.BaseDelay {
+		c.log.Info("Invalid config value found and use base delay instead.",
+			"configmap", constants.ControllerConfigConfigMapName,
+			constants.AutoImportRetryMaxDelayKey, policy.MaxDelay,
+			constants.AutoImportRetryBaseDelayKey, policy.BaseDelay)
+		policy.MaxDelay = policy.BaseDelay
+	}
+
+	return policy, nil
+}
+
+// GetKlusterletDriftDetectionInterval returns the interval of the klusterlet drift detection, the detection is
+// disabled if the interval is 0.
+func (c *ImportControllerConfig) GetKlusterletDriftDetectionInterval() (time.Duration, error) {
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if errors.IsNotFound(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return c.getDuration(cm.Data, constants.KlusterletDriftDetectionIntervalKey, 0), nil
+}
+
+// GetImportAdmissionLimits returns the caps of the imports that are in flight, the invalid config values are
+// treated as unlimited.
+func (c *ImportControllerConfig) GetImportAdmissionLimits() (ImportAdmissionLimits, error) {
+	limits := ImportAdmissionLimits{MaxInFlightPerSource: map[string]int{}}
+
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if errors.IsNotFound(err) {
+		return limits, nil
+	}
+	if err != nil {
+		return limits, err
+	}
+
+	limits.MaxInFlight = c.getLimit(cm.Data, constants.MaxInFlightImportsKey)
+	limits.MaxInFlightPerSource[constants.ImportSourceHive] = c.getLimit(cm.Data,
+		constants.MaxInFlightHiveImportsKey)
+	limits.MaxInFlightPerSource[constants.ImportSourceRosa] = c.getLimit(cm.Data,
+		constants.MaxInFlightRosaImportsKey)
+	limits.MaxInFlightPerSource[constants.ImportSourceAutoImportSecret] = c.getLimit(cm.Data,
+		constants.MaxInFlightAutoImportSecretImportsKey)
+	return limits, nil
+}
+
+// BootstrapTokenPolicy determines the lifetime of the bootstrap token and when the token is refreshed. The
+// RefreshThreshold is 0 if it is not specified, in that case the token is refreshed once its remaining lifetime
+// is less than 1/5 of its lifetime. The LifetimeSpecified is true if the lifetime is customized, in that case the
+// non-expiring legacy service account tokens are not allowed.
+type BootstrapTokenPolicy struct {
+	Lifetime          time.Duration
+	RefreshThreshold  time.Duration
+	LifetimeSpecified bool
+}
+
+// DefaultBootstrapTokenPolicy is the BootstrapTokenPolicy used if no customized policy is specified
+var DefaultBootstrapTokenPolicy = BootstrapTokenPolicy{
+	Lifetime: constants.DefaultSecretTokenExpirationSecond * time.Second,
+}
+
+// GetBootstrapTokenPolicy returns the bootstrap token policy of the given managed cluster. Each setting is
+// determined by the annotation of its klusterletconfig (or the global klusterletconfig), and then the
+// import-controller-config ConfigMap. The invalid values are ignored.
+func (c *ImportControllerConfig) GetBootstrapTokenPolicy(cluster *clusterv1.ManagedCluster,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister) (BootstrapTokenPolicy, error) {
+	policy := DefaultBootstrapTokenPolicy
+
+	data := map[string]string{}
+	cm, err := c.configMapLister.ConfigMaps(c.componentNamespace).Get(constants.ControllerConfigConfigMapName)
+	if err != nil && !errors.IsNotFound(err) {
+		return policy, err
+	}
+	if err == nil {
+		data = cm.Data
+	}
+
+	klusterletconfigName := cluster.Annotations[apiconstants.AnnotationKlusterletConfig]
+	lifetime, err := GetKlusterletConfigAnnotation(klusterletconfigName,
+		constants.BootstrapTokenLifetimeAnnotation, kcLister)
+	if err != nil {
+		return policy, err
+	}
+	threshold, err := GetKlusterletConfigAnnotation(klusterletconfigName,
+		constants.BootstrapTokenRefreshThresholdAnnotation, kcLister)
+	if err != nil {
+		return policy, err
+	}
+
+	if d, ok := c.parseBootstrapTokenLifetime(cluster.Name, constants.BootstrapTokenLifetimeAnnotation,
+		lifetime); ok {
+		policy.Lifetime = d
+		policy.LifetimeSpecified = true
+	} else if d, ok := c.parseBootstrapTokenLifetime(cluster.Name, constants.BootstrapTokenLifetimeKey,
+		data[constants.BootstrapTokenLifetimeKey]); ok {
+		policy.Lifetime = d
+		policy.LifetimeSpecified = true
+	}
+
+	if d, ok := c.parseBootstrapTokenRefreshThreshold(cluster.Name, constants.BootstrapTokenRefreshThresholdAnnotation,
+		threshold, policy.Lifetime); ok {
+		policy.RefreshThreshold = d
+	} else if d, ok := c.parseBootstrapTokenRefreshThreshold(cluster.Name, constants.BootstrapTokenRefreshThresholdKey,
+		data[constants.BootstrapTokenRefreshThresholdKey], policy.Lifetime); ok {
+		policy.RefreshThreshold = d
+	}
+
+	return policy, nil
+}
+
+func (c *ImportControllerConfig) parseBootstrapTokenLifetime(clusterName, key, val string) (time.Duration, bool) {
+	if len(val) == 0 {
+		return 0, false
+	}
+
+	lifetime, err := time.ParseDuration(val)
+	if err != nil || lifetime < constants.MinBootstrapTokenLifetime {
+		c.log.Info("Invalid bootstrap token lifetime found and ignore it.",
+			"managedCluster", clusterName,
+			key, val,
+			"minimum", constants.MinBootstrapTokenLifetime)
+		return 0, false
+	}
+	return lifetime.Truncate(time.Second), true
+}
+
+func (c *ImportControllerConfig) parseBootstrapTokenRefreshThreshold(clusterName, key, val string,
+	lifetime time.Duration) (time.Duration, bool) {
+	if len(val) == 0 {
+		return 0, false
+	}
+
+	threshold, err := time.ParseDuration(val)
+	if err != nil || threshold <= 0 || threshold >= lifetime {
+		c.log.Info("Invalid bootstrap token refresh threshold found and ignore it.",
+			"managedCluster", clusterName,
+			key, val,
+			"lifetime", lifetime)
+		return 0, false
+	}
+	return threshold, true
+}
+
+func (c *ImportControllerConfig) getLimit(data map[string]string, key string) int {
+	val, ok := data[key]
+	if !ok {
+		return 0
+	}
+
+	limit, err := strconv.Atoi(val)
+	if err != nil || limit < 0 {
+		c.log.Info("Invalid config value found and use default instead.",
+			"configmap", constants.ControllerConfigConfigMapName,
+			key, val,
+			"default", 0)
+		return 0
+	}
+	return limit
+}
+
+func (c *ImportControllerConfig) getDuration(data map[string]string, key string,
+	defaultValue time.Duration) time.Duration {
+	val, ok := data[key]
+	if !ok {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(val)
+	if err != nil || duration <= 0 {
+		c.log.Info("Invalid config value found and use default instead.",
+			"configmap", constants.ControllerConfigConfigMapName,
+			key, val,
+			"default", defaultValue)
+		return defaultValue
+	}
+	return duration
+}
@@ -5,11 +5,14 @@ import (
 	"time"
 
 	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/informers"
 	kubefake "k8s.io/client-go/kubernetes/fake"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 )
 
@@ -19,6 +22,7 @@ func TestImportControllerConfig(t *testing.T) {
 		controllerConfig       *corev1.ConfigMap
 		expectedStrategy       string
 		expectedGenerateSecret bool
+		expectedMigrateTokens  bool
 	}{
 		{
 			name:                   "default auto-import-strategy",
@@ -95,6 +99,20 @@ func TestImportControllerConfig(t *testing.T) {
 			expectedStrategy:       apiconstants.AutoImportStrategyImportOnly,
 			expectedGenerateSecret: true,
 		},
+		{
+			name: "configmap to migrate legacy bootstrap tokens",
+			controllerConfig: &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "import-controller-config",
+					Namespace: "test",
+				},
+				Data: map[string]string{
+					"legacyBootstrapTokenMigration": "true",
+				},
+			},
+			expectedStrategy:      apiconstants.AutoImportStrategyImportOnly,
+			expectedMigrateTokens: true,
+		},
 	}
 
 	for _, c := range cases {
@@ -119,6 +137,10 @@ func TestImportControllerConfig(t *testing.T) {
 			if err != nil {
 				t.Errorf("unexpected err %v", err)
 			}
+			migrateTokens, err := controllerConfig.MigrateLegacyBootstrapTokens()
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
 
 			if c.expectedStrategy != autoImportStrategy {
 				t.Errorf("expect %s, but got %s", c.expectedStrategy, autoImportStrategy)
@@ -126,6 +148,308 @@ func TestImportControllerConfig(t *testing.T) {
 			if c.expectedGenerateSecret != generateSecret {
 				t.Errorf("expect %v, but got %v", c.expectedGenerateSecret, generateSecret)
 			}
+			if c.expectedMigrateTokens != migrateTokens {
+				t.Errorf("expect %v, but got %v", c.expectedMigrateTokens, migrateTokens)
+			}
+		})
+	}
+}
+
+func TestGetAutoImportRetryPolicy(t *testing.T) {
+	defaultPolicy := ImportRetryPolicy{
+		MaxAttempts: 0,
+		BaseDelay:   10 * time.Second,
+		MaxDelay:    10 * time.Minute,
+	}
+
+	cases := []struct {
+		name           string
+		data           map[string]string
+		expectedPolicy ImportRetryPolicy
+	}{
+		{
+			name:           "default policy",
+			expectedPolicy: defaultPolicy,
+		},
+		{
+			name: "customized policy",
+			data: map[string]string{
+				"autoImportMaxAttempts":    "3",
+				"autoImportRetryBaseDelay": "1m",
+				"autoImportRetryMaxDelay":  "1h",
+			},
+			expectedPolicy: ImportRetryPolicy{
+				MaxAttempts: 3,
+				BaseDelay:   time.Minute,
+				MaxDelay:    time.Hour,
+			},
+		},
+		{
+			name: "invalid policy",
+			data: map[string]string{
+				"autoImportMaxAttempts":    "-1",
+				"autoImportRetryBaseDelay": "invalid",
+				"autoImportRetryMaxDelay":  "-1m",
+			},
+			expectedPolicy: defaultPolicy,
+		},
+		{
+			name: "max delay is less than base delay",
+			data: map[string]string{
+				"autoImportRetryBaseDelay": "20m",
+			},
+			expectedPolicy: ImportRetryPolicy{
+				BaseDelay: 20 * time.Minute,
+				MaxDelay:  20 * time.Minute,
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			configmapInformer := kubeInformerFactory.Core().V1().ConfigMaps().Informer()
+			if c.data != nil {
+				configmapInformer.GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "import-controller-config",
+						Namespace: "test",
+					},
+					Data: c.data,
+				})
+			}
+			controllerConfig := NewImportControllerConfig("test",
+				kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("import-controller-config"))
+
+			policy, err := controllerConfig.GetAutoImportRetryPolicy()
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expect %v, but got %v", c.expectedPolicy, policy)
+			}
+		})
+	}
+}
+
+func TestGetClusterAutoImportStrategy(t *testing.T) {
+	klusterletConfigs := map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+		"sync": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "sync",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportAndSync,
+				},
+			},
+		},
+		"invalid": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "invalid",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/auto-import-strategy": "invalid",
+				},
+			},
+		},
+	}
+	lister := &mockKlusterletConfigLister{
+		GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			if kc, ok := klusterletConfigs[name]; ok {
+				return kc, nil
+			}
+			return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+		},
+	}
+
+	cases := []struct {
+		name             string
+		annotations      map[string]string
+		globalStrategy   string
+		expectedStrategy string
+	}{
+		{
+			name:             "global strategy",
+			globalStrategy:   apiconstants.AutoImportStrategyImportAndSync,
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+		{
+			name: "cluster annotation",
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportAndSync,
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+		{
+			name: "cluster annotation takes precedence over klusterletconfig",
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-strategy": apiconstants.AutoImportStrategyImportOnly,
+				"agent.open-cluster-management.io/klusterlet-config":     "sync",
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportOnly,
+		},
+		{
+			name: "klusterletconfig annotation",
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "sync",
+			},
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+		{
+			name: "invalid values are ignored",
+			annotations: map[string]string{
+				"import.open-cluster-management.io/auto-import-strategy": "invalid",
+				"agent.open-cluster-management.io/klusterlet-config":     "invalid",
+			},
+			globalStrategy:   apiconstants.AutoImportStrategyImportAndSync,
+			expectedStrategy: apiconstants.AutoImportStrategyImportAndSync,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if len(c.globalStrategy) != 0 {
+				kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "import-controller-config",
+						Namespace: "test",
+					},
+					Data: map[string]string{
+						"autoImportStrategy": c.globalStrategy,
+					},
+				})
+			}
+			controllerConfig := NewImportControllerConfig("test",
+				kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("import-controller-config"))
+
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: c.annotations,
+				},
+			}
+			strategy, err := controllerConfig.GetClusterAutoImportStrategy(cluster, lister)
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if c.expectedStrategy != strategy {
+				t.Errorf("expect %s, but got %s", c.expectedStrategy, strategy)
+			}
+		})
+	}
+}
+
+func TestGetBootstrapTokenPolicy(t *testing.T) {
+	klusterletConfigs := map[string]*klusterletconfigv1alpha1.KlusterletConfig{
+		"short": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "short",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/bootstrap-token-lifetime":          "24h",
+					"import.open-cluster-management.io/bootstrap-token-refresh-threshold": "2h",
+				},
+			},
+		},
+		"invalid": {
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "invalid",
+				Annotations: map[string]string{
+					"import.open-cluster-management.io/bootstrap-token-lifetime":          "1m",
+					"import.open-cluster-management.io/bootstrap-token-refresh-threshold": "invalid",
+				},
+			},
+		},
+	}
+	lister := &mockKlusterletConfigLister{
+		GetFunc: func(name string) (*klusterletconfigv1alpha1.KlusterletConfig, error) {
+			if kc, ok := klusterletConfigs[name]; ok {
+				return kc, nil
+			}
+			return nil, errors.NewNotFound(klusterletconfigv1alpha1.Resource("klusterletconfigs"), name)
+		},
+	}
+
+	cases := []struct {
+		name           string
+		data           map[string]string
+		annotations    map[string]string
+		expectedPolicy BootstrapTokenPolicy
+	}{
+		{
+			name:           "default policy",
+			expectedPolicy: DefaultBootstrapTokenPolicy,
+		},
+		{
+			name: "global policy",
+			data: map[string]string{
+				"bootstrapTokenLifetime":         "168h",
+				"bootstrapTokenRefreshThreshold": "48h",
+			},
+			expectedPolicy: BootstrapTokenPolicy{Lifetime: 168 * time.Hour, RefreshThreshold: 48 * time.Hour,
+				LifetimeSpecified: true},
+		},
+		{
+			name: "klusterletconfig takes precedence over global policy",
+			data: map[string]string{
+				"bootstrapTokenLifetime":         "168h",
+				"bootstrapTokenRefreshThreshold": "48h",
+			},
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "short",
+			},
+			expectedPolicy: BootstrapTokenPolicy{Lifetime: 24 * time.Hour, RefreshThreshold: 2 * time.Hour,
+				LifetimeSpecified: true},
+		},
+		{
+			name: "invalid klusterletconfig values are ignored",
+			data: map[string]string{
+				"bootstrapTokenLifetime": "168h",
+			},
+			annotations: map[string]string{
+				"agent.open-cluster-management.io/klusterlet-config": "invalid",
+			},
+			expectedPolicy: BootstrapTokenPolicy{Lifetime: 168 * time.Hour, LifetimeSpecified: true},
+		},
+		{
+			name: "refresh threshold is not less than lifetime",
+			data: map[string]string{
+				"bootstrapTokenLifetime":         "1h",
+				"bootstrapTokenRefreshThreshold": "2h",
+			},
+			expectedPolicy: BootstrapTokenPolicy{Lifetime: time.Hour, LifetimeSpecified: true},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			kubeClient := kubefake.NewSimpleClientset()
+			kubeInformerFactory := informers.NewSharedInformerFactory(kubeClient, 10*time.Minute)
+			if c.data != nil {
+				kubeInformerFactory.Core().V1().ConfigMaps().Informer().GetStore().Add(&corev1.ConfigMap{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      "import-controller-config",
+						Namespace: "test",
+					},
+					Data: c.data,
+				})
+			}
+			controllerConfig := NewImportControllerConfig("test",
+				kubeInformerFactory.Core().V1().ConfigMaps().Lister(), logf.Log.WithName("import-controller-config"))
+
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "cluster1",
+					Annotations: c.annotations,
+				},
+			}
+			policy, err := controllerConfig.GetBootstrapTokenPolicy(cluster, lister)
+			if err != nil {
+				t.Errorf("unexpected err %v", err)
+			}
+			if policy != c.expectedPolicy {
+				t.Errorf("expect %v, but got %v", c.expectedPolicy, policy)
+			}
 		})
 	}
 }
@@ -0,0 +1,90 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// GetImportPriority returns the import priority of the managed cluster. The priority is specified by the
+// import priority annotation, the self managed cluster has a higher default priority than the other clusters.
+// The invalid annotation value is ignored.
+func GetImportPriority(cluster *clusterv1.ManagedCluster) int {
+	if val, ok := cluster.Annotations[constants.ImportPriorityAnnotation]; ok {
+		if priority, err := strconv.Atoi(strings.TrimSpace(val)); err == nil {
+			return priority
+		}
+	}
+
+	if strings.EqualFold(cluster.Labels[constants.SelfManagedLabel], constants.LabelValueTrue) {
+		return constants.DefaultSelfManagedClusterImportPriority
+	}
+	return constants.DefaultImportPriority
+}
+
+// NewImportPriorityQueueFunc returns a func to build the workqueue of the controllers that import the managed
+// clusters. The workqueue takes the request name as the managed cluster name, and processes the requests of the
+// clusters with higher import priority first when it is backlogged.
+func NewImportPriorityQueueFunc(reader client.Reader) func(string,
+	workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+	return func(controllerName string,
+		rateLimiter workqueue.TypedRateLimiter[reconcile.Request]) workqueue.TypedRateLimitingInterface[reconcile.Request] {
+		return &importPriorityQueue{
+			PriorityQueue: priorityqueue.New(controllerName, func(o *priorityqueue.Opts[reconcile.Request]) {
+				o.RateLimiter = rateLimiter
+			}),
+			getPriority: func(clusterName string) int {
+				cluster := &clusterv1.ManagedCluster{}
+				if err := reader.Get(context.TODO(), types.NamespacedName{Name: clusterName}, cluster); err != nil {
+					return constants.DefaultImportPriority
+				}
+				return GetImportPriority(cluster)
+			},
+		}
+	}
+}
+
+// importPriorityQueue sets the import priority of the managed cluster to the requests that are added to the
+// queue.
+type importPriorityQueue struct {
+	priorityqueue.PriorityQueue[reconcile.Request]
+	getPriority func(clusterName string) int
+}
+
+func (q *importPriorityQueue) Add(item reconcile.Request) {
+	q.AddWithOpts(priorityqueue.AddOpts{}, item)
+}
+
+func (q *importPriorityQueue) AddAfter(item reconcile.Request, duration time.Duration) {
+	q.AddWithOpts(priorityqueue.AddOpts{After: duration}, item)
+}
+
+func (q *importPriorityQueue) AddRateLimited(item reconcile.Request) {
+	q.AddWithOpts(priorityqueue.AddOpts{RateLimited: true}, item)
+}
+
+func (q *importPriorityQueue) AddWithOpts(o priorityqueue.AddOpts, items ...reconcile.Request) {
+	for _, item := range items {
+		opts := o
+		// the requests of the unchanged objects (e.g. resync) are kept in low priority
+		if opts.Priority == nil || *opts.Priority != handler.LowPriority {
+			opts.Priority = ptr.To(q.getPriority(item.Name))
+		}
+		q.PriorityQueue.AddWithOpts(opts, item)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/controller/priorityqueue"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestGetImportPriority(t *testing.T) {
+	cases := []struct {
+		name             string
+		labels           map[string]string
+		annotations      map[string]string
+		expectedPriority int
+	}{
+		{
+			name:             "default",
+			expectedPriority: 0,
+		},
+		{
+			name:             "local-cluster",
+			labels:           map[string]string{"local-cluster": "true"},
+			expectedPriority: 100,
+		},
+		{
+			name:             "annotation",
+			annotations:      map[string]string{"import.open-cluster-management.io/priority": "50"},
+			expectedPriority: 50,
+		},
+		{
+			name:             "annotation overrides local-cluster",
+			labels:           map[string]string{"local-cluster": "true"},
+			annotations:      map[string]string{"import.open-cluster-management.io/priority": "-10"},
+			expectedPriority: -10,
+		},
+		{
+			name:             "invalid annotation",
+			annotations:      map[string]string{"import.open-cluster-management.io/priority": "high"},
+			expectedPriority: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			cluster := &clusterv1.ManagedCluster{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:        "test",
+					Labels:      c.labels,
+					Annotations: c.annotations,
+				},
+			}
+			if priority := GetImportPriority(cluster); priority != c.expectedPriority {
+				t.Errorf("expected priority %d, but got %d", c.expectedPriority, priority)
+			}
+		})
+	}
+}
+
+func TestImportPriorityQueue(t *testing.T) {
+	newCluster := func(name, priority string) runtime.Object {
+		cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: name}}
+		if len(priority) != 0 {
+			cluster.Annotations = map[string]string{"import.open-cluster-management.io/priority": priority}
+		}
+		return cluster
+	}
+	reader := fake.NewClientBuilder().WithScheme(testscheme).WithRuntimeObjects(
+		newCluster("lab", "-10"),
+		newCluster("default", ""),
+		newCluster("production", "10"),
+	).Build()
+
+	queue := NewImportPriorityQueueFunc(reader)("test",
+		workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+	defer queue.ShutDown()
+
+	request := func(name string) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Name: name}}
+	}
+	queue.Add(request("lab"))
+	queue.Add(request("default"))
+	queue.Add(request("production"))
+	queue.Add(request("unknown"))
+	// the resync request is kept in low priority
+	queue.(priorityqueue.PriorityQueue[reconcile.Request]).AddWithOpts(
+		priorityqueue.AddOpts{Priority: ptr.To(handler.LowPriority)}, request("resync"))
+
+	expected := []struct {
+		name     string
+		priority int
+	}{
+		{name: "production", priority: 10},
+		{name: "default", priority: 0},
+		{name: "unknown", priority: 0},
+		{name: "lab", priority: -10},
+		{name: "resync", priority: handler.LowPriority},
+	}
+	for _, e := range expected {
+		item, priority, _ := queue.(priorityqueue.PriorityQueue[reconcile.Request]).GetWithPriority()
+		if item.Name != e.name || priority != e.priority {
+			t.Errorf("expected %s with priority %d, but got %s with priority %d", e.name, e.priority,
+				item.Name, priority)
+		}
+		queue.Done(item)
+	}
+}
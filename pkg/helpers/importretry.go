@@ -0,0 +1,129 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+var autoImportAttemptsAnnotations = sets.New(
+	constants.AutoImportAttemptsAnnotation,
+	constants.AutoImportNextRetryTimeAnnotation,
+	constants.AutoImportSecretVersionAnnotation,
+)
+
+// ImportRetryPolicy determines how many times a managed cluster is attempted to import with the auto-import
+// secret, and how long to wait between two attempts.
+type ImportRetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// WithAutoImportSecret returns a copy of the policy whose max attempts is overridden by the autoImportRetry
+// of the auto-import secret.
+func (p ImportRetryPolicy) WithAutoImportSecret(secret *corev1.Secret) ImportRetryPolicy {
+	val, ok := secret.Data[constants.AutoImportSecretRetryKey]
+	if !ok {
+		return p
+	}
+
+	retries, err := strconv.Atoi(string(val))
+	if err != nil || retries < 0 {
+		klog.Warningf("the %s of the auto-import secret %s/%s is invalid, using the max attempts (%d)",
+			constants.AutoImportSecretRetryKey, secret.Namespace, secret.Name, p.MaxAttempts)
+		return p
+	}
+
+	// the first attempt is not a retry
+	p.MaxAttempts = retries + 1
+	return p
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts, the delay
+// starts from the base delay and is doubled after each failed attempt until it reaches the max delay.
+func (p ImportRetryPolicy) Backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+	return delay
+}
+
+// Exhausted returns true if there is no attempt left after the given number of failed attempts, the attempts
+// are unlimited if the max attempts is 0.
+func (p ImportRetryPolicy) Exhausted(attempts int) bool {
+	return p.MaxAttempts > 0 && attempts >= p.MaxAttempts
+}
+
+// autoImportSecretVersion identifies a version of the auto-import secret, it is changed once the secret is
+// recreated or updated.
+func autoImportSecretVersion(secret *corev1.Secret) string {
+	return fmt.Sprintf("%s/%s", secret.UID, secret.ResourceVersion)
+}
+
+// GetAutoImportAttempts returns the number of failed attempts and the next retry time recorded on the
+// managed cluster, the invalid records and the records of another version of the auto-import secret are
+// ignored, so the attempts are restarted once the auto-import secret is changed.
+func GetAutoImportAttempts(cluster *clusterv1.ManagedCluster, secret *corev1.Secret) (int, time.Time) {
+	if cluster.Annotations[constants.AutoImportSecretVersionAnnotation] != autoImportSecretVersion(secret) {
+		return 0, time.Time{}
+	}
+
+	attempts, err := strconv.Atoi(cluster.Annotations[constants.AutoImportAttemptsAnnotation])
+	if err != nil || attempts < 0 {
+		return 0, time.Time{}
+	}
+
+	nextRetryTime, err := time.Parse(time.RFC3339, cluster.Annotations[constants.AutoImportNextRetryTimeAnnotation])
+	if err != nil {
+		return attempts, time.Time{}
+	}
+	return attempts, nextRetryTime
+}
+
+// UpdateAutoImportAttempts records the number of failed attempts and the next retry time together with the
+// version of the auto-import secret on the managed cluster, the records are removed if the attempts is 0 and
+// the next retry time is not recorded if it is zero.
+func UpdateAutoImportAttempts(ctx context.Context, runtimeClient client.Client,
+	cluster *clusterv1.ManagedCluster, secret *corev1.Secret, attempts int, nextRetryTime time.Time) error {
+	annotations := map[string]string{}
+	for k, v := range cluster.Annotations {
+		if autoImportAttemptsAnnotations.Has(k) {
+			continue
+		}
+		annotations[k] = v
+	}
+	if attempts > 0 {
+		annotations[constants.AutoImportAttemptsAnnotation] = strconv.Itoa(attempts)
+		annotations[constants.AutoImportSecretVersionAnnotation] = autoImportSecretVersion(secret)
+	}
+	if attempts > 0 && !nextRetryTime.IsZero() {
+		annotations[constants.AutoImportNextRetryTimeAnnotation] = nextRetryTime.UTC().Format(time.RFC3339)
+	}
+
+	if equality.Semantic.DeepEqual(annotations, cluster.Annotations) {
+		return nil
+	}
+
+	cluster = cluster.DeepCopy()
+	patch := client.MergeFrom(cluster.DeepCopy())
+	cluster.Annotations = annotations
+	return runtimeClient.Patch(ctx, cluster, patch)
+}
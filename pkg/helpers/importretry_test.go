@@ -0,0 +1,235 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+	testinghelpers "github.com/stolostron/managedcluster-import-controller/pkg/helpers/testing"
+)
+
+func TestImportRetryPolicyBackoff(t *testing.T) {
+	policy := ImportRetryPolicy{
+		MaxAttempts: 10,
+		BaseDelay:   10 * time.Second,
+		MaxDelay:    time.Minute,
+	}
+
+	cases := []struct {
+		attempts      int
+		expectedDelay time.Duration
+	}{
+		{attempts: 1, expectedDelay: 10 * time.Second},
+		{attempts: 2, expectedDelay: 20 * time.Second},
+		{attempts: 3, expectedDelay: 40 * time.Second},
+		{attempts: 4, expectedDelay: time.Minute},
+		{attempts: 100, expectedDelay: time.Minute},
+	}
+
+	for _, c := range cases {
+		if delay := policy.Backoff(c.attempts); delay != c.expectedDelay {
+			t.Errorf("expected delay %v after %d attempts, but got %v", c.expectedDelay, c.attempts, delay)
+		}
+	}
+}
+
+func TestImportRetryPolicyExhausted(t *testing.T) {
+	cases := []struct {
+		name              string
+		maxAttempts       int
+		attempts          int
+		expectedExhausted bool
+	}{
+		{name: "attempts left", maxAttempts: 3, attempts: 2},
+		{name: "attempts exhausted", maxAttempts: 3, attempts: 3, expectedExhausted: true},
+		{name: "unlimited attempts", maxAttempts: 0, attempts: 100},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			policy := ImportRetryPolicy{MaxAttempts: c.maxAttempts}
+			if exhausted := policy.Exhausted(c.attempts); exhausted != c.expectedExhausted {
+				t.Errorf("expected exhausted %v, but got %v", c.expectedExhausted, exhausted)
+			}
+		})
+	}
+}
+
+func TestImportRetryPolicyWithAutoImportSecret(t *testing.T) {
+	policy := ImportRetryPolicy{MaxAttempts: 10}
+
+	cases := []struct {
+		name                string
+		data                map[string][]byte
+		expectedMaxAttempts int
+	}{
+		{
+			name:                "no retry",
+			data:                map[string][]byte{},
+			expectedMaxAttempts: 10,
+		},
+		{
+			name:                "retry",
+			data:                map[string][]byte{"autoImportRetry": []byte("5")},
+			expectedMaxAttempts: 6,
+		},
+		{
+			name:                "no retry after the first attempt",
+			data:                map[string][]byte{"autoImportRetry": []byte("0")},
+			expectedMaxAttempts: 1,
+		},
+		{
+			name:                "invalid retry",
+			data:                map[string][]byte{"autoImportRetry": []byte("-1")},
+			expectedMaxAttempts: 10,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			actual := policy.WithAutoImportSecret(&corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "auto-import-secret", Namespace: "test"},
+				Data:       c.data,
+			})
+			if actual.MaxAttempts != c.expectedMaxAttempts {
+				t.Errorf("expected max attempts %d, but got %d", c.expectedMaxAttempts, actual.MaxAttempts)
+			}
+			if policy.MaxAttempts != 10 {
+				t.Errorf("expected the policy is not changed, but got %d", policy.MaxAttempts)
+			}
+		})
+	}
+}
+
+func TestUpdateAutoImportAttempts(t *testing.T) {
+	testscheme := scheme.Scheme
+	testscheme.AddKnownTypes(clusterv1.SchemeGroupVersion, &clusterv1.ManagedCluster{})
+
+	nextRetryTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	secret := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid1", ResourceVersion: "1"}}
+
+	cases := []struct {
+		name                  string
+		cluster               *clusterv1.ManagedCluster
+		attempts              int
+		nextRetryTime         time.Time
+		expectedAttempts      int
+		expectedNextRetryTime time.Time
+	}{
+		{
+			name:                  "record the first attempt",
+			cluster:               testinghelpers.NewManagedClusterBuilder("test").Build(),
+			attempts:              1,
+			nextRetryTime:         nextRetryTime,
+			expectedAttempts:      1,
+			expectedNextRetryTime: nextRetryTime,
+		},
+		{
+			name: "record the last attempt",
+			cluster: testinghelpers.NewManagedClusterBuilder("test").
+				WithAnnotations(constants.AutoImportAttemptsAnnotation, "1").
+				WithAnnotations(constants.AutoImportNextRetryTimeAnnotation, nextRetryTime.Format(time.RFC3339)).
+				WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+				Build(),
+			attempts:         2,
+			expectedAttempts: 2,
+		},
+		{
+			name: "restart the attempts",
+			cluster: testinghelpers.NewManagedClusterBuilder("test").
+				WithAnnotations(constants.AutoImportAttemptsAnnotation, "2").
+				WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+				Build(),
+			attempts: 0,
+		},
+		{
+			name: "invalid records",
+			cluster: testinghelpers.NewManagedClusterBuilder("test").
+				WithAnnotations(constants.AutoImportAttemptsAnnotation, "invalid").
+				WithAnnotations(constants.AutoImportNextRetryTimeAnnotation, "invalid").
+				Build(),
+			attempts: 0,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			runtimeClient := fake.NewClientBuilder().WithScheme(testscheme).WithObjects(c.cluster).Build()
+
+			if err := UpdateAutoImportAttempts(context.TODO(), runtimeClient, c.cluster, secret,
+				c.attempts, c.nextRetryTime); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			cluster := &clusterv1.ManagedCluster{}
+			if err := runtimeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			attempts, nextRetryTime := GetAutoImportAttempts(cluster, secret)
+			if attempts != c.expectedAttempts {
+				t.Errorf("expected attempts %d, but got %d", c.expectedAttempts, attempts)
+			}
+			if !nextRetryTime.Equal(c.expectedNextRetryTime) {
+				t.Errorf("expected next retry time %v, but got %v", c.expectedNextRetryTime, nextRetryTime)
+			}
+			if c.attempts == 0 && len(cluster.Annotations) != 0 {
+				t.Errorf("expected no annotations, but got %v", cluster.Annotations)
+			}
+		})
+	}
+}
+
+func TestGetAutoImportAttempts(t *testing.T) {
+	nextRetryTime := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	cluster := testinghelpers.NewManagedClusterBuilder("test").
+		WithAnnotations(constants.AutoImportAttemptsAnnotation, "3").
+		WithAnnotations(constants.AutoImportNextRetryTimeAnnotation, nextRetryTime.Format(time.RFC3339)).
+		WithAnnotations(constants.AutoImportSecretVersionAnnotation, "uid1/1").
+		Build()
+
+	cases := []struct {
+		name                  string
+		secret                *corev1.Secret
+		expectedAttempts      int
+		expectedNextRetryTime time.Time
+	}{
+		{
+			name:                  "same auto-import secret",
+			secret:                &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid1", ResourceVersion: "1"}},
+			expectedAttempts:      3,
+			expectedNextRetryTime: nextRetryTime,
+		},
+		{
+			name:   "auto-import secret is updated",
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid1", ResourceVersion: "2"}},
+		},
+		{
+			name:   "auto-import secret is recreated",
+			secret: &corev1.Secret{ObjectMeta: metav1.ObjectMeta{UID: "uid2", ResourceVersion: "1"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			attempts, nextRetryTime := GetAutoImportAttempts(cluster, c.secret)
+			if attempts != c.expectedAttempts {
+				t.Errorf("expected attempts %d, but got %d", c.expectedAttempts, attempts)
+			}
+			if !nextRetryTime.Equal(c.expectedNextRetryTime) {
+				t.Errorf("expected next retry time %v, but got %v", c.expectedNextRetryTime, nextRetryTime)
+			}
+		})
+	}
+}
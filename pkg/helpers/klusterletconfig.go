@@ -31,3 +31,36 @@ func GetMergedKlusterletConfigWithGlobal(
 	// The object get from a lister should be be modified directly.
 	return klusterletconfighelper.MergeKlusterletConfigs(globalKlusterletConfig.DeepCopy(), kc.DeepCopy())
 }
+
+// GetKlusterletConfigAnnotation returns the value of the annotation on the given klusterletconfig, the value
+// on the global klusterletconfig is returned if the annotation is not found on the given klusterletconfig.
+// The annotations are used for the settings that are not supported by the KlusterletConfig spec yet, they are
+// expected to be replaced with the spec fields once the KlusterletConfig API of the cluster-lifecycle-api gains them.
+func GetKlusterletConfigAnnotation(
+	klusterletconfigName, key string,
+	kcLister listerklusterletconfigv1alpha1.KlusterletConfigLister,
+) (string, error) {
+	if kcLister == nil {
+		return "", nil
+	}
+
+	for _, name := range []string{klusterletconfigName, constants.GlobalKlusterletConfigName} {
+		if name == "" {
+			continue
+		}
+
+		kc, err := kcLister.Get(name)
+		if apierrors.IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to get klusterletconfig %s: %v", name, err)
+		}
+
+		if val, ok := kc.Annotations[key]; ok {
+			return val, nil
+		}
+	}
+
+	return "", nil
+}
@@ -0,0 +1,166 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// supportedKlusterletResources are the resources that can be requested by the klusterlet operator and agents
+var supportedKlusterletResources = map[corev1.ResourceName]bool{
+	corev1.ResourceCPU:              true,
+	corev1.ResourceMemory:           true,
+	corev1.ResourceEphemeralStorage: true,
+}
+
+// ParseResourceRequirements parses the resource requirements in JSON or YAML format, nil is returned if the value
+// is empty.
+func ParseResourceRequirements(val string) (*corev1.ResourceRequirements, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+
+	resources := &corev1.ResourceRequirements{}
+	if err := yaml.UnmarshalStrict([]byte(val), resources); err != nil {
+		return nil, fmt.Errorf("invalid resource requirements %v", err)
+	}
+	return resources, nil
+}
+
+// ParseReplicaCount parses the replica count, nil is returned if the value is empty.
+func ParseReplicaCount(val string) (*int, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+
+	replicas, err := strconv.Atoi(strings.TrimSpace(val))
+	if err != nil {
+		return nil, fmt.Errorf("invalid replica count %v", err)
+	}
+	return &replicas, nil
+}
+
+// GetResourcesFromManagedClusterAnnotations returns the klusterlet resource requirements that are specified with
+// the managed cluster annotation, nil is returned if the annotation is not set.
+func GetResourcesFromManagedClusterAnnotations(clusterAnnotations map[string]string) (
+	*corev1.ResourceRequirements, error) {
+	resources, err := ParseResourceRequirements(clusterAnnotations[klusterletResourcesAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid klusterlet resources annotation %v", err)
+	}
+	return resources, nil
+}
+
+// GetReplicaCountFromManagedClusterAnnotations returns the klusterlet operator replica count that is specified
+// with the managed cluster annotation, nil is returned if the annotation is not set.
+func GetReplicaCountFromManagedClusterAnnotations(clusterAnnotations map[string]string) (*int, error) {
+	replicas, err := ParseReplicaCount(clusterAnnotations[klusterletReplicasAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid klusterlet replicas annotation %v", err)
+	}
+	return replicas, nil
+}
+
+// ValidateResourceRequirements validates the klusterlet resource requirements, only the cpu, memory and
+// ephemeral-storage can be requested, and the requests cannot be greater than the limits.
+func ValidateResourceRequirements(resources corev1.ResourceRequirements) error {
+	errs := []error{}
+	for _, list := range []struct {
+		name      string
+		resources corev1.ResourceList
+	}{
+		{name: "limits", resources: resources.Limits},
+		{name: "requests", resources: resources.Requests},
+	} {
+		for _, name := range sortedResourceNames(list.resources) {
+			quantity := list.resources[name]
+			if !supportedKlusterletResources[name] {
+				errs = append(errs, fmt.Errorf("%s: unsupported resource %s", list.name, name))
+				continue
+			}
+			if quantity.Sign() < 0 {
+				errs = append(errs, fmt.Errorf("%s: %s must be greater than or equal to 0", list.name, name))
+			}
+		}
+	}
+
+	for _, name := range sortedResourceNames(resources.Requests) {
+		request := resources.Requests[name]
+		limit, ok := resources.Limits[name]
+		if ok && request.Cmp(limit) > 0 {
+			errs = append(errs, fmt.Errorf("requests: %s %s must be less than or equal to the limit %s",
+				name, request.String(), limit.String()))
+		}
+	}
+
+	if len(resources.Claims) != 0 {
+		errs = append(errs, fmt.Errorf("claims are not supported"))
+	}
+	return utilerrors.NewAggregate(errs)
+}
+
+// ValidateReplicaCount validates the klusterlet operator replica count
+func ValidateReplicaCount(replicas int) error {
+	if replicas < 1 {
+		return fmt.Errorf("the replica count %d must be greater than or equal to 1", replicas)
+	}
+	return nil
+}
+
+// NewKlusterletSettingsInvalidCondition returns the KlusterletSettingsValid condition with the error of the invalid
+// klusterlet settings
+func NewKlusterletSettingsInvalidCondition(err error) metav1.Condition {
+	return metav1.Condition{
+		Type:    constants.ConditionKlusterletSettingsValid,
+		Status:  metav1.ConditionFalse,
+		Reason:  constants.ConditionReasonKlusterletSettingsInvalid,
+		Message: fmt.Sprintf("The import.yaml is not updated: %v", err),
+	}
+}
+
+// UpdateManagedClusterSettingsCondition updates the KlusterletSettingsValid condition of the managed cluster, and
+// records an event once the settings become invalid.
+func UpdateManagedClusterSettingsCondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
+	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	if cond.Type != constants.ConditionKlusterletSettingsValid {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	changed, err := updateManagedClusterStatus(client, managedCluster.Name, cond)
+	if err != nil {
+		return err
+	}
+	if !changed || cond.Status != metav1.ConditionFalse {
+		return nil
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+		constants.EventReasonKlusterletSettingsInvalid, constants.EventReasonKlusterletSettingsInvalid,
+		"%s", cond.Message)
+	return nil
+}
+
+func sortedResourceNames(resources corev1.ResourceList) []corev1.ResourceName {
+	names := make([]corev1.ResourceName, 0, len(resources))
+	for name := range resources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return names[i] < names[j] })
+	return names
+}
@@ -0,0 +1,157 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestParseResourceRequirements(t *testing.T) {
+	cases := []struct {
+		name        string
+		val         string
+		expectedNil bool
+		expectedErr bool
+	}{
+		{
+			name:        "empty",
+			expectedNil: true,
+		},
+		{
+			name: "json",
+			val:  `{"limits":{"cpu":"1","memory":"4Gi"},"requests":{"cpu":"10m","memory":"32Mi"}}`,
+		},
+		{
+			name: "yaml",
+			val:  "requests:\n  cpu: 10m\n",
+		},
+		{
+			name:        "invalid quantity",
+			val:         `{"requests":{"cpu":"ten"}}`,
+			expectedErr: true,
+		},
+		{
+			name:        "unknown field",
+			val:         `{"request":{"cpu":"10m"}}`,
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resources, err := ParseResourceRequirements(c.val)
+			if (err != nil) != c.expectedErr {
+				t.Fatalf("expected error %v, but got %v", c.expectedErr, err)
+			}
+			if c.expectedErr {
+				return
+			}
+			if (resources == nil) != c.expectedNil {
+				t.Errorf("expected nil %v, but got %v", c.expectedNil, resources)
+			}
+		})
+	}
+}
+
+func TestParseReplicaCount(t *testing.T) {
+	replicas, err := ParseReplicaCount("")
+	if err != nil || replicas != nil {
+		t.Errorf("expected no replica count, but got %v, %v", replicas, err)
+	}
+
+	replicas, err = ParseReplicaCount(" 3 ")
+	if err != nil || replicas == nil || *replicas != 3 {
+		t.Errorf("expected replica count 3, but got %v, %v", replicas, err)
+	}
+
+	if _, err := ParseReplicaCount("three"); err == nil {
+		t.Errorf("expected error, but got nil")
+	}
+}
+
+func TestValidateResourceRequirements(t *testing.T) {
+	cases := []struct {
+		name        string
+		resources   corev1.ResourceRequirements
+		expectedErr bool
+	}{
+		{
+			name: "valid",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("1"),
+					corev1.ResourceMemory: resource.MustParse("32Mi"),
+				},
+			},
+		},
+		{
+			name: "requests without limits",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceEphemeralStorage: resource.MustParse("1Gi"),
+				},
+			},
+		},
+		{
+			name: "unsupported resource",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					"nvidia.com/gpu": resource.MustParse("1"),
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "negative quantity",
+			resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceCPU: resource.MustParse("-1"),
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "requests greater than limits",
+			resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("1Gi"),
+				},
+				Requests: corev1.ResourceList{
+					corev1.ResourceMemory: resource.MustParse("2Gi"),
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "claims",
+			resources: corev1.ResourceRequirements{
+				Claims: []corev1.ResourceClaim{{Name: "test"}},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateResourceRequirements(c.resources)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+
+	if err := ValidateReplicaCount(0); err == nil {
+		t.Errorf("expected error for 0 replicas, but got nil")
+	}
+	if err := ValidateReplicaCount(1); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// NewKlusterletManifestsPatchedCondition returns the KlusterletManifestsPatched condition with the result of the
+// klusterlet manifest patches
+func NewKlusterletManifestsPatchedCondition(err error) metav1.Condition {
+	if err != nil {
+		return metav1.Condition{
+			Type:    constants.ConditionKlusterletManifestsPatched,
+			Status:  metav1.ConditionFalse,
+			Reason:  constants.ConditionReasonKlusterletManifestsPatchFailed,
+			Message: fmt.Sprintf("The import.yaml is not updated: %v", err),
+		}
+	}
+
+	return metav1.Condition{
+		Type:    constants.ConditionKlusterletManifestsPatched,
+		Status:  metav1.ConditionTrue,
+		Reason:  constants.ConditionReasonKlusterletManifestsPatchApplied,
+		Message: "The klusterlet manifest patches are applied to the import.yaml",
+	}
+}
+
+// UpdateManagedClusterPatchCondition updates the KlusterletManifestsPatched condition of the managed cluster, and
+// records an event once the patches fail.
+func UpdateManagedClusterPatchCondition(client client.Client, managedCluster *clusterv1.ManagedCluster,
+	cond metav1.Condition, recorder kevents.EventRecorder) error {
+	if cond.Type != constants.ConditionKlusterletManifestsPatched {
+		return fmt.Errorf("the condition type %s is not supported", cond.Type)
+	}
+
+	changed, err := updateManagedClusterStatus(client, managedCluster.Name, cond)
+	if err != nil {
+		return err
+	}
+	if !changed || cond.Status != metav1.ConditionFalse {
+		return nil
+	}
+
+	mc := managedCluster.DeepCopy()
+	mc.SetNamespace(mc.Name)
+	recorder.Eventf(mc, nil, corev1.EventTypeWarning,
+		constants.EventReasonKlusterletManifestsPatchFailed, constants.EventReasonKlusterletManifestsPatchFailed,
+		"%s", cond.Message)
+	return nil
+}
+
+// RemoveManagedClusterCondition removes the condition of the given type from the managed cluster status
+func RemoveManagedClusterCondition(client client.Client, managedClusterName, condType string) error {
+	managedCluster := &clusterv1.ManagedCluster{}
+	if err := client.Get(context.TODO(), types.NamespacedName{Name: managedClusterName}, managedCluster); err != nil {
+		return err
+	}
+
+	if !meta.RemoveStatusCondition(&managedCluster.Status.Conditions, condType) {
+		return nil
+	}
+	return client.Status().Update(context.TODO(), managedCluster)
+}
@@ -0,0 +1,80 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	kevents "k8s.io/client-go/tools/events"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestUpdateManagedClusterPatchCondition(t *testing.T) {
+	managedCluster := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test",
+		},
+	}
+	fakeClient := fake.NewClientBuilder().WithScheme(testscheme).
+		WithObjects(managedCluster).WithStatusSubresource(managedCluster).Build()
+	recorder := kevents.NewFakeRecorder(10)
+
+	getCondition := func() *metav1.Condition {
+		cluster := &clusterv1.ManagedCluster{}
+		if err := fakeClient.Get(context.TODO(), types.NamespacedName{Name: "test"}, cluster); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+		return meta.FindStatusCondition(cluster.Status.Conditions, constants.ConditionKlusterletManifestsPatched)
+	}
+
+	failed := NewKlusterletManifestsPatchedCondition(fmt.Errorf("the target is not found"))
+	if err := UpdateManagedClusterPatchCondition(fakeClient, managedCluster, failed, recorder); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cond := getCondition(); cond == nil || cond.Status != metav1.ConditionFalse ||
+		cond.Reason != constants.ConditionReasonKlusterletManifestsPatchFailed ||
+		cond.Message != "The import.yaml is not updated: the target is not found" {
+		t.Errorf("unexpected condition %v", cond)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected a warning event, but got %d events", len(recorder.Events))
+	}
+
+	// the event is not recorded again if the condition is not changed
+	if err := UpdateManagedClusterPatchCondition(fakeClient, managedCluster, failed, recorder); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(recorder.Events) != 1 {
+		t.Errorf("expected no more events, but got %d events", len(recorder.Events))
+	}
+
+	applied := NewKlusterletManifestsPatchedCondition(nil)
+	if err := UpdateManagedClusterPatchCondition(fakeClient, managedCluster, applied, recorder); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cond := getCondition(); cond == nil || cond.Status != metav1.ConditionTrue {
+		t.Errorf("unexpected condition %v", cond)
+	}
+
+	if err := RemoveManagedClusterCondition(fakeClient, "test",
+		constants.ConditionKlusterletManifestsPatched); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if cond := getCondition(); cond != nil {
+		t.Errorf("expected the condition is removed, but got %v", cond)
+	}
+
+	if err := UpdateManagedClusterPatchCondition(fakeClient, managedCluster,
+		NewKlusterletDriftedCondition(nil, nil), recorder); err == nil {
+		t.Errorf("expected error for the unsupported condition type")
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// OIDCTokenGetter requests an access token from an OIDC provider with the OAuth2 client credentials flow,
+// the token is cached and only requested again after it expires.
+type OIDCTokenGetter struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	scopes       []string
+
+	tokenSource oauth2.TokenSource
+}
+
+func NewOIDCTokenGetter() *OIDCTokenGetter {
+	return &OIDCTokenGetter{}
+}
+
+func (g *OIDCTokenGetter) SetTokenURL(tokenURL string) {
+	if g.tokenURL != tokenURL {
+		g.tokenURL = tokenURL
+		g.tokenSource = nil
+	}
+}
+
+func (g *OIDCTokenGetter) SetClientID(clientID string) {
+	if g.clientID != clientID {
+		g.clientID = clientID
+		g.tokenSource = nil
+	}
+}
+
+func (g *OIDCTokenGetter) SetClientSecret(clientSecret string) {
+	if g.clientSecret != clientSecret {
+		g.clientSecret = clientSecret
+		g.tokenSource = nil
+	}
+}
+
+// SetScope sets the requested scopes, multiple scopes are separated by spaces
+func (g *OIDCTokenGetter) SetScope(scope string) {
+	scopes := strings.Fields(scope)
+	if strings.Join(g.scopes, " ") != strings.Join(scopes, " ") {
+		g.scopes = scopes
+		g.tokenSource = nil
+	}
+}
+
+// Token returns a valid access token, a new token is requested if there is no cached token or the
+// cached token is expired.
+func (g *OIDCTokenGetter) Token() (string, error) {
+	if g.tokenSource == nil {
+		config := &clientcredentials.Config{
+			ClientID:     g.clientID,
+			ClientSecret: g.clientSecret,
+			TokenURL:     g.tokenURL,
+			Scopes:       g.scopes,
+		}
+		g.tokenSource = oauth2.ReuseTokenSource(nil, config.TokenSource(context.Background()))
+	}
+
+	token, err := g.tokenSource.Token()
+	if err != nil {
+		return "", fmt.Errorf("failed to request the access token from %s: %v", g.tokenURL, err)
+	}
+
+	return token.AccessToken, nil
+}
+
+// GenerateImportClientFromOIDCSecret generate a client from a given secret that contains kube apiserver and
+// oidc client credentials, the access token is requested with the client credentials flow.
+func GenerateImportClientFromOIDCSecret(getter *OIDCTokenGetter,
+	secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+	required := []string{
+		constants.AutoImportSecretOIDCServerKey,
+		constants.AutoImportSecretOIDCTokenURLKey,
+		constants.AutoImportSecretOIDCClientIDKey,
+		constants.AutoImportSecretOIDCClientSecretKey,
+	}
+	for _, key := range required {
+		if len(secret.Data[key]) == 0 {
+			return reconcile.Result{}, nil, nil, fmt.Errorf("%s is missing", key)
+		}
+	}
+
+	getter.SetTokenURL(string(secret.Data[constants.AutoImportSecretOIDCTokenURLKey]))
+	getter.SetClientID(string(secret.Data[constants.AutoImportSecretOIDCClientIDKey]))
+	getter.SetClientSecret(string(secret.Data[constants.AutoImportSecretOIDCClientSecretKey]))
+	getter.SetScope(string(secret.Data[constants.AutoImportSecretOIDCScopeKey]))
+
+	token, err := getter.Token()
+	if err != nil {
+		return reconcile.Result{}, nil, nil, err
+	}
+
+	return buildImportClient(buildKubeConfigFileWithTokenAndCA(
+		string(secret.Data[constants.AutoImportSecretOIDCServerKey]),
+		token,
+		secret.Data[constants.AutoImportSecretOIDCCAKey],
+	))
+}
@@ -0,0 +1,186 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newOIDCTestServer(t *testing.T, tokenRequests *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var obj interface{}
+		switch req.URL.Path {
+		case "/token":
+			*tokenRequests++
+			if err := req.ParseForm(); err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			clientID, clientSecret, ok := req.BasicAuth()
+			if !ok {
+				clientID = req.PostForm.Get("client_id")
+				clientSecret = req.PostForm.Get("client_secret")
+			}
+			if clientID != "import" || clientSecret != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if req.PostForm.Get("grant_type") != "client_credentials" {
+				t.Fatalf("unexpected grant type %s", req.PostForm.Get("grant_type"))
+			}
+			obj = map[string]interface{}{
+				"access_token": "access-token-" + strings.ReplaceAll(req.PostForm.Get("scope"), " ", "-"),
+				"token_type":   "Bearer",
+				"expires_in":   3600,
+			}
+		case "/api":
+			obj = &metav1.APIVersions{
+				Versions: []string{
+					"v1",
+				},
+			}
+		case "/apis":
+			obj = &metav1.APIGroupList{}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		output, err := json.Marshal(obj)
+		if err != nil {
+			t.Fatalf("unexpected encoding error: %v", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(output)
+	}))
+}
+
+func TestOIDCTokenGetter(t *testing.T) {
+	tokenRequests := 0
+	server := newOIDCTestServer(t, &tokenRequests)
+	defer server.Close()
+
+	getter := NewOIDCTokenGetter()
+	getter.SetTokenURL(server.URL + "/token")
+	getter.SetClientID("import")
+	getter.SetClientSecret("secret")
+	getter.SetScope("openid  cluster-admin")
+
+	token, err := getter.Token()
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if token != "access-token-openid-cluster-admin" {
+		t.Errorf("unexpected token %s", token)
+	}
+
+	// the token is cached
+	if _, err := getter.Token(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected 1 token request, but got %d", tokenRequests)
+	}
+
+	// the same scope does not reset the cached token
+	getter.SetScope("openid cluster-admin")
+	if _, err := getter.Token(); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Errorf("expected 1 token request, but got %d", tokenRequests)
+	}
+
+	// the changed credentials reset the cached token
+	getter.SetClientSecret("invalid")
+	if _, err := getter.Token(); err == nil {
+		t.Errorf("expected error, but failed")
+	}
+	if tokenRequests < 2 {
+		t.Errorf("expected a new token request, but got %d", tokenRequests)
+	}
+}
+
+func TestGenerateImportClientFromOIDCSecret(t *testing.T) {
+	tokenRequests := 0
+	server := newOIDCTestServer(t, &tokenRequests)
+	defer server.Close()
+
+	cases := []struct {
+		name           string
+		data           map[string][]byte
+		expectedErrMsg string
+	}{
+		{
+			name: "missing token url",
+			data: map[string][]byte{
+				"server":        []byte(server.URL),
+				"client_id":     []byte("import"),
+				"client_secret": []byte("secret"),
+			},
+			expectedErrMsg: "token_url is missing",
+		},
+		{
+			name: "missing client secret",
+			data: map[string][]byte{
+				"server":    []byte(server.URL),
+				"token_url": []byte(server.URL + "/token"),
+				"client_id": []byte("import"),
+			},
+			expectedErrMsg: "client_secret is missing",
+		},
+		{
+			name: "invalid client",
+			data: map[string][]byte{
+				"server":        []byte(server.URL),
+				"token_url":     []byte(server.URL + "/token"),
+				"client_id":     []byte("import"),
+				"client_secret": []byte("invalid"),
+			},
+			expectedErrMsg: "failed to request the access token from " + server.URL + "/token",
+		},
+		{
+			name: "generate client",
+			data: map[string][]byte{
+				"server":        []byte(server.URL),
+				"token_url":     []byte(server.URL + "/token"),
+				"client_id":     []byte("import"),
+				"client_secret": []byte("secret"),
+				"scope":         []byte("openid"),
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			_, clientHolder, _, err := GenerateImportClientFromOIDCSecret(NewOIDCTokenGetter(), &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{
+					Name: "auto-import-secret",
+				},
+				Type: "auto-import/oidc",
+				Data: c.data,
+			})
+			if len(c.expectedErrMsg) != 0 {
+				if err == nil || !strings.HasPrefix(err.Error(), c.expectedErrMsg) {
+					t.Errorf("expected error %q, but got %v", c.expectedErrMsg, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if clientHolder == nil {
+				t.Errorf("expected client holder, but got nil")
+			}
+		})
+	}
+}
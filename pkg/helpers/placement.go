@@ -0,0 +1,241 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/yaml"
+)
+
+// ParseAffinity parses the affinity in JSON or YAML format, nil is returned if the value is empty.
+func ParseAffinity(val string) (*corev1.Affinity, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+
+	affinity := &corev1.Affinity{}
+	if err := yaml.UnmarshalStrict([]byte(val), affinity); err != nil {
+		return nil, fmt.Errorf("invalid affinity %v", err)
+	}
+	return affinity, nil
+}
+
+// ParseTopologySpreadConstraints parses the list of the topology spread constraints in JSON or YAML format.
+func ParseTopologySpreadConstraints(val string) ([]corev1.TopologySpreadConstraint, error) {
+	if len(strings.TrimSpace(val)) == 0 {
+		return nil, nil
+	}
+
+	constraints := []corev1.TopologySpreadConstraint{}
+	if err := yaml.UnmarshalStrict([]byte(val), &constraints); err != nil {
+		return nil, fmt.Errorf("invalid topologySpreadConstraints %v", err)
+	}
+	return constraints, nil
+}
+
+func GetAffinityFromManagedClusterAnnotations(clusterAnnotations map[string]string) (*corev1.Affinity, error) {
+	affinity, err := ParseAffinity(clusterAnnotations[affinityAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid affinity annotation %v", err)
+	}
+	return affinity, nil
+}
+
+func GetTopologySpreadConstraintsFromManagedClusterAnnotations(
+	clusterAnnotations map[string]string) ([]corev1.TopologySpreadConstraint, error) {
+	constraints, err := ParseTopologySpreadConstraints(clusterAnnotations[topologySpreadConstraintsAnnotation])
+	if err != nil {
+		return nil, fmt.Errorf("invalid topologySpreadConstraints annotation %v", err)
+	}
+	return constraints, nil
+}
+
+// refer to https://github.com/kubernetes/kubernetes/blob/master/pkg/apis/core/validation/validation.go#L4330
+func ValidateAffinity(affinity *corev1.Affinity) error {
+	if affinity == nil {
+		return nil
+	}
+
+	errs := []error{}
+	if nodeAffinity := affinity.NodeAffinity; nodeAffinity != nil {
+		if required := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution; required != nil {
+			if len(required.NodeSelectorTerms) == 0 {
+				errs = append(errs, fmt.Errorf("nodeAffinity: must have at least one node selector term"))
+			}
+			for _, term := range required.NodeSelectorTerms {
+				errs = append(errs, validateNodeSelectorTerm(term)...)
+			}
+		}
+		for _, preferred := range nodeAffinity.PreferredDuringSchedulingIgnoredDuringExecution {
+			errs = append(errs, validateWeight(preferred.Weight)...)
+			errs = append(errs, validateNodeSelectorTerm(preferred.Preference)...)
+		}
+	}
+
+	if podAffinity := affinity.PodAffinity; podAffinity != nil {
+		errs = append(errs, validatePodAffinityTerms("podAffinity",
+			podAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			podAffinity.PreferredDuringSchedulingIgnoredDuringExecution)...)
+	}
+	if podAntiAffinity := affinity.PodAntiAffinity; podAntiAffinity != nil {
+		errs = append(errs, validatePodAffinityTerms("podAntiAffinity",
+			podAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution,
+			podAntiAffinity.PreferredDuringSchedulingIgnoredDuringExecution)...)
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+// refer to https://github.com/kubernetes/kubernetes/blob/master/pkg/apis/core/validation/validation.go#L7530
+func ValidateTopologySpreadConstraints(constraints []corev1.TopologySpreadConstraint) error {
+	errs := []error{}
+	existing := map[string]bool{}
+	for _, constraint := range constraints {
+		if constraint.MaxSkew <= 0 {
+			errs = append(errs, fmt.Errorf("maxSkew must be greater than 0"))
+		}
+
+		if len(constraint.TopologyKey) == 0 {
+			errs = append(errs, fmt.Errorf("topologyKey can not be empty"))
+		} else if errMsgs := validation.IsQualifiedName(constraint.TopologyKey); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("%s", strings.Join(errMsgs, ";")))
+		}
+
+		switch constraint.WhenUnsatisfiable {
+		case corev1.DoNotSchedule, corev1.ScheduleAnyway:
+		default:
+			errs = append(errs, fmt.Errorf("the whenUnsatisfiable %q is not supported", constraint.WhenUnsatisfiable))
+		}
+
+		key := fmt.Sprintf("%s/%s", constraint.TopologyKey, constraint.WhenUnsatisfiable)
+		if existing[key] {
+			errs = append(errs, fmt.Errorf("the pair of topologyKey %q and whenUnsatisfiable %q is duplicated",
+				constraint.TopologyKey, constraint.WhenUnsatisfiable))
+		}
+		existing[key] = true
+
+		if constraint.MinDomains != nil {
+			if *constraint.MinDomains <= 0 {
+				errs = append(errs, fmt.Errorf("minDomains must be greater than 0"))
+			}
+			if constraint.WhenUnsatisfiable != corev1.DoNotSchedule {
+				errs = append(errs, fmt.Errorf("minDomains can only be set when whenUnsatisfiable is DoNotSchedule"))
+			}
+		}
+
+		for _, policy := range []*corev1.NodeInclusionPolicy{constraint.NodeAffinityPolicy, constraint.NodeTaintsPolicy} {
+			if policy != nil && *policy != corev1.NodeInclusionPolicyHonor &&
+				*policy != corev1.NodeInclusionPolicyIgnore {
+				errs = append(errs, fmt.Errorf("the node inclusion policy %q is not supported", *policy))
+			}
+		}
+
+		if constraint.LabelSelector != nil {
+			if _, err := metav1.LabelSelectorAsSelector(constraint.LabelSelector); err != nil {
+				errs = append(errs, fmt.Errorf("invalid labelSelector %v", err))
+			}
+		}
+	}
+
+	return utilerrors.NewAggregate(errs)
+}
+
+func validatePodAffinityTerms(name string, required []corev1.PodAffinityTerm,
+	preferred []corev1.WeightedPodAffinityTerm) []error {
+	errs := []error{}
+	for _, term := range required {
+		errs = append(errs, validatePodAffinityTerm(name, term)...)
+	}
+	for _, term := range preferred {
+		errs = append(errs, validateWeight(term.Weight)...)
+		errs = append(errs, validatePodAffinityTerm(name, term.PodAffinityTerm)...)
+	}
+	return errs
+}
+
+func validateWeight(weight int32) []error {
+	if weight < 1 || weight > 100 {
+		return []error{fmt.Errorf("the weight %d must be in the range 1-100", weight)}
+	}
+	return nil
+}
+
+func validateNodeSelectorTerm(term corev1.NodeSelectorTerm) []error {
+	errs := []error{}
+	for _, req := range term.MatchExpressions {
+		if errMsgs := validation.IsQualifiedName(req.Key); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("%s", strings.Join(errMsgs, ";")))
+		}
+		errs = append(errs, validateNodeSelectorOperator(req)...)
+	}
+
+	for _, req := range term.MatchFields {
+		if req.Key != metav1.ObjectNameField {
+			errs = append(errs, fmt.Errorf("the field %q is not supported, it must be %s",
+				req.Key, metav1.ObjectNameField))
+		}
+		if req.Operator != corev1.NodeSelectorOpIn && req.Operator != corev1.NodeSelectorOpNotIn {
+			errs = append(errs, fmt.Errorf("the operator %q is not supported for the fields", req.Operator))
+		} else if len(req.Values) != 1 {
+			errs = append(errs, fmt.Errorf("a single value must be specified when `operator` is %q for the fields",
+				req.Operator))
+		}
+	}
+	return errs
+}
+
+func validateNodeSelectorOperator(req corev1.NodeSelectorRequirement) []error {
+	switch req.Operator {
+	case corev1.NodeSelectorOpIn, corev1.NodeSelectorOpNotIn:
+		if len(req.Values) == 0 {
+			return []error{fmt.Errorf("values must be specified when `operator` is 'In' or 'NotIn'")}
+		}
+	case corev1.NodeSelectorOpExists, corev1.NodeSelectorOpDoesNotExist:
+		if len(req.Values) > 0 {
+			return []error{fmt.Errorf("values may not be specified when `operator` is 'Exists' or 'DoesNotExist'")}
+		}
+	case corev1.NodeSelectorOpGt, corev1.NodeSelectorOpLt:
+		if len(req.Values) != 1 {
+			return []error{fmt.Errorf("a single value must be specified when `operator` is 'Lt' or 'Gt'")}
+		}
+		if _, err := strconv.ParseInt(req.Values[0], 10, 64); err != nil {
+			return []error{fmt.Errorf("the value %q must be an integer when `operator` is 'Lt' or 'Gt'",
+				req.Values[0])}
+		}
+	default:
+		return []error{fmt.Errorf("the operator %q is not supported", req.Operator)}
+	}
+	return nil
+}
+
+func validatePodAffinityTerm(name string, term corev1.PodAffinityTerm) []error {
+	errs := []error{}
+	if len(term.TopologyKey) == 0 {
+		errs = append(errs, fmt.Errorf("%s: topologyKey can not be empty", name))
+	} else if errMsgs := validation.IsQualifiedName(term.TopologyKey); len(errMsgs) != 0 {
+		errs = append(errs, fmt.Errorf("%s: %s", name, strings.Join(errMsgs, ";")))
+	}
+
+	for _, selector := range []*metav1.LabelSelector{term.LabelSelector, term.NamespaceSelector} {
+		if selector == nil {
+			continue
+		}
+		if _, err := metav1.LabelSelectorAsSelector(selector); err != nil {
+			errs = append(errs, fmt.Errorf("%s: invalid selector %v", name, err))
+		}
+	}
+	for _, ns := range term.Namespaces {
+		if errMsgs := validation.IsDNS1123Label(ns); len(errMsgs) != 0 {
+			errs = append(errs, fmt.Errorf("%s: %s", name, strings.Join(errMsgs, ";")))
+		}
+	}
+	return errs
+}
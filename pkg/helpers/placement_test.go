@@ -0,0 +1,274 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func TestGetPlacementFromManagedClusterAnnotations(t *testing.T) {
+	affinity, err := GetAffinityFromManagedClusterAnnotations(map[string]string{
+		affinityAnnotation: `{"nodeAffinity":{"requiredDuringSchedulingIgnoredDuringExecution":{"nodeSelectorTerms":` +
+			`[{"matchExpressions":[{"key":"nvidia.com/gpu.present","operator":"DoesNotExist"}]}]}}}`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if affinity == nil || affinity.NodeAffinity == nil {
+		t.Errorf("expected the node affinity, but got %v", affinity)
+	}
+
+	constraints, err := GetTopologySpreadConstraintsFromManagedClusterAnnotations(map[string]string{
+		topologySpreadConstraintsAnnotation: `[{"maxSkew":1,"topologyKey":"topology.kubernetes.io/zone",` +
+			`"whenUnsatisfiable":"ScheduleAnyway"}]`,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if len(constraints) != 1 {
+		t.Errorf("expected 1 constraint, but got %v", constraints)
+	}
+
+	affinity, err = GetAffinityFromManagedClusterAnnotations(map[string]string{})
+	if err != nil || affinity != nil {
+		t.Errorf("expected no affinity, but got %v, %v", affinity, err)
+	}
+
+	if _, err := GetAffinityFromManagedClusterAnnotations(map[string]string{
+		affinityAnnotation: `{"nodeAfinity":{}}`,
+	}); err == nil {
+		t.Errorf("expected error for the unknown field, but got nil")
+	}
+	if _, err := GetTopologySpreadConstraintsFromManagedClusterAnnotations(map[string]string{
+		topologySpreadConstraintsAnnotation: `{"maxSkew":1}`,
+	}); err == nil {
+		t.Errorf("expected error for the invalid list, but got nil")
+	}
+}
+
+func TestValidateAffinity(t *testing.T) {
+	cases := []struct {
+		name        string
+		affinity    *corev1.Affinity
+		expectedErr bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "valid",
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{
+								MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "nvidia.com/gpu.present", Operator: corev1.NodeSelectorOpDoesNotExist},
+									{Key: "example.com/cores", Operator: corev1.NodeSelectorOpGt, Values: []string{"2"}},
+								},
+							},
+						},
+					},
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+						{
+							Weight: 10,
+							Preference: corev1.NodeSelectorTerm{
+								MatchFields: []corev1.NodeSelectorRequirement{
+									{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"node1"}},
+								},
+							},
+						},
+					},
+				},
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+						{
+							Weight: 100,
+							PodAffinityTerm: corev1.PodAffinityTerm{
+								LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "klusterlet"}},
+								TopologyKey:   "kubernetes.io/hostname",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "empty node selector terms",
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "values with the Exists operator",
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{
+								MatchExpressions: []corev1.NodeSelectorRequirement{
+									{Key: "foo", Operator: corev1.NodeSelectorOpExists, Values: []string{"bar"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid weight",
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					PreferredDuringSchedulingIgnoredDuringExecution: []corev1.PreferredSchedulingTerm{
+						{Weight: 0},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported field",
+			affinity: &corev1.Affinity{
+				NodeAffinity: &corev1.NodeAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+						NodeSelectorTerms: []corev1.NodeSelectorTerm{
+							{
+								MatchFields: []corev1.NodeSelectorRequirement{
+									{Key: "metadata.labels", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}},
+								},
+							},
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no topology key",
+			affinity: &corev1.Affinity{
+				PodAffinity: &corev1.PodAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+						{LabelSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "klusterlet"}}},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "invalid label selector",
+			affinity: &corev1.Affinity{
+				PodAntiAffinity: &corev1.PodAntiAffinity{
+					RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{
+						{
+							LabelSelector: &metav1.LabelSelector{
+								MatchExpressions: []metav1.LabelSelectorRequirement{
+									{Key: "app", Operator: "Equals"},
+								},
+							},
+							TopologyKey: "kubernetes.io/hostname",
+						},
+					},
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateAffinity(c.affinity)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
+
+func TestValidateTopologySpreadConstraints(t *testing.T) {
+	cases := []struct {
+		name        string
+		constraints []corev1.TopologySpreadConstraint
+		expectedErr bool
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name: "valid",
+			constraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       "topology.kubernetes.io/zone",
+					WhenUnsatisfiable: corev1.DoNotSchedule,
+					MinDomains:        ptr.To[int32](2),
+				},
+				{
+					MaxSkew:            1,
+					TopologyKey:        "kubernetes.io/hostname",
+					WhenUnsatisfiable:  corev1.ScheduleAnyway,
+					NodeTaintsPolicy:   ptr.To(corev1.NodeInclusionPolicyHonor),
+					NodeAffinityPolicy: ptr.To(corev1.NodeInclusionPolicyIgnore),
+				},
+			},
+		},
+		{
+			name: "invalid max skew",
+			constraints: []corev1.TopologySpreadConstraint{
+				{TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.DoNotSchedule},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "no topology key",
+			constraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, WhenUnsatisfiable: corev1.DoNotSchedule},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "unsupported whenUnsatisfiable",
+			constraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: "Ignore"},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "duplicated",
+			constraints: []corev1.TopologySpreadConstraint{
+				{MaxSkew: 1, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.DoNotSchedule},
+				{MaxSkew: 2, TopologyKey: "topology.kubernetes.io/zone", WhenUnsatisfiable: corev1.DoNotSchedule},
+			},
+			expectedErr: true,
+		},
+		{
+			name: "minDomains with ScheduleAnyway",
+			constraints: []corev1.TopologySpreadConstraint{
+				{
+					MaxSkew:           1,
+					TopologyKey:       "topology.kubernetes.io/zone",
+					WhenUnsatisfiable: corev1.ScheduleAnyway,
+					MinDomains:        ptr.To[int32](2),
+				},
+			},
+			expectedErr: true,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			err := ValidateTopologySpreadConstraints(c.constraints)
+			if (err != nil) != c.expectedErr {
+				t.Errorf("expected error %v, but got %v", c.expectedErr, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,203 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilerrors "k8s.io/apimachinery/pkg/util/errors"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+var (
+	// rollbackKlusterletDeletionTimeout is how long the rollback waits for the klusterlet operator to clean up the
+	// Klusterlet and remove its finalizer, before the klusterlet operator is deleted by the rollback
+	rollbackKlusterletDeletionTimeout = 30 * time.Second
+
+	// rollbackRequeueInterval is the interval to check whether the pending rollback is able to be continued
+	rollbackRequeueInterval = 2 * time.Second
+)
+
+var (
+	klusterletGroupKind = operatorv1.SchemeGroupVersion.WithKind("Klusterlet").GroupKind()
+	deploymentGroupKind = appsv1.SchemeGroupVersion.WithKind("Deployment").GroupKind()
+)
+
+// IsImportRollbackEnabled returns true if the objects created by a failed import should be deleted from the
+// managed cluster
+func IsImportRollbackEnabled(cluster *clusterv1.ManagedCluster) bool {
+	return strings.EqualFold(cluster.Annotations[constants.ImportRollbackAnnotation], constants.LabelValueTrue)
+}
+
+// importRollback records the objects that do not exist on the managed cluster before an import attempt, so the
+// objects that are created by the attempt can be deleted if the attempt fails. The rollback does not block, it is
+// continued by the following reconciles until it is done.
+type importRollback struct {
+	client  client.Client
+	objs    []*metav1.PartialObjectMetadata
+	existed []bool
+
+	// failureMessage is the failure message of the import attempt
+	failureMessage string
+	// next is the index of the next object to delete, the objects are deleted in the reverse order
+	next    int
+	deleted []string
+	errs    []error
+	// klusterlet is the Klusterlet that is deleted by the rollback but may still exist
+	klusterlet          *metav1.PartialObjectMetadata
+	klusterletDeletedAt time.Time
+}
+
+// newImportRollback records the existence of the objects on the managed cluster. An object is treated as
+// existing if its existence cannot be determined, so it is never deleted by the rollback.
+func newImportRollback(ctx context.Context, runtimeClient client.Client,
+	objs []runtime.Object) (*importRollback, error) {
+	r := &importRollback{client: runtimeClient, deleted: []string{}}
+	for _, obj := range objs {
+		accessor, err := meta.Accessor(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		gvk, err := apiutil.GVKForObject(obj, genericScheme)
+		if err != nil {
+			return nil, err
+		}
+
+		partial := &metav1.PartialObjectMetadata{}
+		partial.SetGroupVersionKind(gvk)
+		partial.SetNamespace(accessor.GetNamespace())
+		partial.SetName(accessor.GetName())
+
+		exists, err := r.exists(ctx, partial)
+		r.objs = append(r.objs, partial)
+		r.existed = append(r.existed, exists || err != nil)
+	}
+	r.next = len(r.objs) - 1
+	return r, nil
+}
+
+// Rollback deletes the objects that are created after the rollback is recorded in the reverse order, and returns
+// true once the rollback is done. The Klusterlet is deleted before the klusterlet operator, the rollback returns
+// false until the Klusterlet is gone, so the operator can clean up the agents and remove the finalizer of the
+// Klusterlet before it is deleted. If the Klusterlet is not gone in time, the rest of the objects are deleted, and
+// the finalizer of the Klusterlet is removed once the klusterlet operator is confirmed to be gone.
+func (r *importRollback) Rollback(ctx context.Context) bool {
+	if r.klusterlet != nil && time.Since(r.klusterletDeletedAt) < rollbackKlusterletDeletionTimeout {
+		if exists, err := r.exists(ctx, r.klusterlet); exists || err != nil {
+			return false
+		}
+		r.klusterlet = nil
+	}
+
+	for ; r.next >= 0; r.next-- {
+		if r.existed[r.next] {
+			continue
+		}
+
+		obj := r.objs[r.next].DeepCopy()
+		err := r.client.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if errors.IsNotFound(err) {
+			// the object is not created by the attempt
+			continue
+		}
+		if err != nil {
+			r.errs = append(r.errs, err)
+			continue
+		}
+		r.deleted = append(r.deleted, formatPartialObjectRef(obj))
+
+		if obj.GroupVersionKind().GroupKind() == klusterletGroupKind {
+			r.klusterlet = obj
+			r.klusterletDeletedAt = time.Now()
+			r.next--
+			return false
+		}
+	}
+
+	if r.klusterlet == nil {
+		return true
+	}
+
+	if r.klusterletOperatorKept() {
+		// the klusterlet operator is not deleted by the rollback, it removes the finalizer of the Klusterlet itself
+		r.klusterlet = nil
+		return true
+	}
+	if !r.klusterletOperatorDeleted(ctx) {
+		return false
+	}
+
+	klog.Infof("The Klusterlet %s is not deleted in %v, remove its finalizers", r.klusterlet.GetName(),
+		rollbackKlusterletDeletionTimeout)
+	err := r.client.Patch(ctx, r.klusterlet.DeepCopy(),
+		client.RawPatch(types.MergePatchType, []byte(`{"metadata":{"finalizers":null}}`)))
+	if err != nil && !errors.IsNotFound(err) {
+		r.errs = append(r.errs, err)
+	}
+	r.klusterlet = nil
+	return true
+}
+
+// Result returns the deleted objects and the errors of the rollback
+func (r *importRollback) Result() ([]string, error) {
+	return r.deleted, utilerrors.NewAggregate(r.errs)
+}
+
+// klusterletOperatorKept returns true if the Deployment of the klusterlet operator exists before the attempt
+func (r *importRollback) klusterletOperatorKept() bool {
+	for i, obj := range r.objs {
+		if obj.GroupVersionKind().GroupKind() == deploymentGroupKind && r.existed[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// klusterletOperatorDeleted returns true if the Deployment of the klusterlet operator is confirmed to be gone
+func (r *importRollback) klusterletOperatorDeleted(ctx context.Context) bool {
+	for _, obj := range r.objs {
+		if obj.GroupVersionKind().GroupKind() != deploymentGroupKind {
+			continue
+		}
+		if exists, err := r.exists(ctx, obj); exists || err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func (r *importRollback) exists(ctx context.Context, obj *metav1.PartialObjectMetadata) (bool, error) {
+	err := r.client.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+		obj.DeepCopy())
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func formatPartialObjectRef(obj *metav1.PartialObjectMetadata) string {
+	if len(obj.GetNamespace()) == 0 {
+		return fmt.Sprintf("%s %s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetName())
+	}
+	return fmt.Sprintf("%s %s/%s", obj.GetObjectKind().GroupVersionKind().Kind, obj.GetNamespace(), obj.GetName())
+}
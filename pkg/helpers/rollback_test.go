@@ -0,0 +1,252 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	operatorv1 "open-cluster-management.io/api/operator/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+func TestImportRollback(t *testing.T) {
+	rollbackKlusterletDeletionTimeout = 100 * time.Millisecond
+
+	namespace := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{Name: "open-cluster-management-agent"},
+	}
+	serviceAccount := &corev1.ServiceAccount{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet", Namespace: "open-cluster-management-agent"},
+	}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet", Namespace: "open-cluster-management-agent"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "bootstrap-hub-kubeconfig", Namespace: "open-cluster-management-agent"},
+	}
+	klusterlet := &operatorv1.Klusterlet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "klusterlet",
+			Finalizers: []string{"operator.open-cluster-management.io/klusterlet-cleanup"},
+		},
+	}
+
+	cases := []struct {
+		name     string
+		existing []runtime.Object
+		created  []client.Object
+		// removeFinalizer simulates the klusterlet operator that removes the finalizer of the Klusterlet
+		removeFinalizer  bool
+		expectedPending  bool
+		expectedDeleted  []string
+		expectedKept     []client.Object
+		expectedDeleting []client.Object
+	}{
+		{
+			name:            "nothing is created",
+			existing:        []runtime.Object{namespace.DeepCopy()},
+			expectedDeleted: []string{},
+			expectedKept:    []client.Object{namespace.DeepCopy()},
+		},
+		{
+			name:     "delete the created objects only",
+			existing: []runtime.Object{serviceAccount.DeepCopy()},
+			created:  []client.Object{namespace.DeepCopy(), deployment.DeepCopy()},
+			expectedDeleted: []string{
+				"Deployment open-cluster-management-agent/klusterlet",
+				"Namespace open-cluster-management-agent",
+			},
+			expectedKept: []client.Object{serviceAccount.DeepCopy()},
+		},
+		{
+			name:            "wait for the klusterlet operator to remove the finalizer of the klusterlet",
+			existing:        []runtime.Object{namespace.DeepCopy()},
+			created:         []client.Object{deployment.DeepCopy(), klusterlet.DeepCopy()},
+			removeFinalizer: true,
+			expectedPending: true,
+			expectedDeleted: []string{
+				"Klusterlet klusterlet",
+				"Deployment open-cluster-management-agent/klusterlet",
+			},
+			expectedKept: []client.Object{namespace.DeepCopy()},
+		},
+		{
+			name:            "remove the finalizer of the klusterlet after the klusterlet operator is deleted",
+			existing:        []runtime.Object{namespace.DeepCopy()},
+			created:         []client.Object{deployment.DeepCopy(), klusterlet.DeepCopy()},
+			expectedPending: true,
+			expectedDeleted: []string{
+				"Klusterlet klusterlet",
+				"Deployment open-cluster-management-agent/klusterlet",
+			},
+			expectedKept: []client.Object{namespace.DeepCopy()},
+		},
+		{
+			name:            "keep the finalizer of the klusterlet if the klusterlet operator is not created",
+			existing:        []runtime.Object{namespace.DeepCopy(), deployment.DeepCopy()},
+			created:         []client.Object{klusterlet.DeepCopy()},
+			expectedPending: true,
+			expectedDeleted: []string{
+				"Klusterlet klusterlet",
+			},
+			expectedKept:     []client.Object{namespace.DeepCopy(), deployment.DeepCopy()},
+			expectedDeleting: []client.Object{klusterlet.DeepCopy()},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ctx := context.TODO()
+			runtimeClient := fake.NewClientBuilder().WithScheme(genericScheme).WithRuntimeObjects(c.existing...).Build()
+
+			rollback, err := newImportRollback(ctx, runtimeClient, []runtime.Object{
+				namespace.DeepCopy(), serviceAccount.DeepCopy(), deployment.DeepCopy(), secret.DeepCopy(),
+				klusterlet.DeepCopy(),
+			})
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+
+			// the import attempt creates the objects and then fails
+			for _, obj := range c.created {
+				if err := runtimeClient.Create(ctx, obj); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			done := rollback.Rollback(ctx)
+			if done == c.expectedPending {
+				t.Errorf("expected pending %v, but got done %v", c.expectedPending, done)
+			}
+			if c.removeFinalizer {
+				if err := runtimeClient.Patch(ctx, klusterlet.DeepCopy(), client.RawPatch(types.MergePatchType,
+					[]byte(`{"metadata":{"finalizers":null}}`))); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+			for !done {
+				time.Sleep(10 * time.Millisecond)
+				done = rollback.Rollback(ctx)
+			}
+
+			deleted, err := rollback.Result()
+			if err != nil {
+				t.Errorf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(deleted, c.expectedDeleted) {
+				t.Errorf("expected deleted %v, but got %v", c.expectedDeleted, deleted)
+			}
+
+			deleting := map[string]bool{}
+			for _, obj := range c.expectedDeleting {
+				deleting[obj.GetName()] = true
+				current := obj.DeepCopyObject().(client.Object)
+				if err := runtimeClient.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(),
+					Name: obj.GetName()}, current); err != nil {
+					t.Errorf("expected %s is in deleting, but got %v", obj.GetName(), err)
+				} else if current.GetDeletionTimestamp().IsZero() {
+					t.Errorf("expected %s is in deleting", obj.GetName())
+				}
+			}
+			for _, obj := range c.created {
+				if deleting[obj.GetName()] {
+					continue
+				}
+				err := runtimeClient.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+					obj.DeepCopyObject().(client.Object))
+				if !errors.IsNotFound(err) {
+					t.Errorf("expected %s is deleted, but got %v", obj.GetName(), err)
+				}
+			}
+			for _, obj := range c.expectedKept {
+				if err := runtimeClient.Get(ctx, types.NamespacedName{Namespace: obj.GetNamespace(), Name: obj.GetName()},
+					obj); err != nil {
+					t.Errorf("expected %s is kept, but got %v", obj.GetName(), err)
+				}
+			}
+		})
+	}
+}
+
+func TestImportHelperContinueRollback(t *testing.T) {
+	rollbackKlusterletDeletionTimeout = 100 * time.Millisecond
+
+	ctx := context.TODO()
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "klusterlet", Namespace: "open-cluster-management-agent"},
+	}
+	klusterlet := &operatorv1.Klusterlet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:       "klusterlet",
+			Finalizers: []string{"operator.open-cluster-management.io/klusterlet-cleanup"},
+		},
+	}
+	runtimeClient := fake.NewClientBuilder().WithScheme(genericScheme).Build()
+	rollback, err := newImportRollback(ctx, runtimeClient, []runtime.Object{deployment.DeepCopy(), klusterlet.DeepCopy()})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	for _, obj := range []client.Object{deployment.DeepCopy(), klusterlet.DeepCopy()} {
+		if err := runtimeClient.Create(ctx, obj); err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	}
+	rollback.failureMessage = "Try to import managed cluster, error: failed"
+	if rollback.Rollback(ctx) {
+		t.Fatalf("expected the rollback is pending")
+	}
+
+	helper := NewImportHelper(nil, nil, logr.Discard()).WithGenerateClientHolderFunc(
+		func(secret *corev1.Secret) (reconcile.Result, *ClientHolder, meta.RESTMapper, error) {
+			t.Errorf("expected the next attempt is not started before the rollback is done")
+			return reconcile.Result{}, nil, nil, nil
+		})
+	helper.setPendingRollback("cluster1", rollback)
+	cluster := &clusterv1.ManagedCluster{ObjectMeta: metav1.ObjectMeta{Name: "cluster1"}}
+
+	result, condition, _, err := helper.Import(false, cluster, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if result.RequeueAfter != rollbackRequeueInterval || !helper.IsRollbackPending("cluster1") {
+		t.Errorf("expected the rollback is pending, but got %v", result)
+	}
+	if condition.Reason != constants.ConditionReasonManagedClusterImportFailed ||
+		!strings.Contains(condition.Message, "The import is being rolled back") {
+		t.Errorf("unexpected condition %v", condition)
+	}
+
+	time.Sleep(rollbackKlusterletDeletionTimeout)
+	result, condition, modified, err := helper.Import(false, cluster, nil)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !result.Requeue || helper.IsRollbackPending("cluster1") {
+		t.Errorf("expected the rollback is done, but got %v", result)
+	}
+	if !modified || !strings.Contains(condition.Message, "The import is rolled back") {
+		t.Errorf("unexpected condition %v", condition)
+	}
+	err = runtimeClient.Get(ctx, types.NamespacedName{Name: "klusterlet"}, &operatorv1.Klusterlet{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the klusterlet is deleted, but got %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	sdk "github.com/openshift-online/ocm-sdk-go"
@@ -34,6 +35,16 @@ const (
 	defaultRetryTimes     = 20 // default timeout will be `defaultRetryTimes*rosaImportRetryPeriod (10 mins)
 )
 
+// RosaImportState is the state of the import user that is created on a rosa cluster by the
+// RosaKubeConfigGetter, it is persisted to clean up the import user after the controller restarts.
+type RosaImportState struct {
+	ProviderID string
+	UserID     string
+	RetryTimes int
+	// BreakGlassCredentialID is the break-glass credential that is requested for the import
+	BreakGlassCredentialID string
+}
+
 type RosaKubeConfigGetter struct {
 	apiServerURL      string
 	tokenURL          string
@@ -42,9 +53,17 @@ type RosaKubeConfigGetter struct {
 	clientSecret      string
 	clusterID         string
 	importUserPasswd  string
+	providerID        string
+	userID            string
+	importUserCreated bool
 	totalRetryTimes   int
 	currentRetryTimes int
 	authMethod        string
+	// product is the OCM product of the cluster, the cluster product is not validated if it is empty
+	product string
+	// breakGlassEnabled allows the service account to use the break-glass credentials of the cluster
+	breakGlassEnabled      bool
+	breakGlassCredentialID string
 }
 
 func NewRosaKubeConfigGetter() *RosaKubeConfigGetter {
@@ -100,6 +119,39 @@ func (g *RosaKubeConfigGetter) SetClientSecret(clientSecret string) {
 	g.clientSecret = clientSecret
 }
 
+func (g *RosaKubeConfigGetter) SetProduct(product string) {
+	g.product = product
+}
+
+// SetBreakGlassEnabled allows the getter to get the kubeconfig from a break-glass credential instead of
+// creating an import user when the service-account auth method is used and the cluster offers break-glass
+// credentials.
+func (g *RosaKubeConfigGetter) SetBreakGlassEnabled(enabled bool) {
+	g.breakGlassEnabled = enabled
+}
+
+// State returns the state of the import user and the break-glass credential, the bool is false if neither the
+// import user is created nor the break-glass credential is requested, or they have been cleaned up.
+func (g *RosaKubeConfigGetter) State() (RosaImportState, bool) {
+	return RosaImportState{
+		ProviderID:             g.providerID,
+		UserID:                 g.userID,
+		RetryTimes:             g.currentRetryTimes,
+		BreakGlassCredentialID: g.breakGlassCredentialID,
+	}, g.importUserCreated || len(g.breakGlassCredentialID) != 0
+}
+
+// RestoreState restores the state persisted by a previous getter, the password of the import user is not
+// persisted, so it will be reset by the next KubeConfig.
+func (g *RosaKubeConfigGetter) RestoreState(state RosaImportState) {
+	g.providerID = state.ProviderID
+	g.userID = state.UserID
+	g.currentRetryTimes = state.RetryTimes
+	g.breakGlassCredentialID = state.BreakGlassCredentialID
+	// the state without a break-glass credential is persisted for the import user only
+	g.importUserCreated = len(state.ProviderID) != 0 || len(state.BreakGlassCredentialID) == 0
+}
+
 func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error) {
 	connection, err := g.newConnection()
 	if err != nil {
@@ -114,16 +166,32 @@ func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error)
 		return false, nil, err
 	}
 
+	if err := g.validateProduct(resp.Body()); err != nil {
+		return false, nil, err
+	}
+
 	api, ok := resp.Body().GetAPI()
 	if !ok {
-		return false, nil, fmt.Errorf("rosa cluster api url is not found, clusterID: %s", g.clusterID)
+		return false, nil, fmt.Errorf("%s cluster api url is not found, clusterID: %s", g.productName(), g.clusterID)
+	}
+
+	if g.breakGlassEnabled && g.authMethod == constants.AutoImportSecretRosaConfigAuthMethodServiceAccount {
+		requeue, config, err := g.breakGlassKubeConfig(connection)
+		if err != errBreakGlassCredentialsNotOffered {
+			return requeue, config, err
+		}
+		klog.Infof("%s cluster %s does not offer break-glass credentials, creating the import user",
+			g.productName(), g.clusterID)
 	}
 
 	if len(g.importUserPasswd) == 0 {
-		importUserPassword, err := createImportUserWithHTPasswdIDProvider(clusterClient)
+		providerID, userID, importUserPassword, err := createImportUserWithHTPasswdIDProvider(clusterClient)
 		if err != nil {
 			return false, nil, err
 		}
+		g.providerID = providerID
+		g.userID = userID
+		g.importUserCreated = true
 
 		// add the acm import user to cluster admin group
 		adminsClient := clusterClient.Groups().Group(clusterAdminGroup).Users()
@@ -141,14 +209,14 @@ func (g *RosaKubeConfigGetter) KubeConfig() (bool, *clientcmdapi.Config, error)
 	})
 	if err != nil {
 		if g.shouldRetry(clusterClient) {
-			klog.Infof("Failed to get kubeconfig for rosa cluster %s, retry after %d seconds, %v",
-				g.clusterID, rosaImportRetryPeriod/time.Second, err)
-			return true, nil, fmt.Errorf("kubeconfig for rosa cluster %s is not ready, retry after %d seconds",
-				g.clusterID, rosaImportRetryPeriod/time.Second)
+			klog.Infof("Failed to get kubeconfig for %s cluster %s, retry after %d seconds, %v",
+				g.productName(), g.clusterID, rosaImportRetryPeriod/time.Second, err)
+			return true, nil, fmt.Errorf("kubeconfig for %s cluster %s is not ready, retry after %d seconds",
+				g.productName(), g.clusterID, rosaImportRetryPeriod/time.Second)
 		}
 
-		return false, nil, fmt.Errorf("failed to get kubeconfig for rosa cluster %s after %d seconds, err",
-			g.clusterID, (rosaImportRetryPeriod*time.Duration(g.totalRetryTimes))/time.Second)
+		return false, nil, fmt.Errorf("failed to get kubeconfig for %s cluster %s after %d seconds, err",
+			g.productName(), g.clusterID, (rosaImportRetryPeriod*time.Duration(g.totalRetryTimes))/time.Second)
 	}
 
 	return false, buildKubeConfigFileWithToken(api.URL(), token), nil
@@ -162,19 +230,72 @@ func (g *RosaKubeConfigGetter) Cleanup() error {
 	defer connection.Close()
 
 	errs := []error{}
-	clusterClient := connection.ClustersMgmt().V1().Clusters().Cluster(g.clusterID)
-	// the cluster token is requested, delete the id provider and remove the import user from cluster admin group
-	if err := deleteHTPasswdIDProvider(clusterClient.IdentityProviders(), g.clusterID); err != nil {
-		errs = append(errs, err)
+	cleanupImportUser := g.importUserCreated || len(g.breakGlassCredentialID) == 0
+	if len(g.breakGlassCredentialID) != 0 {
+		// the break-glass credential is not required after the importing resources are applied, revoke it
+		// instead of waiting for its expiration
+		if err := revokeBreakGlassCredentials(connection, g.clusterID); err != nil {
+			errs = append(errs, err)
+		} else {
+			g.breakGlassCredentialID = ""
+		}
 	}
 
-	if err := removeImportUserFromClusterAdminGroup(clusterClient.Groups().Group(clusterAdminGroup).Users()); err != nil {
-		errs = append(errs, err)
+	if cleanupImportUser {
+		clusterClient := connection.ClustersMgmt().V1().Clusters().Cluster(g.clusterID)
+		// the cluster token is requested, delete the id provider and remove the import user from cluster admin group
+		userErrs := []error{}
+		if err := deleteHTPasswdIDProvider(clusterClient.IdentityProviders(), g.providerID); err != nil {
+			userErrs = append(userErrs, err)
+		}
+
+		if err := removeImportUserFromClusterAdminGroup(
+			clusterClient.Groups().Group(clusterAdminGroup).Users()); err != nil {
+			userErrs = append(userErrs, err)
+		}
+
+		if len(userErrs) == 0 {
+			g.resetImportUser()
+		}
+		errs = append(errs, userErrs...)
 	}
 
 	return utilerrors.NewAggregate(errs)
 }
 
+// productName returns the product that is used in the messages, the rosa is used if the product is not specified
+func (g *RosaKubeConfigGetter) productName() string {
+	if len(g.product) == 0 {
+		return constants.AutoImportSecretOCMProductROSA
+	}
+	return g.product
+}
+
+// validateProduct ensures the cluster is a cluster of the specified product, the validation is skipped if the
+// product is not specified or the cluster does not report its product.
+func (g *RosaKubeConfigGetter) validateProduct(cluster *clustersmgmtv1.Cluster) error {
+	if len(g.product) == 0 {
+		return nil
+	}
+
+	product, ok := cluster.GetProduct()
+	if !ok || len(product.ID()) == 0 {
+		return nil
+	}
+
+	if !strings.EqualFold(product.ID(), g.product) {
+		return fmt.Errorf("the cluster %s is a %s cluster, but the product is %s", g.clusterID, product.ID(), g.product)
+	}
+	return nil
+}
+
+func (g *RosaKubeConfigGetter) resetImportUser() {
+	g.importUserPasswd = ""
+	g.providerID = ""
+	g.userID = ""
+	g.importUserCreated = false
+}
+
 func (g *RosaKubeConfigGetter) newConnection() (*sdk.Connection, error) {
 	logger, err := sdk.NewGoLoggerBuilder().
 		Debug(true).
@@ -197,6 +318,8 @@ func (g *RosaKubeConfigGetter) newConnection() (*sdk.Connection, error) {
 		// https://github.com/openshift-online/ocm-sdk-go/blob/e523a3317a2e9da40b63acf1b20a985041b7ea99/examples/client_credentials_grant.go#L48
 		return sdk.NewConnectionBuilder().
 			Logger(logger).
+			URL(g.apiServerURL).
+			TokenURL(g.tokenURL).
 			Client(g.clientID, g.clientSecret).
 			Build()
 	default:
@@ -207,16 +330,23 @@ func (g *RosaKubeConfigGetter) newConnection() (*sdk.Connection, error) {
 func (g *RosaKubeConfigGetter) shouldRetry(clusterClient *clustersmgmtv1.ClusterClient) bool {
 	if g.currentRetryTimes >= g.totalRetryTimes {
 		// request the cluster token timeout, delete its id provider and remove the import user from cluster admin group
-		klog.Warningf("stop to retry getting kube token for rosa cluster %s, reach the retry times limit (%d)",
-			g.clusterID, g.totalRetryTimes)
-		if err := deleteHTPasswdIDProvider(clusterClient.IdentityProviders(), g.clusterID); err != nil {
-			klog.Warningf("failed to delete the htPasswd id provider %s for rosa cluster %s, %v",
-				importHTPasswdIDProvider, g.clusterID, err)
+		klog.Warningf("stop to retry getting kube token for %s cluster %s, reach the retry times limit (%d)",
+			g.productName(), g.clusterID, g.totalRetryTimes)
+		cleaned := true
+		if err := deleteHTPasswdIDProvider(clusterClient.IdentityProviders(), g.providerID); err != nil {
+			klog.Warningf("failed to delete the htPasswd id provider %s for %s cluster %s, %v",
+				importHTPasswdIDProvider, g.productName(), g.clusterID, err)
+			cleaned = false
 		}
 
 		if err := removeImportUserFromClusterAdminGroup(clusterClient.Groups().Group(clusterAdminGroup).Users()); err != nil {
-			klog.Warningf("failed to remove the import user %s from cluster admin group for rosa cluster %s, %v",
-				importHTPasswdIDProvider, g.clusterID, err)
+			klog.Warningf("failed to remove the import user %s from cluster admin group for %s cluster %s, %v",
+				importHTPasswdIDProvider, g.productName(), g.clusterID, err)
+			cleaned = false
+		}
+
+		if cleaned {
+			g.resetImportUser()
 		}
 
 		return false
@@ -226,12 +356,14 @@ func (g *RosaKubeConfigGetter) shouldRetry(clusterClient *clustersmgmtv1.Cluster
 	return true
 }
 
-func createImportUserWithHTPasswdIDProvider(clusterClient *clustersmgmtv1.ClusterClient) (string, error) {
+// createImportUserWithHTPasswdIDProvider creates or updates the import user, and returns the htPasswd id provider
+// ID, the import user ID and the password of the import user.
+func createImportUserWithHTPasswdIDProvider(clusterClient *clustersmgmtv1.ClusterClient) (string, string, string, error) {
 	// try to find a htPasswd provider for acm import user
 	idProvidersClient := clusterClient.IdentityProviders()
 	providerID, err := findHTPasswdIDProvider(idProvidersClient)
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
 
 	if len(providerID) == 0 {
@@ -241,14 +373,16 @@ func createImportUserWithHTPasswdIDProvider(clusterClient *clustersmgmtv1.Cluste
 	// try to find the acm import user in the htPasswd provider
 	userID, err := findHTPasswdUser(idProvidersClient, providerID)
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
 
 	if len(userID) == 0 {
-		return addHTPasswdUser(idProvidersClient, providerID)
+		userID, pw, err := addHTPasswdUser(idProvidersClient, providerID)
+		return providerID, userID, pw, err
 	}
 
-	return updateHTPasswdUserPassword(idProvidersClient, providerID, userID)
+	pw, err := updateHTPasswdUserPassword(idProvidersClient, providerID, userID)
+	return providerID, userID, pw, err
 }
 
 func findHTPasswdIDProvider(client *clustersmgmtv1.IdentityProvidersClient) (string, error) {
@@ -339,10 +473,10 @@ func addImportUserToClusterAdminGroup(client *clustersmgmtv1.UsersClient) error
 	return err
 }
 
-func createHTPasswdIDProviderWithUser(client *clustersmgmtv1.IdentityProvidersClient) (string, error) {
+func createHTPasswdIDProviderWithUser(client *clustersmgmtv1.IdentityProvidersClient) (string, string, string, error) {
 	pw, err := password.Generate(20, 10, 0, false, false)
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
 
 	htPasswdUserBuilder := clustersmgmtv1.NewHTPasswdUser().Username(importHTPasswdUser).Password(pw)
@@ -353,29 +487,32 @@ func createHTPasswdIDProviderWithUser(client *clustersmgmtv1.IdentityProvidersCl
 		Htpasswd(clustersmgmtv1.NewHTPasswdIdentityProvider().Users(htPasswdUserListBuilder)).
 		Build()
 	if err != nil {
-		return "", err
+		return "", "", "", err
 	}
 
-	if _, err := client.Add().Body(htPasswdIDProvider).Send(); err != nil {
-		return "", err
+	resp, err := client.Add().Body(htPasswdIDProvider).Send()
+	if err != nil {
+		return "", "", "", err
 	}
-	return pw, nil
+	provider := resp.Body()
+	return provider.ID(), provider.Htpasswd().Users().Get(0).ID(), pw, nil
 }
 
-func addHTPasswdUser(client *clustersmgmtv1.IdentityProvidersClient, providerID string) (string, error) {
+func addHTPasswdUser(client *clustersmgmtv1.IdentityProvidersClient, providerID string) (string, string, error) {
 	pw, err := password.Generate(20, 10, 0, false, false)
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
 	htPasswdUser, err := clustersmgmtv1.NewHTPasswdUser().Username(importHTPasswdUser).Password(pw).Build()
 	if err != nil {
-		return "", err
+		return "", "", err
 	}
-	if _, err = client.IdentityProvider(providerID).HtpasswdUsers().Add().Body(htPasswdUser).Send(); err != nil {
-		return "", err
+	resp, err := client.IdentityProvider(providerID).HtpasswdUsers().Add().Body(htPasswdUser).Send()
+	if err != nil {
+		return "", "", err
 	}
 
-	return pw, nil
+	return resp.Body().ID(), pw, nil
 }
 
 func updateHTPasswdUserPassword(client *clustersmgmtv1.IdentityProvidersClient, providerID, userID string) (string, error) {
@@ -395,17 +532,24 @@ func updateHTPasswdUserPassword(client *clustersmgmtv1.IdentityProvidersClient,
 	return pw, nil
 }
 
-func deleteHTPasswdIDProvider(client *clustersmgmtv1.IdentityProvidersClient, clusterID string) error {
-	providerID, err := findHTPasswdIDProvider(client)
-	if err != nil {
-		return err
+// deleteHTPasswdIDProvider deletes the htPasswd id provider of the import user, the provider is found by its
+// name if the provider ID is unknown.
+func deleteHTPasswdIDProvider(client *clustersmgmtv1.IdentityProvidersClient, providerID string) error {
+	if len(providerID) == 0 {
+		var err error
+		if providerID, err = findHTPasswdIDProvider(client); err != nil {
+			return err
+		}
 	}
 
 	if len(providerID) == 0 {
 		return nil
 	}
 
-	_, err = client.IdentityProvider(providerID).Delete().Send()
+	resp, err := client.IdentityProvider(providerID).Delete().Send()
+	if err != nil && resp != nil && resp.Status() == http.StatusNotFound {
+		return nil
+	}
 	return err
 }
 
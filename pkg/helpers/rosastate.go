@@ -0,0 +1,130 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"reflect"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+// rosaClusterInfoKeys are the keys of the rosa auto-import secret that are required to clean up the import user
+var rosaClusterInfoKeys = []string{
+	constants.AutoImportSecretRosaConfigAuthMethodKey,
+	constants.AutoImportSecretRosaConfigAPITokenKey,
+	constants.AutoImportSecretRosaConfigClientIDKey,
+	constants.AutoImportSecretRosaConfigClientSecretKey,
+	constants.AutoImportSecretRosaConfigClusterIDKey,
+	constants.AutoImportSecretRosaConfigAPIURLKey,
+	constants.AutoImportSecretRosaConfigTokenURLKey,
+}
+
+// SaveRosaImportState persists the state of the import user and the break-glass credential with the rosa cluster
+// info of the auto-import secret to the rosa import state secret in the cluster namespace.
+func SaveRosaImportState(ctx context.Context, kubeClient kubernetes.Interface, autoImportSecret *corev1.Secret,
+	state RosaImportState) error {
+	data := map[string][]byte{
+		constants.AutoImportSecretRosaStateProviderIDKey: []byte(state.ProviderID),
+		constants.AutoImportSecretRosaStateUserIDKey:     []byte(state.UserID),
+		constants.AutoImportSecretRosaStateRetryCountKey: []byte(strconv.Itoa(state.RetryTimes)),
+	}
+	if len(state.BreakGlassCredentialID) != 0 {
+		data[constants.AutoImportSecretRosaStateBreakGlassCredentialIDKey] = []byte(state.BreakGlassCredentialID)
+	}
+	for _, key := range rosaClusterInfoKeys {
+		if val, ok := autoImportSecret.Data[key]; ok {
+			data[key] = val
+		}
+	}
+
+	secrets := kubeClient.CoreV1().Secrets(autoImportSecret.Namespace)
+	existing, err := secrets.Get(ctx, constants.RosaImportStateSecretName, metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		_, err := secrets.Create(ctx, &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      constants.RosaImportStateSecretName,
+				Namespace: autoImportSecret.Namespace,
+				Labels: map[string]string{
+					constants.RosaImportStateLabel: constants.LabelValueTrue,
+				},
+			},
+			Type: corev1.SecretTypeOpaque,
+			Data: data,
+		}, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if reflect.DeepEqual(existing.Data, data) {
+		return nil
+	}
+
+	existing = existing.DeepCopy()
+	existing.Data = data
+	_, err = secrets.Update(ctx, existing, metav1.UpdateOptions{})
+	return err
+}
+
+// LoadRosaImportState returns the state of the import user persisted in the cluster namespace, the bool is false
+// if there is no persisted state.
+func LoadRosaImportState(ctx context.Context, kubeClient kubernetes.Interface,
+	namespace string) (RosaImportState, bool, error) {
+	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(ctx, constants.RosaImportStateSecretName,
+		metav1.GetOptions{})
+	if errors.IsNotFound(err) {
+		return RosaImportState{}, false, nil
+	}
+	if err != nil {
+		return RosaImportState{}, false, err
+	}
+
+	return rosaImportStateFromSecret(secret), true, nil
+}
+
+// DeleteRosaImportState deletes the state of the import user persisted in the cluster namespace
+func DeleteRosaImportState(ctx context.Context, kubeClient kubernetes.Interface, namespace string) error {
+	err := kubeClient.CoreV1().Secrets(namespace).Delete(ctx, constants.RosaImportStateSecretName,
+		metav1.DeleteOptions{})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// CleanupRosaImportState cleans up the import user and revokes the break-glass credential on the rosa cluster with
+// the given rosa import state secret, and deletes the secret once they are cleaned up.
+func CleanupRosaImportState(ctx context.Context, kubeClient kubernetes.Interface, stateSecret *corev1.Secret) error {
+	getter := NewRosaKubeConfigGetter()
+	if err := setRosaKubeConfigGetter(getter, stateSecret); err != nil {
+		return err
+	}
+	getter.RestoreState(rosaImportStateFromSecret(stateSecret))
+
+	if err := getter.Cleanup(); err != nil {
+		return err
+	}
+
+	return DeleteRosaImportState(ctx, kubeClient, stateSecret.Namespace)
+}
+
+func rosaImportStateFromSecret(secret *corev1.Secret) RosaImportState {
+	// the invalid retry count is treated as no retry
+	retryTimes, _ := strconv.Atoi(string(secret.Data[constants.AutoImportSecretRosaStateRetryCountKey]))
+	return RosaImportState{
+		ProviderID: string(secret.Data[constants.AutoImportSecretRosaStateProviderIDKey]),
+		UserID:     string(secret.Data[constants.AutoImportSecretRosaStateUserIDKey]),
+		RetryTimes: retryTimes,
+		BreakGlassCredentialID: string(
+			secret.Data[constants.AutoImportSecretRosaStateBreakGlassCredentialIDKey]),
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package helpers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/onsi/gomega"
+	"github.com/onsi/gomega/ghttp"
+	clustersmgmttesting "github.com/openshift-online/ocm-sdk-go/testing"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	kubefake "k8s.io/client-go/kubernetes/fake"
+)
+
+func TestRosaImportState(t *testing.T) {
+	autoImportSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-import-secret",
+			Namespace: "test",
+		},
+		Type: "auto-import/rosa",
+		Data: map[string][]byte{
+			"api_token":       []byte("token"),
+			"cluster_id":      []byte("1234"),
+			"autoImportRetry": []byte("5"),
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset()
+
+	if _, found, err := LoadRosaImportState(context.TODO(), kubeClient, "test"); err != nil || found {
+		t.Fatalf("expected no state, but got %v, %v", found, err)
+	}
+
+	state := RosaImportState{ProviderID: "p1", UserID: "u1", RetryTimes: 2, BreakGlassCredentialID: "b1"}
+	if err := SaveRosaImportState(context.TODO(), kubeClient, autoImportSecret, state); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	state.RetryTimes = 3
+	if err := SaveRosaImportState(context.TODO(), kubeClient, autoImportSecret, state); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	actual, found, err := LoadRosaImportState(context.TODO(), kubeClient, "test")
+	if err != nil || !found {
+		t.Fatalf("expected state, but got %v, %v", found, err)
+	}
+	if actual != state {
+		t.Errorf("expected state %v, but got %v", state, actual)
+	}
+
+	stateSecret, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "auto-import-rosa-state",
+		metav1.GetOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if stateSecret.Labels["import.open-cluster-management.io/rosa-import-state"] != "true" {
+		t.Errorf("expected the state label, but got %v", stateSecret.Labels)
+	}
+	if string(stateSecret.Data["cluster_id"]) != "1234" || string(stateSecret.Data["api_token"]) != "token" {
+		t.Errorf("expected the rosa cluster info is saved, but got %v", stateSecret.Data)
+	}
+	if _, ok := stateSecret.Data["autoImportRetry"]; ok {
+		t.Errorf("expected only the rosa cluster info is saved, but got %v", stateSecret.Data)
+	}
+
+	if err := DeleteRosaImportState(context.TODO(), kubeClient, "test"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := DeleteRosaImportState(context.TODO(), kubeClient, "test"); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestCleanupRosaImportState(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	accessToken := clustersmgmttesting.MakeTokenString("Bearer", 5*time.Minute)
+	refreshToken := clustersmgmttesting.MakeTokenString("Refresh", 10*time.Hour)
+
+	oidServer := clustersmgmttesting.MakeTCPServer()
+	oidServer.AppendHandlers(
+		ghttp.CombineHandlers(
+			clustersmgmttesting.RespondWithAccessAndRefreshTokens(accessToken, refreshToken),
+		),
+	)
+	apiServer := clustersmgmttesting.MakeTCPServer()
+	defer func() {
+		oidServer.Close()
+		apiServer.Close()
+	}()
+
+	apiServer.AppendHandlers(
+		ghttp.CombineHandlers(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the provider is deleted by its recorded ID
+				if r.Method != http.MethodDelete || r.URL.Path != "/api/clusters_mgmt/v1/clusters/test/identity_providers/1234" {
+					t.Fatalf("unexpected request %s - %s", r.Method, r.URL.Path)
+				}
+			}),
+			clustersmgmttesting.RespondWithJSON(http.StatusNotFound, "{}"),
+		),
+		ghttp.CombineHandlers(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodGet || r.URL.Path != "/api/clusters_mgmt/v1/clusters/test/groups/cluster-admins/users/acm-import" {
+					t.Fatalf("unexpected request %s - %s", r.Method, r.URL.Path)
+				}
+			}),
+			clustersmgmttesting.RespondWithJSON(http.StatusOK, "{}"),
+		),
+		ghttp.CombineHandlers(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodDelete || r.URL.Path != "/api/clusters_mgmt/v1/clusters/test/groups/cluster-admins/users/acm-import" {
+					t.Fatalf("unexpected request %s - %s", r.Method, r.URL.Path)
+				}
+			}),
+			clustersmgmttesting.RespondWithJSON(http.StatusOK, "{}"),
+		),
+	)
+
+	stateSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-import-rosa-state",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"api_url":     []byte(apiServer.URL()),
+			"token_url":   []byte(oidServer.URL()),
+			"api_token":   []byte(accessToken),
+			"cluster_id":  []byte("test"),
+			"provider_id": []byte("1234"),
+			"user_id":     []byte("4567"),
+			"retry_count": []byte("3"),
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(stateSecret)
+
+	if err := CleanupRosaImportState(context.TODO(), kubeClient, stateSecret); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "auto-import-rosa-state", metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the state is deleted, but got %v", err)
+	}
+}
+
+func TestCleanupRosaImportStateWithBreakGlassCredential(t *testing.T) {
+	gomega.RegisterTestingT(t)
+
+	accessToken := clustersmgmttesting.MakeTokenString("Bearer", 5*time.Minute)
+	refreshToken := clustersmgmttesting.MakeTokenString("Refresh", 10*time.Hour)
+
+	oidServer := clustersmgmttesting.MakeTCPServer()
+	oidServer.RouteToHandler(http.MethodPost, "/",
+		clustersmgmttesting.RespondWithAccessAndRefreshTokens(accessToken, refreshToken))
+	apiServer := clustersmgmttesting.MakeTCPServer()
+	defer func() {
+		oidServer.Close()
+		apiServer.Close()
+	}()
+
+	// only the break-glass credentials are revoked, the import user is not created
+	apiServer.AppendHandlers(
+		ghttp.CombineHandlers(
+			ghttp.VerifyRequest(http.MethodDelete, "/api/clusters_mgmt/v1/clusters/test/break_glass_credentials"),
+			clustersmgmttesting.RespondWithJSON(http.StatusNoContent, ""),
+		),
+	)
+
+	stateSecret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "auto-import-rosa-state",
+			Namespace: "test",
+		},
+		Data: map[string][]byte{
+			"api_url":                   []byte(apiServer.URL()),
+			"token_url":                 []byte(oidServer.URL()),
+			"auth_method":               []byte("service-account"),
+			"client_id":                 []byte("id"),
+			"client_secret":             []byte("secret"),
+			"cluster_id":                []byte("test"),
+			"provider_id":               []byte(""),
+			"user_id":                   []byte(""),
+			"retry_count":               []byte("1"),
+			"break_glass_credential_id": []byte("b1"),
+		},
+	}
+	kubeClient := kubefake.NewSimpleClientset(stateSecret)
+
+	if err := CleanupRosaImportState(context.TODO(), kubeClient, stateSecret); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+
+	if len(apiServer.ReceivedRequests()) != 1 {
+		t.Errorf("expected the break-glass credentials are revoked only, but got %d requests",
+			len(apiServer.ReceivedRequests()))
+	}
+
+	_, err := kubeClient.CoreV1().Secrets("test").Get(context.TODO(), "auto-import-rosa-state", metav1.GetOptions{})
+	if !errors.IsNotFound(err) {
+		t.Errorf("expected the state is deleted, but got %v", err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog/v2"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	apiconstants "github.com/stolostron/cluster-lifecycle-api/constants"
+
+	"github.com/stolostron/managedcluster-import-controller/pkg/constants"
+)
+
+const (
+	// ManagedClusterKlusterletConfigAnnotationIndexKey is the index of the managed cluster informer to find the
+	// managed clusters by their klusterletconfigs, every managed cluster is indexed with the global klusterletconfig.
+	ManagedClusterKlusterletConfigAnnotationIndexKey = "annotation-klusterletconfig"
+)
+
+func IndexManagedClusterByKlusterletconfigAnnotation(obj interface{}) ([]string, error) {
+	managedCluster, ok := obj.(*clusterv1.ManagedCluster)
+	if !ok {
+		return nil, fmt.Errorf("not a managedcluster object")
+	}
+	klusterletconfigs := []string{constants.GlobalKlusterletConfigName}
+	klusterletconfig, ok := managedCluster.GetAnnotations()[apiconstants.AnnotationKlusterletConfig]
+	if ok && klusterletconfig != "" {
+		klusterletconfigs = append(klusterletconfigs, klusterletconfig)
+	}
+	return klusterletconfigs, nil
+}
+
+// KlusterletConfigEventHandler enqueues the managed clusters that use the klusterletconfig, all of the managed
+// clusters are enqueued for the global klusterletconfig. The managed clusters are found with the
+// ManagedClusterKlusterletConfigAnnotationIndexKey of the ManagedClusterIndexer, and the request of a managed
+// cluster is mapped with the MapFunc if it is set, otherwise the request is the name of the managed cluster.
+type KlusterletConfigEventHandler struct {
+	ManagedClusterIndexer cache.Indexer
+	MapFunc
+}
+
+var _ handler.EventHandler = &KlusterletConfigEventHandler{}
+
+func (e *KlusterletConfigEventHandler) Create(ctx context.Context, evt event.TypedCreateEvent[client.Object],
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *KlusterletConfigEventHandler) Update(ctx context.Context, evt event.TypedUpdateEvent[client.Object],
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.ObjectNew.GetName(), q)
+}
+
+func (e *KlusterletConfigEventHandler) Delete(ctx context.Context, evt event.TypedDeleteEvent[client.Object],
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *KlusterletConfigEventHandler) Generic(ctx context.Context, evt event.TypedGenericEvent[client.Object],
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	e.enqueue(evt.Object.GetName(), q)
+}
+
+func (e *KlusterletConfigEventHandler) enqueue(klusterletconfigName string,
+	q workqueue.TypedRateLimitingInterface[reconcile.Request]) {
+	objs, err := e.ManagedClusterIndexer.ByIndex(ManagedClusterKlusterletConfigAnnotationIndexKey, klusterletconfigName)
+	if err != nil {
+		klog.Errorf("Failed to get managed clusters of the klusterletconfig %s by indexer: %v",
+			klusterletconfigName, err)
+		return
+	}
+	for _, obj := range objs {
+		mc, ok := obj.(*clusterv1.ManagedCluster)
+		if !ok {
+			continue
+		}
+		request := reconcile.Request{NamespacedName: types.NamespacedName{Name: mc.GetName()}}
+		if e.MapFunc != nil {
+			request = e.MapFunc(mc)
+		}
+		q.Add(request)
+	}
+}
+
+// NewAutoImportStrategyPredicate returns the predicate of the klusterletconfigs that only accepts the events of the
+// auto-import-strategy annotation.
+func NewAutoImportStrategyPredicate() predicate.Predicate {
+	return predicate.Funcs{
+		GenericFunc: func(e event.GenericEvent) bool { return false },
+		CreateFunc:  func(e event.CreateEvent) bool { return hasAutoImportStrategy(e.Object) },
+		DeleteFunc:  func(e event.DeleteEvent) bool { return hasAutoImportStrategy(e.Object) },
+		UpdateFunc: func(e event.UpdateEvent) bool {
+			return e.ObjectOld.GetAnnotations()[constants.AutoImportStrategyAnnotation] !=
+				e.ObjectNew.GetAnnotations()[constants.AutoImportStrategyAnnotation]
+		},
+	}
+}
+
+func hasAutoImportStrategy(obj client.Object) bool {
+	_, ok := obj.GetAnnotations()[constants.AutoImportStrategyAnnotation]
+	return ok
+}
@@ -0,0 +1,196 @@
+// Copyright (c) Red Hat, Inc.
+// Copyright Contributors to the Open Cluster Management project
+
+package source
+
+import (
+	"context"
+	"testing"
+
+	klusterletconfigv1alpha1 "github.com/stolostron/cluster-lifecycle-api/klusterletconfig/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/sets"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+	clusterv1 "open-cluster-management.io/api/cluster/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func TestKlusterletConfigEventHandler(t *testing.T) {
+	mcs := []*clusterv1.ManagedCluster{
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster1",
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "kc1"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster2",
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "kc2"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "cluster3",
+				Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "kc2"},
+			},
+		},
+		{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: "cluster4",
+			},
+		},
+	}
+
+	namespaced := func(obj client.Object) reconcile.Request {
+		return reconcile.Request{NamespacedName: types.NamespacedName{Namespace: obj.GetName(), Name: obj.GetName()}}
+	}
+
+	cases := []struct {
+		name             string
+		klusterletconfig string
+		mapFunc          MapFunc
+		expectedRequests []reconcile.Request
+	}{
+		{
+			name:             "klusterletconfig",
+			klusterletconfig: "kc1",
+			expectedRequests: []reconcile.Request{{NamespacedName: types.NamespacedName{Name: "cluster1"}}},
+		},
+		{
+			name:             "klusterletconfig of multiple clusters",
+			klusterletconfig: "kc2",
+			expectedRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "cluster2"}},
+				{NamespacedName: types.NamespacedName{Name: "cluster3"}},
+			},
+		},
+		{
+			name:             "global klusterletconfig",
+			klusterletconfig: "global",
+			expectedRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Name: "cluster1"}},
+				{NamespacedName: types.NamespacedName{Name: "cluster2"}},
+				{NamespacedName: types.NamespacedName{Name: "cluster3"}},
+				{NamespacedName: types.NamespacedName{Name: "cluster4"}},
+			},
+		},
+		{
+			name:             "unused klusterletconfig",
+			klusterletconfig: "kc3",
+		},
+		{
+			name:             "mapped requests",
+			klusterletconfig: "kc1",
+			mapFunc:          namespaced,
+			expectedRequests: []reconcile.Request{
+				{NamespacedName: types.NamespacedName{Namespace: "cluster1", Name: "cluster1"}},
+			},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			indexer := cache.NewIndexer(cache.MetaNamespaceKeyFunc, cache.Indexers{
+				ManagedClusterKlusterletConfigAnnotationIndexKey: IndexManagedClusterByKlusterletconfigAnnotation,
+			})
+			for _, mc := range mcs {
+				if err := indexer.Add(mc); err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+			}
+
+			h := &KlusterletConfigEventHandler{ManagedClusterIndexer: indexer, MapFunc: c.mapFunc}
+			queue := workqueue.NewTypedRateLimitingQueue(workqueue.DefaultTypedControllerRateLimiter[reconcile.Request]())
+			h.Update(context.TODO(), event.UpdateEvent{
+				ObjectOld: &klusterletconfigv1alpha1.KlusterletConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: c.klusterletconfig},
+				},
+				ObjectNew: &klusterletconfigv1alpha1.KlusterletConfig{
+					ObjectMeta: metav1.ObjectMeta{Name: c.klusterletconfig},
+				},
+			}, queue)
+
+			actual := sets.New[reconcile.Request]()
+			for queue.Len() > 0 {
+				item, _ := queue.Get()
+				actual.Insert(item)
+				queue.Done(item)
+			}
+			if !actual.Equal(sets.New(c.expectedRequests...)) {
+				t.Errorf("expected requests %v, but got %v", c.expectedRequests, actual.UnsortedList())
+			}
+		})
+	}
+}
+
+func TestAutoImportStrategyPredicate(t *testing.T) {
+	withStrategy := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "kc1",
+			Annotations: map[string]string{"import.open-cluster-management.io/auto-import-strategy": "ImportAndSync"},
+		},
+	}
+	withoutStrategy := &klusterletconfigv1alpha1.KlusterletConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "kc1",
+			Annotations: map[string]string{"test": "test"},
+		},
+	}
+
+	p := NewAutoImportStrategyPredicate()
+	if !p.Create(event.CreateEvent{Object: withStrategy}) {
+		t.Errorf("expected the klusterletconfig with the strategy is accepted")
+	}
+	if p.Create(event.CreateEvent{Object: withoutStrategy}) {
+		t.Errorf("expected the klusterletconfig without the strategy is ignored")
+	}
+	if !p.Update(event.UpdateEvent{ObjectOld: withoutStrategy, ObjectNew: withStrategy}) {
+		t.Errorf("expected the change of the strategy is accepted")
+	}
+	if p.Update(event.UpdateEvent{ObjectOld: withStrategy, ObjectNew: withStrategy}) {
+		t.Errorf("expected the update without the change of the strategy is ignored")
+	}
+	if !p.Delete(event.DeleteEvent{Object: withStrategy}) {
+		t.Errorf("expected the deletion of the klusterletconfig with the strategy is accepted")
+	}
+}
+
+func TestIndexManagedClusterByKlusterletconfigAnnotation(t *testing.T) {
+	// Create a new managed cluster with a klusterletconfig annotation
+	mcWithAnnotation := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "test1",
+			Annotations: map[string]string{"agent.open-cluster-management.io/klusterlet-config": "test-klusterletconfig"},
+		},
+	}
+
+	// Create a new managed cluster without a klusterletconfig annotation
+	mcWithoutAnnotation := &clusterv1.ManagedCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "test2",
+		},
+	}
+
+	// Test the function with a managed cluster that has a klusterletconfig annotation
+	result, err := IndexManagedClusterByKlusterletconfigAnnotation(mcWithAnnotation)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 2 || result[0] != "global" || result[1] != "test-klusterletconfig" {
+		t.Errorf("Expected result to be [\"global, test-klusterletconfig\"], but got %v", result)
+	}
+
+	// Test the function with a managed cluster that does not have a klusterletconfig annotation
+	result, err = IndexManagedClusterByKlusterletconfigAnnotation(mcWithoutAnnotation)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result) != 1 || result[0] != "global" {
+		t.Errorf("Expected result to be [\"global\"], but got %v", result)
+	}
+}
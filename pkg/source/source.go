@@ -45,6 +45,10 @@ type InformerHolder struct {
 	ControllerConfigLister   corev1listers.ConfigMapLister
 
 	ManagedClusterInformer cache.SharedIndexInformer
+
+	// ServiceAccountTokenSecretInformer watches the service account token secrets, the secrets are indexed by
+	// their service accounts with the bootstrap.ServiceAccountTokenSecretsIndexKey
+	ServiceAccountTokenSecretInformer cache.SharedIndexInformer
 }
 
 // NewImportSecretSource return a source only for import secrets
@@ -103,6 +107,20 @@ func NewHostedWorkSource(workInformer cache.SharedIndexInformer,
 	}
 }
 
+// NewControllerConfigSource return a source only for the import-controller-config configmap
+func NewControllerConfigSource(configMapInformer cache.SharedIndexInformer,
+	handler handler.EventHandler,
+	predicates ...predicate.Predicate) *Source {
+	return &Source{
+		informer:     configMapInformer,
+		expectedType: reflect.TypeOf(&corev1.ConfigMap{}),
+		name:         "import-controller-config",
+
+		handler:    handler,
+		predicates: predicates,
+	}
+}
+
 // Source is the event source of specified objects
 type Source struct {
 	informer     cache.SharedIndexInformer
@@ -296,12 +296,14 @@ var _ = Describe("Use KlusterletConfig to customize klusterlet manifests", Label
 		})
 
 		// klusterletconfig is missing and it will be ignored
-		defaultServerUrl, err := bootstrap.GetKubeAPIServerAddress(context.TODO(), hubRuntimeClient, nil)
-		Expect(err).ToNot(HaveOccurred())
-		defaultCABundle, err := bootstrap.GetBootstrapCAData(context.TODO(), &helpers.ClientHolder{
+		hubClientHolder := &helpers.ClientHolder{
 			KubeClient:    hubKubeClient,
 			RuntimeClient: hubRuntimeClient,
-		}, defaultServerUrl, managedClusterName, nil)
+		}
+		defaultServerUrl, err := bootstrap.GetKubeAPIServerAddress(context.TODO(), hubClientHolder, nil)
+		Expect(err).ToNot(HaveOccurred())
+		defaultCABundle, err := bootstrap.GetBootstrapCAData(context.TODO(), hubClientHolder,
+			defaultServerUrl, managedClusterName, nil)
 		Expect(err).ToNot(HaveOccurred())
 		assertBootstrapKubeconfig(defaultServerUrl, "", "", defaultCABundle, false)
 		assertManagedClusterAvailable(managedClusterName)